@@ -0,0 +1,213 @@
+package ajisai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/cache"
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+func cacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Inspect, verify and trim the cache of fetched imports",
+		Commands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "Show the cached imports with their source, revision, size and last fetch",
+				Flags:  []cli.Flag{formatFlag()},
+				Action: doCacheList,
+			},
+			{
+				Name:      "path",
+				Usage:     "Print the cache directory of an import",
+				ArgsUsage: "IMPORT",
+				Action:    doCachePath,
+			},
+			{
+				Name:   "verify",
+				Usage:  "Check the cached imports against their source in the config and " + lockfile.FileName,
+				Flags:  []cli.Flag{formatFlag()},
+				Action: doCacheVerify,
+			},
+			{
+				Name:  "prune",
+				Usage: "Remove cached imports that exceed an age or size limit",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "max-age",
+						Usage: "Remove imports last fetched longer than `DURATION` ago (e.g. 720h)",
+					},
+					&cli.StringFlag{
+						Name:  "max-size",
+						Usage: "Remove the least recently fetched imports until the cache fits in `SIZE` (e.g. 500M)",
+					},
+					formatFlag(),
+				},
+				Action: doCachePrune,
+			},
+		},
+	}
+}
+
+// formatFlag returns a new flag for the output format. Flags hold their parsed value,
+// so every command gets its own.
+func formatFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (" + formatTable + ", " + formatJSON + ")",
+		Value: formatTable,
+		Validator: func(format string) error {
+			if format != formatTable && format != formatJSON {
+				return fmt.Errorf("unknown format %q: must be %s or %s", format, formatTable, formatJSON)
+			}
+			return nil
+		},
+	}
+}
+
+func doCacheList(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := retrieveConfigForCache(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := cache.New(cfgCtx.Config).List()
+	if err != nil {
+		return err
+	}
+
+	return writeCacheEntries(cmd.Root().Writer, cmd.String("format"), entries)
+}
+
+func doCachePath(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := retrieveConfigForCache(c)
+	if err != nil {
+		return err
+	}
+
+	name := cmd.Args().First()
+	if name == "" {
+		return errors.New("cache path command requires the name of an import")
+	}
+	if _, exists := cfgCtx.Config.Workspace.Imports[name]; !exists {
+		return fmt.Errorf("import %s is not in the config", name)
+	}
+
+	dir, err := cache.New(cfgCtx.Config).Path(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.Root().Writer, dir)
+	return err
+}
+
+func doCacheVerify(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := retrieveConfigForCache(c)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockfile.Load(lockfile.PathForConfig(cfgCtx.Path))
+	if err != nil {
+		return err
+	}
+
+	results, err := cache.New(cfgCtx.Config).Verify(lock)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.Root().Writer
+	if cmd.String("format") == formatJSON {
+		err = writeJSON(w, results)
+	} else {
+		err = cache.WriteVerifyResults(w, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, result := range results {
+		if result.Status.IsProblem() {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d cached imports need to be fetched again; run `ajisai apply --refresh`", problems)
+	}
+
+	return nil
+}
+
+func doCachePrune(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := retrieveConfigForCache(c)
+	if err != nil {
+		return err
+	}
+
+	opts := cache.PruneOptions{MaxAge: cmd.Duration("max-age")}
+	if maxSize := cmd.String("max-size"); maxSize != "" {
+		opts.MaxSize, err = cache.ParseSize(maxSize)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.MaxAge <= 0 && opts.MaxSize <= 0 {
+		return errors.New("cache prune command requires --max-age or --max-size")
+	}
+
+	removed, err := cache.New(cfgCtx.Config).Prune(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeCacheEntries(cmd.Root().Writer, cmd.String("format"), removed)
+}
+
+func retrieveConfigForCache(c context.Context) (*config.Context, error) {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		// No action needed.
+		// But we include it for use exhaustive linter.
+	case config.StatusNotFound:
+		return nil, errors.New("cache command requires an existing config file")
+	case config.StatusValidationFailed:
+		return nil, cfgCtx.ValidationError
+	}
+
+	return cfgCtx, nil
+}
+
+func writeCacheEntries(w io.Writer, format string, entries []cache.Entry) error {
+	if format == formatJSON {
+		return writeJSON(w, entries)
+	}
+
+	return cache.WriteEntries(w, entries)
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,30 @@
+package ajisai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/doctor"
+)
+
+func doDoctor(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	report := doctor.New().Diagnose(cfgCtx)
+	if writeErr := report.Write(cmd.Root().Writer); writeErr != nil {
+		return fmt.Errorf("failed to write report: %w", writeErr)
+	}
+
+	if report.HasFailure() {
+		return errors.New("doctor found problems that need to be fixed")
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package ajisai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/importer"
+)
+
+func importableAgents() []string {
+	return []string{
+		string(config.AgentIntegrationTypeCursor),
+		string(config.AgentIntegrationTypeGitHubCopilot),
+		string(config.AgentIntegrationTypeWindsurf),
+	}
+}
+
+func doImport(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	// Import works without a config file, so fall back to the default namespace and the working directory.
+	namespace := config.DefaultNamespace
+	projectDir, err := resolveArgPath(cmd, "")
+	if err != nil {
+		return fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		namespace = cfgCtx.Config.Settings.Namespace
+		projectDir = cfgCtx.Config.Dir
+	case config.StatusNotFound:
+		// No action needed.
+	case config.StatusValidationFailed:
+		return cfgCtx.ValidationError
+	}
+
+	from := cmd.String("from")
+	if !slices.Contains(importableAgents(), from) {
+		return fmt.Errorf(
+			"unsupported agent to import from: %s (supported: %s)",
+			from,
+			strings.Join(importableAgents(), ", "),
+		)
+	}
+
+	source, err := engine.GetIntegration(projectDir, config.AgentIntegrationType(from))
+	if err != nil {
+		return fmt.Errorf("failed to get integration for %s: %w", from, err)
+	}
+
+	outputDir, err := resolveArgPath(cmd, cmd.String("output"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	result, err := importer.New(outputDir, cmd.Bool("force")).Import(source, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to import from %s: %w", from, err)
+	}
+
+	fmt.Fprintf(
+		cmd.Root().Writer,
+		"Imported %d rules and %d prompts from %s into %s\n",
+		len(result.Rules),
+		len(result.Prompts),
+		from,
+		filepath.Dir(result.Manifest),
+	)
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package ajisai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/loader"
+)
+
+func doLint(_ context.Context, cmd *cli.Command) error {
+	dir := cmd.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+
+	absDir, err := resolveArgPath(cmd, dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve package directory: %w", err)
+	}
+
+	if lintErr := loader.LintPackage(absDir); lintErr != nil {
+		return lintErr
+	}
+
+	fmt.Fprintf(cmd.Root().Writer, "No problems found in %s\n", absDir)
+	return nil
+}
@@ -0,0 +1,47 @@
+package ajisai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/updater"
+)
+
+func doOutdated(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		// No action needed.
+		// But we include it for use exhaustive linter.
+	case config.StatusNotFound:
+		return errors.New("outdated command requires an existing config file")
+	case config.StatusValidationFailed:
+		return cfgCtx.ValidationError
+	}
+
+	lock, err := lockfile.Load(lockfile.PathForConfig(cfgCtx.Path))
+	if err != nil {
+		return err
+	}
+
+	statuses, err := updater.NewWithConfig(cfgCtx.Config).Outdated(cfgCtx.Config, lock)
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		_, writeErr := fmt.Fprintln(cmd.Root().Writer, "No git imports to check.")
+		return writeErr
+	}
+
+	return updater.WriteOutdated(cmd.Root().Writer, statuses)
+}
@@ -0,0 +1,53 @@
+package ajisai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/packer"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+func doPack(_ context.Context, cmd *cli.Command) error {
+	dir := cmd.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+
+	absDir, err := resolveArgPath(cmd, dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve package directory: %w", err)
+	}
+
+	output := cmd.String("output")
+	if output == "" {
+		output = filepath.Base(absDir) + ".tar.gz"
+	}
+
+	absOutput, err := resolveArgPath(cmd, output)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+
+	var archive bytes.Buffer
+	result, err := packer.Pack(absDir, &archive)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %w", absDir, err)
+	}
+
+	if writeErr := utils.AtomicWriteFile(absOutput, &archive); writeErr != nil {
+		return fmt.Errorf("failed to write %s: %w", absOutput, writeErr)
+	}
+
+	w := cmd.Root().Writer
+	for _, file := range result.Files {
+		fmt.Fprintf(w, "  %s\n", file)
+	}
+	fmt.Fprintf(w, "Packed %d files into %s\nsha256: %s\n", len(result.Files), absOutput, result.SHA256)
+
+	return nil
+}
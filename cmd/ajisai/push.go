@@ -0,0 +1,53 @@
+package ajisai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/packer"
+)
+
+func doPush(_ context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() == 0 {
+		return errors.New("push command requires a reference (e.g. registry.example.com/ai/presets:1.2.0)")
+	}
+
+	ref, err := oci.ParseReference(cmd.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if ref.Digest != "" {
+		return fmt.Errorf("cannot push to %s: reference must have a tag, not a digest", ref)
+	}
+
+	dir := cmd.Args().Get(1)
+	if dir == "" {
+		dir = "."
+	}
+
+	absDir, err := resolveArgPath(cmd, dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve package directory: %w", err)
+	}
+
+	var archive bytes.Buffer
+	result, err := packer.Pack(absDir, &archive)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %w", absDir, err)
+	}
+
+	digest, err := oci.NewClient().PushPackage(ref, archive.Bytes(), map[string]string{
+		"org.opencontainers.image.title": ref.Repository,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push %s: %w", ref, err)
+	}
+
+	fmt.Fprintf(cmd.Root().Writer, "Pushed %d files to %s\ndigest: %s\n", len(result.Files), ref, digest)
+	return nil
+}
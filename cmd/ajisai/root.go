@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 
 	"github.com/sushichan044/ajisai/internal/config"
 	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/watch"
 	"github.com/sushichan044/ajisai/utils"
 	"github.com/sushichan044/ajisai/version"
 )
@@ -32,11 +37,27 @@ func Run(args []string, revision string) error {
 				Usage:   "Load configuration from `FILE`",
 				Sources: cli.EnvVars("AJISAI_CONFIG_LOCATION"),
 			},
+			&cli.StringFlag{
+				Name:    "workdir",
+				Aliases: []string{"C"},
+				Usage:   "Run as if ajisai was started in `DIR`",
+			},
 		},
 		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			workdir := c.String("workdir")
+			if workdir != "" {
+				exists, err := utils.IsDirExists(workdir)
+				if err != nil {
+					return ctx, fmt.Errorf("failed to check working directory: %w", err)
+				}
+				if !exists {
+					return ctx, fmt.Errorf("working directory %s does not exist", workdir)
+				}
+			}
+
 			cfgPath := c.String("config")
 
-			manager, err := prepareConfigManager(cfgPath)
+			manager, err := prepareConfigManager(workdir, cfgPath)
 			if err != nil {
 				return ctx, fmt.Errorf("failed to prepare loading configuration: %w", err)
 			}
@@ -48,21 +69,70 @@ func Run(args []string, revision string) error {
 					return config.StoreNotFoundInContext(ctx), nil
 				}
 
-				// TODO: add validation and distinguish between validation errors and other errors.
-				return config.StoreValidationErrorInContext(ctx, err), nil
+				var validationErr *config.ValidationError
+				if errors.As(err, &validationErr) {
+					return config.StoreValidationErrorInContext(ctx, validationErr), nil
+				}
+
+				return ctx, fmt.Errorf("failed to load configuration: %w", err)
 			}
 
-			return config.StoreInContext(ctx, loadedCfg), nil
+			cfgPath, err = manager.Path()
+			if err != nil {
+				return ctx, fmt.Errorf("failed to resolve configuration path: %w", err)
+			}
+
+			return config.StoreInContext(ctx, loadedCfg, cfgPath), nil
 		},
 		Action: func(_ context.Context, cmd *cli.Command) error {
 			return cli.ShowAppHelp(cmd)
 		},
 		Commands: []*cli.Command{
 			{
-				Name:   "apply",
-				Usage:  "Apply presets to the agent according to the config",
+				Name:  "apply",
+				Usage: "Apply presets to the agent according to the config",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Fail if any included rule or prompt file has invalid front matter",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "update",
+						Usage: "Ignore the commits pinned in " + lockfile.FileName + " and fetch the latest revisions",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "refresh",
+						Usage: "Fetch every import, even if it was fetched within its refresh interval",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:    "offline",
+						Usage:   "Apply the cached imports as they are without accessing the network",
+						Value:   false,
+						Sources: cli.EnvVars("AJISAI_OFFLINE"),
+					},
+				},
 				Action: doApply,
 			},
+			{
+				Name:  "watch",
+				Usage: "Apply presets, and apply them again when local imports or the config change",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "Fail if any included rule or prompt file has invalid front matter",
+						Value: false,
+					},
+					&cli.DurationFlag{
+						Name:  "debounce",
+						Usage: "Wait for `DURATION` after the last change before applying",
+						Value: watch.DefaultDebounce,
+					},
+				},
+				Action: doWatch,
+			},
 			{
 				Name:  "clean",
 				Usage: "Clean the cache",
@@ -76,6 +146,7 @@ func Run(args []string, revision string) error {
 				},
 				Action: doClean,
 			},
+			cacheCommand(),
 			{
 				Name:  "import",
 				Usage: "Import presets from an existing agent format into the default format",
@@ -83,20 +154,69 @@ func Run(args []string, revision string) error {
 					&cli.StringFlag{
 						// Cursor, VSCode GitHub Copilot, etc.
 						Name:     "from",
-						Usage:    "Input source to import from",
+						Usage:    "Input source to import from (" + strings.Join(importableAgents(), ", ") + ")",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the imported package to `DIR`",
+						Value:   "./.ai",
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "Overwrite existing files in the output directory",
+						Value:   false,
+					},
 				},
-				Action: func(_ context.Context, _ *cli.Command) error {
-					return nil
-				},
+				Action: doImport,
 			},
 			{
-				Name:  "doctor",
-				Usage: "Validate preset directory structure or config inputs against the default format",
-				Action: func(_ context.Context, _ *cli.Command) error {
-					return nil
+				Name:      "lint",
+				Usage:     "Check the front matter of rule and prompt files exported by a package",
+				ArgsUsage: "[DIR]",
+				Action:    doLint,
+			},
+			{
+				Name:      "pack",
+				Usage:     "Build a distributable archive of the rule and prompt files exported by a package",
+				ArgsUsage: "[DIR]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the archive to `FILE` (default: <DIR name>.tar.gz)",
+					},
 				},
+				Action: doPack,
+			},
+			{
+				Name:      "push",
+				Usage:     "Pack a package and push it to an OCI registry",
+				ArgsUsage: "REF [DIR]",
+				Action:    doPush,
+			},
+			{
+				Name:   "outdated",
+				Usage:  "Show git imports whose remote has newer commits or tags",
+				Action: doOutdated,
+			},
+			{
+				Name:      "update",
+				Usage:     "Move git imports to the latest commit of their revision and show changed files",
+				ArgsUsage: "[IMPORT...]",
+				Action:    doUpdate,
+			},
+			{
+				Name:   "vendor",
+				Usage:  "Copy the included files of git, archive and OCI imports into the vendor directory",
+				Action: doVendor,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "Diagnose the environment, imported packages and generated outputs",
+				Action: doDoctor,
 			},
 		},
 	}
@@ -104,7 +224,7 @@ func Run(args []string, revision string) error {
 	return app.Run(context.Background(), args)
 }
 
-func doApply(c context.Context, _ *cli.Command) error {
+func doApply(c context.Context, cmd *cli.Command) error {
 	cfgCtx, err := config.RetrieveFromContext(c)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve config from context: %w", err)
@@ -120,22 +240,52 @@ func doApply(c context.Context, _ *cli.Command) error {
 		return cfgCtx.ValidationError
 	}
 
+	if cmd.Bool("offline") && cmd.Bool("update") {
+		return errors.New("--update cannot be used offline, since it fetches the latest revisions")
+	}
+	if cmd.Bool("offline") && cmd.Bool("refresh") {
+		return errors.New("--refresh cannot be used offline, since it fetches every import")
+	}
+
 	cfg := cfgCtx.Config
-	eng, err := engine.NewEngine(cfg)
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{
+		Strict:       cmd.Bool("strict"),
+		LockfilePath: lockfile.PathForConfig(cfgCtx.Path),
+		Update:       cmd.Bool("update"),
+		Offline:      cmd.Bool("offline"),
+		Refresh:      cmd.Bool("refresh"),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create engine: %w", err)
 	}
 
+	return applyAll(cmd.Root().Writer, eng, cfg)
+}
+
+// applyAll regenerates the outputs of every import and writes the lockfile.
+// The tags that version range revisions resolved to and the warnings of imports are reported to w.
+func applyAll(w io.Writer, eng *engine.Engine, cfg *config.Config) error {
 	cleanErr := eng.CleanOutputs()
 	if cleanErr != nil {
 		return fmt.Errorf("failed to clean: %w", cleanErr)
 	}
 
-	for packageName := range cfg.Workspace.Imports {
+	for _, packageName := range slices.Sorted(maps.Keys(cfg.Workspace.Imports)) {
 		applyErr := eng.ApplyPackage(packageName)
 		if applyErr != nil {
 			return fmt.Errorf("failed to apply package %s: %w", packageName, applyErr)
 		}
+
+		if entry, isResolved := eng.Resolved(packageName); isResolved && entry.Version != "" {
+			fmt.Fprintf(w, "%s: %s resolved to %s\n", packageName, entry.Revision, entry.Version)
+		}
+		if warning, hasWarning := eng.Warning(packageName); hasWarning {
+			fmt.Fprintf(w, "%s: warning: %s\n", packageName, warning)
+		}
+	}
+
+	if lockErr := eng.WriteLockfile(); lockErr != nil {
+		return fmt.Errorf("failed to write lockfile: %w", lockErr)
 	}
 
 	return nil
@@ -173,21 +323,29 @@ func doClean(c context.Context, cmd *cli.Command) error {
 	return nil
 }
 
-func prepareConfigManager(cfgPath string) (*config.Manager, error) {
+// prepareConfigManager returns a manager that reads cfgPath, or the default config file in workdir if it is empty.
+// A relative cfgPath is resolved against workdir, and an empty workdir means the current working directory.
+func prepareConfigManager(workdir string, cfgPath string) (*config.Manager, error) {
 	if cfgPath == "" {
 		// init with default file since user didn't specify a config file via -c.
-		cwd, err := os.Getwd()
+		dir, err := utils.ResolveAbsPathFrom(workdir, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to get current working directory: %w", err)
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
 		}
 
-		return config.NewDefaultManagerInDir(cwd)
+		return config.NewDefaultManagerInDir(dir)
 	}
 
-	absPath, err := utils.ResolveAbsPath(cfgPath)
+	absPath, err := utils.ResolveAbsPathFrom(workdir, cfgPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve config path: %w", err)
 	}
 
 	return config.NewManager(absPath)
 }
+
+// resolveArgPath returns the absolute path of a path given on the command line,
+// which is relative to `--workdir` if it is set.
+func resolveArgPath(cmd *cli.Command, path string) (string, error) {
+	return utils.ResolveAbsPathFrom(cmd.Root().String("workdir"), path)
+}
@@ -0,0 +1,94 @@
+package ajisai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/updater"
+)
+
+func doUpdate(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		// No action needed.
+		// But we include it for use exhaustive linter.
+	case config.StatusNotFound:
+		return errors.New("update command requires an existing config file")
+	case config.StatusValidationFailed:
+		return cfgCtx.ValidationError
+	}
+
+	cfg := cfgCtx.Config
+
+	targets := cmd.Args().Slice()
+	if len(targets) == 0 {
+		targets = slices.Sorted(maps.Keys(cfg.Workspace.Imports))
+	}
+	for _, target := range targets {
+		if _, imported := cfg.Workspace.Imports[target]; !imported {
+			return fmt.Errorf("package %s is not imported", target)
+		}
+	}
+
+	lockPath := lockfile.PathForConfig(cfgCtx.Path)
+	lock, err := lockfile.Load(lockPath)
+	if err != nil {
+		return err
+	}
+
+	upd := updater.NewWithConfig(cfgCtx.Config)
+	before, err := upd.CurrentCommits(cfg, lock)
+	if err != nil {
+		return err
+	}
+
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath, UpdateImports: targets})
+	if err != nil {
+		return fmt.Errorf("failed to create engine: %w", err)
+	}
+
+	if applyErr := applyAll(cmd.Root().Writer, eng, cfg); applyErr != nil {
+		return applyErr
+	}
+
+	after, err := upd.CurrentCommits(cfg, lockfile.New())
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		to, isGit := after[target]
+		if !isGit {
+			continue
+		}
+
+		from := before[target]
+		var changes []fetcher.FileChange
+		if from != "" && from != to {
+			changes, err = upd.ChangedFiles(cfg, target, from, to)
+			if err != nil {
+				return fmt.Errorf("failed to list changed files of %s: %w", target, err)
+			}
+		}
+
+		if writeErr := updater.WriteUpdate(cmd.Root().Writer, target, from, to, changes); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	return nil
+}
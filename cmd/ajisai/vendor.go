@@ -0,0 +1,73 @@
+package ajisai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+)
+
+func doVendor(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		// No action needed.
+		// But we include it for use exhaustive linter.
+	case config.StatusNotFound:
+		return errors.New("vendor command requires an existing config file")
+	case config.StatusValidationFailed:
+		return cfgCtx.ValidationError
+	}
+
+	cfg := cfgCtx.Config
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockfile.PathForConfig(cfgCtx.Path)})
+	if err != nil {
+		return fmt.Errorf("failed to create engine: %w", err)
+	}
+
+	w := cmd.Root().Writer
+	vendored := make([]string, 0, len(cfg.Workspace.Imports))
+	for _, packageName := range slices.Sorted(maps.Keys(cfg.Workspace.Imports)) {
+		if cfg.Workspace.Imports[packageName].Type == config.ImportTypeLocal {
+			continue
+		}
+
+		if vendorErr := eng.VendorPackage(packageName); vendorErr != nil {
+			return vendorErr
+		}
+		vendored = append(vendored, packageName)
+
+		vendorDir, dirErr := cfg.GetImportedPackageVendorRoot(packageName)
+		if dirErr != nil {
+			return dirErr
+		}
+		fmt.Fprintf(w, "%s: vendored into %s\n", packageName, vendorDir)
+	}
+
+	vendorRoot, err := cfg.ResolvePath(cfg.Settings.VendorDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve vendor directory: %w", err)
+	}
+
+	removed, err := vendoring.Prune(vendorRoot, vendored)
+	if err != nil {
+		return err
+	}
+	for _, packageName := range removed {
+		fmt.Fprintf(w, "%s: removed vendored copy, since it is not a remote import anymore\n", packageName)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package ajisai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/watch"
+)
+
+func doWatch(c context.Context, cmd *cli.Command) error {
+	cfgCtx, err := config.RetrieveFromContext(c)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config from context: %w", err)
+	}
+
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		// No action needed.
+		// But we include it for use exhaustive linter.
+	case config.StatusNotFound:
+		return errors.New("watch command requires an existing config file")
+	case config.StatusValidationFailed:
+		return cfgCtx.ValidationError
+	}
+
+	ctx, stop := signal.NotifyContext(c, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := watch.New(cfgCtx.Path, cmd.Root().Writer, watch.Options{
+		Strict:   cmd.Bool("strict"),
+		Debounce: cmd.Duration("debounce"),
+	})
+
+	return watcher.Run(ctx)
+}
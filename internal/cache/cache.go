@@ -0,0 +1,405 @@
+package cache
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	// VerifyOK indicates the entry was fetched from the configured source and matches the lockfile.
+	VerifyOK VerifyStatus = iota
+
+	// VerifyMissing indicates the import has not been fetched into the cache.
+	VerifyMissing
+
+	// VerifySourceChanged indicates the entry was fetched from another source than the configured one,
+	// or its source is unknown.
+	VerifySourceChanged
+
+	// VerifyLockMismatch indicates the entry resolves to another commit or digest than the lockfile records.
+	VerifyLockMismatch
+
+	// VerifyUnused indicates the entry belongs to no configured import.
+	VerifyUnused
+)
+
+type (
+	// Cache inspects and trims the directory that imports are fetched into.
+	Cache struct {
+		cfg *config.Config
+		git fetcher.Git
+	}
+
+	// Entry is a directory in the cache.
+	Entry struct {
+		// Name of the import, which is also the name of the directory.
+		Name string `json:"name"`
+		Dir  string `json:"dir"`
+
+		// Type and Source are read from the cache metadata, and are empty if it has none.
+		// Source is the repository, URL, OCI reference or directory the entry was fetched from.
+		Type   config.ImportType `json:"type,omitempty"`
+		Source string            `json:"source,omitempty"`
+
+		// Commit of a git import, or digest of the content of other imports. Empty if it cannot be resolved.
+		Revision string `json:"revision,omitempty"`
+
+		// Size of all files in the directory in bytes.
+		Size int64 `json:"size"`
+
+		// When the entry was last fetched. Zero for entries fetched by older versions of ajisai.
+		FetchedAt time.Time `json:"fetchedAt,omitzero"`
+
+		// Whether the entry belongs to an import in the config.
+		Configured bool `json:"configured"`
+	}
+
+	// VerifyStatus is the outcome of verifying a cache entry.
+	VerifyStatus int
+
+	// VerifyResult is the outcome of verifying the cache entry of an import.
+	VerifyResult struct {
+		Name    string       `json:"name"`
+		Status  VerifyStatus `json:"status"`
+		Message string       `json:"message,omitempty"`
+	}
+
+	// PruneOptions are the limits that Prune enforces. Zero values disable a limit.
+	PruneOptions struct {
+		// Entries fetched longer ago are removed.
+		MaxAge time.Duration
+
+		// Entries are removed, least recently fetched first, until the cache is at most this many bytes.
+		MaxSize int64
+	}
+)
+
+func (s VerifyStatus) String() string {
+	switch s {
+	case VerifyOK:
+		return "ok"
+	case VerifyMissing:
+		return "missing"
+	case VerifySourceChanged:
+		return "source changed"
+	case VerifyLockMismatch:
+		return "lockfile mismatch"
+	case VerifyUnused:
+		return "unused"
+	}
+
+	return "unknown"
+}
+
+// MarshalText writes the status as its name in JSON output.
+func (s VerifyStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// IsProblem reports whether the status needs the import to be fetched again.
+func (s VerifyStatus) IsProblem() bool {
+	return s == VerifyMissing || s == VerifySourceChanged || s == VerifyLockMismatch
+}
+
+// New creates a Cache for the cache directory of the config, resolving git imports with its git backend.
+func New(cfg *config.Config) *Cache {
+	return &Cache{cfg: cfg, git: fetcher.NewGit(cfg.Settings.GitBackend, nil, nil)}
+}
+
+// Dir returns the absolute path of the cache directory.
+func (c *Cache) Dir() (string, error) {
+	return c.cfg.ResolvePath(c.cfg.Settings.CacheDir)
+}
+
+// Path returns the directory the import is fetched into. It does not need to exist.
+func (c *Cache) Path(name string) (string, error) {
+	return c.cfg.GetImportedPackageCacheRoot(name)
+}
+
+// List returns the entries in the cache directory, sorted by name.
+func (c *Cache) List() ([]Entry, error) {
+	cacheDir, err := c.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Entry{}, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", cacheDir, err)
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		// Hidden directories are temporary extraction directories of fetches in progress.
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+
+		entry, entryErr := c.entry(dirEntry.Name(), filepath.Join(cacheDir, dirEntry.Name()))
+		if entryErr != nil {
+			return nil, entryErr
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (c *Cache) entry(name string, dir string) (Entry, error) {
+	_, configured := c.cfg.Workspace.Imports[name]
+	entry := Entry{Name: name, Dir: dir, Configured: configured}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Size = size
+
+	metadata, err := fetcher.ReadCacheMetadata(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	if metadata == nil {
+		return entry, nil
+	}
+
+	entry.Type = metadata.Type
+	entry.FetchedAt = metadata.FetchedAt
+	entry.Source = cmp.Or(metadata.Repository, metadata.URL, metadata.Ref, metadata.Path)
+
+	if resolved, resolveErr := c.resolve(metadata.Type, dir); resolveErr == nil {
+		entry.Revision = resolved
+	}
+
+	return entry, nil
+}
+
+// Verify checks the cache entry of every configured import against its source in the config and the lockfile,
+// and reports entries that belong to no import. Results are sorted by name.
+func (c *Cache) Verify(lock *lockfile.Lockfile) ([]VerifyResult, error) {
+	results := make([]VerifyResult, 0)
+
+	for _, name := range slices.Sorted(maps.Keys(c.cfg.Workspace.Imports)) {
+		result, err := c.verify(name, c.cfg.Workspace.Imports[name], lock)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.Configured {
+			results = append(results, VerifyResult{
+				Name:    entry.Name,
+				Status:  VerifyUnused,
+				Message: "not imported by the config; `ajisai clean` removes it",
+			})
+		}
+	}
+
+	slices.SortFunc(results, func(a, b VerifyResult) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return results, nil
+}
+
+func (c *Cache) verify(name string, pkg config.ImportedPackage, lock *lockfile.Lockfile) (VerifyResult, error) {
+	if pkg.IsLinked() {
+		return VerifyResult{Name: name, Status: VerifyOK, Message: "read from its source directory"}, nil
+	}
+
+	dir, err := c.Path(name)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+
+	exists, err := utils.IsDirExists(dir)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if !exists {
+		return VerifyResult{Name: name, Status: VerifyMissing, Message: "not fetched yet"}, nil
+	}
+
+	metadata, err := fetcher.ReadCacheMetadata(dir)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if metadata == nil {
+		return c.verifyVendored(name, pkg)
+	}
+	if !metadata.MatchesImport(pkg) {
+		return VerifyResult{
+			Name:    name,
+			Status:  VerifySourceChanged,
+			Message: "fetched from " + cmp.Or(metadata.Repository, metadata.URL, metadata.Ref, metadata.Path),
+		}, nil
+	}
+
+	pinned, isPinned := pinnedRevision(lock, name, pkg)
+	if !isPinned {
+		return VerifyResult{Name: name, Status: VerifyOK, Message: "not in the lockfile"}, nil
+	}
+
+	resolved, err := c.resolve(pkg.Type, dir)
+	if err != nil {
+		return VerifyResult{Name: name, Status: VerifyLockMismatch, Message: err.Error()}, nil
+	}
+	if resolved != pinned {
+		return VerifyResult{
+			Name:    name,
+			Status:  VerifyLockMismatch,
+			Message: fmt.Sprintf("resolves to %s, but the lockfile records %s", resolved, pinned),
+		}, nil
+	}
+
+	return VerifyResult{Name: name, Status: VerifyOK}, nil
+}
+
+// verifyVendored verifies an entry without cache metadata, which is either extracted from a vendored copy
+// (see `ajisai vendor`) or of unknown source.
+func (c *Cache) verifyVendored(name string, pkg config.ImportedPackage) (VerifyResult, error) {
+	vendorDir, err := c.cfg.GetImportedPackageVendorRoot(name)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+
+	vendored, err := vendoring.Read(vendorDir)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if vendored == nil {
+		return VerifyResult{Name: name, Status: VerifySourceChanged, Message: "no record of its source"}, nil
+	}
+	if !vendored.Import.Matches(pkg) {
+		return VerifyResult{
+			Name:    name,
+			Status:  VerifySourceChanged,
+			Message: "extracted from a vendored copy that does not match the config; run `ajisai vendor`",
+		}, nil
+	}
+
+	return VerifyResult{Name: name, Status: VerifyOK, Message: "extracted from its vendored copy"}, nil
+}
+
+// Prune removes entries that exceed the limits and returns them, least recently fetched first.
+// Entries without a fetch time count as the oldest.
+func (c *Cache) Prune(opts PruneOptions) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return a.FetchedAt.Compare(b.FetchedAt)
+	})
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	removed := make([]Entry, 0)
+	for _, entry := range entries {
+		tooOld := opts.MaxAge > 0 && time.Since(entry.FetchedAt) > opts.MaxAge
+		tooLarge := opts.MaxSize > 0 && total > opts.MaxSize
+		if !tooOld && !tooLarge {
+			continue
+		}
+
+		if removeErr := os.RemoveAll(entry.Dir); removeErr != nil {
+			return removed, fmt.Errorf("failed to remove cache entry %s: %w", entry.Dir, removeErr)
+		}
+		total -= entry.Size
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// resolve returns what the entry in dir resolves to, like the fetcher of the import type does.
+func (c *Cache) resolve(importType config.ImportType, dir string) (string, error) {
+	var packageFetcher domain.PackageFetcher
+	switch importType {
+	case config.ImportTypeLocal:
+		packageFetcher = fetcher.NewLocalFetcher()
+	case config.ImportTypeGit:
+		packageFetcher = c.git
+	case config.ImportTypeArchive:
+		packageFetcher = fetcher.NewArchiveFetcher()
+	case config.ImportTypeOCI:
+		packageFetcher = fetcher.NewOCIFetcher()
+	}
+	if packageFetcher == nil {
+		return "", fmt.Errorf("unknown import type: %s", importType)
+	}
+
+	return packageFetcher.Resolve(dir)
+}
+
+// pinnedRevision returns the commit or content digest the lockfile records for the import,
+// if the entry still matches its config.
+func pinnedRevision(lock *lockfile.Lockfile, name string, pkg config.ImportedPackage) (string, bool) {
+	switch pkg.Type {
+	case config.ImportTypeGit:
+		return lock.PinnedCommit(name, pkg)
+	case config.ImportTypeOCI:
+		return lock.PinnedDigest(name, pkg)
+	case config.ImportTypeLocal, config.ImportTypeArchive:
+		entry, exists := lock.Imports[name]
+		if !exists || entry.Type != pkg.Type || entry.Digest == "" {
+			return "", false
+		}
+		return entry.Digest, true
+	}
+
+	return "", false
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure cache entry %s: %w", dir, err)
+	}
+
+	return size, nil
+}
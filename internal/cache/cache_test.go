@@ -0,0 +1,303 @@
+package cache_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/cache"
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+)
+
+func localImport(path string) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeLocal,
+		Details: config.LocalImportDetails{Path: path},
+	}
+}
+
+// newSource creates a package directory holding a rule of the given size.
+func newSource(t *testing.T, size int) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "rules"), 0750))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "rules", "rule.md"),
+		[]byte(strings.Repeat("a", size)),
+		0600,
+	))
+
+	return dir
+}
+
+func newTestCache(t *testing.T, imports map[string]config.ImportedPackage) *cache.Cache {
+	t.Helper()
+
+	cfg := &config.Config{
+		Settings:  &config.Settings{CacheDir: filepath.Join(t.TempDir(), "cache"), Namespace: "ajisai"},
+		Workspace: &config.Workspace{Imports: imports},
+	}
+
+	return cache.New(cfg)
+}
+
+// fetch fetches the package into the cache directory of the import name, which does not need to be in the config,
+// and returns what the entry resolves to.
+func fetch(t *testing.T, c *cache.Cache, name string, pkg config.ImportedPackage) string {
+	t.Helper()
+
+	cacheDir, err := c.Dir()
+	require.NoError(t, err)
+	dir := filepath.Join(cacheDir, name)
+
+	localFetcher := fetcher.NewLocalFetcher()
+	require.NoError(t, localFetcher.Fetch(pkg, dir))
+
+	resolved, err := localFetcher.Resolve(dir)
+	require.NoError(t, err)
+	return resolved
+}
+
+// setFetchedAt rewrites the fetch time recorded in the metadata of the cache entry.
+func setFetchedAt(t *testing.T, c *cache.Cache, name string, fetchedAt time.Time) {
+	t.Helper()
+
+	dir, err := c.Path(name)
+	require.NoError(t, err)
+
+	metadata, err := fetcher.ReadCacheMetadata(dir)
+	require.NoError(t, err)
+	require.NotNil(t, metadata)
+
+	metadata.FetchedAt = fetchedAt
+	body, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fetcher.CacheMetadataFileName), body, 0600))
+}
+
+func TestCache_List(t *testing.T) {
+	source := newSource(t, 100)
+	c := newTestCache(t, map[string]config.ImportedPackage{"presets": localImport(source)})
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	digest := fetch(t, c, "presets", localImport(source))
+	fetch(t, c, "removed", localImport(source))
+
+	cacheDir, err := c.Dir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, ".extracting"), 0750))
+
+	entries, err = c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "presets", entries[0].Name)
+	assert.Equal(t, filepath.Join(cacheDir, "presets"), entries[0].Dir)
+	assert.Equal(t, config.ImportTypeLocal, entries[0].Type)
+	assert.Equal(t, source, entries[0].Source)
+	assert.Equal(t, digest, entries[0].Revision)
+	assert.Greater(t, entries[0].Size, int64(100))
+	assert.False(t, entries[0].FetchedAt.IsZero())
+	assert.True(t, entries[0].Configured)
+
+	assert.Equal(t, "removed", entries[1].Name)
+	assert.False(t, entries[1].Configured)
+}
+
+func TestCache_Verify(t *testing.T) {
+	source := newSource(t, 10)
+	otherSource := newSource(t, 10)
+
+	imports := map[string]config.ImportedPackage{
+		"ok":       localImport(source),
+		"missing":  localImport(source),
+		"moved":    localImport(source),
+		"modified": localImport(source),
+		"unlocked": localImport(source),
+	}
+	c := newTestCache(t, imports)
+
+	lock := lockfile.New()
+	for _, name := range []string{"ok", "modified", "unlocked"} {
+		resolved := fetch(t, c, name, imports[name])
+		if name != "unlocked" {
+			lock.Imports[name] = lockfile.NewEntry(imports[name], resolved)
+		}
+	}
+	fetch(t, c, "moved", localImport(otherSource))
+	fetch(t, c, "unused", localImport(source))
+
+	modifiedDir, err := c.Path("modified")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(modifiedDir, "rules", "rule.md"), []byte("edited"), 0600))
+
+	results, err := c.Verify(lock)
+	require.NoError(t, err)
+
+	statuses := make(map[string]cache.VerifyStatus, len(results))
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+		names = append(names, result.Name)
+	}
+
+	assert.Equal(t, []string{"missing", "modified", "moved", "ok", "unlocked", "unused"}, names)
+	assert.Equal(t, map[string]cache.VerifyStatus{
+		"ok":       cache.VerifyOK,
+		"missing":  cache.VerifyMissing,
+		"moved":    cache.VerifySourceChanged,
+		"modified": cache.VerifyLockMismatch,
+		"unlocked": cache.VerifyOK,
+		"unused":   cache.VerifyUnused,
+	}, statuses)
+
+	assert.True(t, cache.VerifyLockMismatch.IsProblem())
+	assert.False(t, cache.VerifyUnused.IsProblem())
+
+	body, err := json.Marshal(results[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"missing","status":"missing","message":"not fetched yet"}`, string(body))
+}
+
+func TestCache_Prune(t *testing.T) {
+	source := newSource(t, 1000)
+	imports := map[string]config.ImportedPackage{
+		"old":    localImport(source),
+		"recent": localImport(source),
+		"new":    localImport(source),
+	}
+
+	setUp := func(t *testing.T) *cache.Cache {
+		t.Helper()
+
+		c := newTestCache(t, imports)
+		for name, pkg := range imports {
+			fetch(t, c, name, pkg)
+		}
+		setFetchedAt(t, c, "old", time.Now().Add(-48*time.Hour))
+		setFetchedAt(t, c, "recent", time.Now().Add(-2*time.Hour))
+		return c
+	}
+
+	names := func(entries []cache.Entry) []string {
+		result := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			result = append(result, entry.Name)
+		}
+		return result
+	}
+
+	t.Run("max age", func(t *testing.T) {
+		c := setUp(t)
+
+		removed, err := c.Prune(cache.PruneOptions{MaxAge: 24 * time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"old"}, names(removed))
+
+		entries, err := c.List()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new", "recent"}, names(entries))
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		c := setUp(t)
+
+		entries, err := c.List()
+		require.NoError(t, err)
+
+		// Only the most recently fetched entry fits.
+		removed, err := c.Prune(cache.PruneOptions{MaxSize: entries[0].Size + 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"old", "recent"}, names(removed))
+
+		entries, err = c.List()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new"}, names(entries))
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		c := setUp(t)
+
+		removed, err := c.Prune(cache.PruneOptions{MaxAge: 72 * time.Hour, MaxSize: 1 << 30})
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"2048":   2048,
+		"10B":    10,
+		"1k":     1024,
+		"1.5 MB": 1536 * 1024,
+		"500M":   500 * 1024 * 1024,
+		"2GiB":   2 * 1024 * 1024 * 1024,
+	}
+
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			size, err := cache.ParseSize(input)
+			require.NoError(t, err)
+			assert.Equal(t, want, size)
+		})
+	}
+
+	for _, input := range []string{"", "M", "-1K", "10 TB", "ten"} {
+		_, err := cache.ParseSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", cache.FormatSize(512))
+	assert.Equal(t, "1.5 KiB", cache.FormatSize(1536))
+	assert.Equal(t, "500.0 MiB", cache.FormatSize(500*1024*1024))
+	assert.Equal(t, "2048.0 GiB", cache.FormatSize(2048*1024*1024*1024))
+}
+
+func TestCache_Verify_Vendored(t *testing.T) {
+	source := newSource(t, 10)
+	pkg := config.ImportedPackage{
+		Type:    config.ImportTypeArchive,
+		Include: []string{config.DefaultPresetName},
+		Details: config.ArchiveImportDetails{
+			URL:    "https://releases.example.com/presets.tar.gz",
+			SHA256: "0f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a6978",
+		},
+	}
+	vendorRoot := t.TempDir()
+	c := cache.New(&config.Config{
+		Settings: &config.Settings{
+			CacheDir:  filepath.Join(t.TempDir(), "cache"),
+			Namespace: "ajisai",
+			VendorDir: vendorRoot,
+		},
+		Workspace: &config.Workspace{Imports: map[string]config.ImportedPackage{"vendored": pkg}},
+	})
+
+	vendorDir := filepath.Join(vendorRoot, "vendored")
+	require.NoError(t, vendoring.Write(source, vendorDir, lockfile.NewEntry(pkg, "sha256:abc")))
+
+	cacheDir, err := c.Path("vendored")
+	require.NoError(t, err)
+	require.NoError(t, vendoring.Extract(vendorDir, cacheDir))
+
+	results, err := c.Verify(lockfile.New())
+	require.NoError(t, err)
+	assert.Equal(t, []cache.VerifyResult{
+		{Name: "vendored", Status: cache.VerifyOK, Message: "extracted from its vendored copy"},
+	}, results)
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	shortRevisionLength = 12
+
+	kibibyte = 1024
+)
+
+// sizeUnits are the suffixes accepted by ParseSize, mapped to the number of bytes they stand for.
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   kibibyte,
+	"kb":  kibibyte,
+	"kib": kibibyte,
+	"m":   kibibyte * kibibyte,
+	"mb":  kibibyte * kibibyte,
+	"mib": kibibyte * kibibyte,
+	"g":   kibibyte * kibibyte * kibibyte,
+	"gb":  kibibyte * kibibyte * kibibyte,
+	"gib": kibibyte * kibibyte * kibibyte,
+}
+
+// WriteEntries writes the entries as a table.
+func WriteEntries(w io.Writer, entries []Entry) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "IMPORT\tTYPE\tSOURCE\tREVISION\tSIZE\tFETCHED")
+	for _, entry := range entries {
+		name := entry.Name
+		if !entry.Configured {
+			name += " (unused)"
+		}
+
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			orDash(string(entry.Type)),
+			orDash(entry.Source),
+			orDash(shortRevision(entry.Revision)),
+			FormatSize(entry.Size),
+			formatTime(entry.FetchedAt),
+		)
+	}
+
+	return table.Flush()
+}
+
+// WriteVerifyResults writes the results as a table.
+func WriteVerifyResults(w io.Writer, results []VerifyResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "IMPORT\tSTATUS\tDETAIL")
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", result.Name, result.Status, orDash(result.Message))
+	}
+
+	return table.Flush()
+}
+
+// FormatSize formats a number of bytes with a binary unit, e.g. `1.5 MiB`.
+func FormatSize(size int64) string {
+	if size < kibibyte {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	value := float64(size)
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		value /= kibibyte
+		if value < kibibyte || unit == "GiB" {
+			return strconv.FormatFloat(value, 'f', 1, 64) + " " + unit
+		}
+	}
+
+	return strconv.FormatInt(size, 10) + " B"
+}
+
+// ParseSize parses a number of bytes with an optional binary unit, e.g. `500M`, `1GiB` or `2048`.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	digits := strings.TrimRightFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	multiplier, known := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[len(digits):]))]
+	value, err := strconv.ParseFloat(digits, 64)
+	if !known || err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. `500M` or `2GiB`)", s)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
+
+// shortRevision shortens a commit SHA or the hash of a digest like `sha256:<hex>`, keeping its algorithm.
+func shortRevision(revision string) string {
+	algorithm, hash, isDigest := strings.Cut(revision, ":")
+	if !isDigest {
+		hash = revision
+	}
+	if len(hash) > shortRevisionLength {
+		hash = hash[:shortRevisionLength]
+	}
+
+	if isDigest {
+		return algorithm + ":" + hash
+	}
+	return hash
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -36,10 +36,27 @@ type Config struct {
 		Definition to use various presets in this workspace.
 	*/
 	Workspace *Workspace
+
+	/*
+		Directory that relative paths in the config are resolved from, and that integrations write into.
+
+		It is the directory of the config file when loaded by Manager. Empty means the current working directory.
+	*/
+	Dir string
+}
+
+// ResolvePath returns the absolute path of a path written in the config, which is relative to Dir.
+func (c *Config) ResolvePath(path string) (string, error) {
+	return utils.ResolveAbsPathFrom(c.Dir, path)
+}
+
+// ProjectRoot returns the absolute path of Dir.
+func (c *Config) ProjectRoot() (string, error) {
+	return c.ResolvePath("")
 }
 
 func (c *Config) GetImportedPackageCacheRoot(packageName string) (string, error) {
-	cacheDir, err := utils.ResolveAbsPath(c.Settings.CacheDir)
+	cacheDir, err := c.ResolvePath(c.Settings.CacheDir)
 	if err != nil {
 		return "", err
 	}
@@ -52,6 +69,44 @@ func (c *Config) GetImportedPackageCacheRoot(packageName string) (string, error)
 	return filepath.Join(cacheDir, packageName), nil
 }
 
+// GetImportedPackageRoot returns the directory the package manifest and export globs are resolved from.
+// It is the cache root, or the configured subdirectory of it for git imports with `path`.
+// Local imports with `mode: link` are read from their source directory instead.
+func (c *Config) GetImportedPackageRoot(packageName string) (string, error) {
+	cacheRoot, err := c.GetImportedPackageCacheRoot(packageName)
+	if err != nil {
+		return "", err
+	}
+
+	pkg := c.Workspace.Imports[packageName]
+	if pkg.IsLinked() {
+		details, _ := GetImportDetails[LocalImportDetails](pkg)
+		return c.ResolvePath(details.Path)
+	}
+
+	details, isGit := GetImportDetails[GitImportDetails](pkg)
+	if !isGit || details.Path == "" {
+		return cacheRoot, nil
+	}
+
+	return filepath.Join(cacheRoot, filepath.FromSlash(details.Path)), nil
+}
+
+// GetImportedPackageVendorRoot returns the directory `ajisai vendor` copies the package into.
+func (c *Config) GetImportedPackageVendorRoot(packageName string) (string, error) {
+	vendorDir, err := c.ResolvePath(c.Settings.VendorDir)
+	if err != nil {
+		return "", err
+	}
+
+	_, isConfigured := c.Workspace.Imports[packageName]
+	if !isConfigured {
+		return "", fmt.Errorf("package %s not found", packageName)
+	}
+
+	return filepath.Join(vendorDir, packageName), nil
+}
+
 func isSupportedConfigFilePath(path string) bool {
 	return slices.Contains(supportedConfigFileExtensions, filepath.Ext(path))
 }
@@ -77,3 +77,83 @@ func TestConfig_GetImportedPackageCacheRoot(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_GetImportedPackageRoot(t *testing.T) {
+	tmpCacheDir := t.TempDir()
+
+	cfg := &config.Config{
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"monorepo": {
+					Type: config.ImportTypeGit,
+					Details: config.GitImportDetails{
+						Repository: "https://github.com/example/presets.git",
+						Path:       "packages/go",
+					},
+				},
+				"whole_repo": {
+					Type:    config.ImportTypeGit,
+					Details: config.GitImportDetails{Repository: "https://github.com/example/presets.git"},
+				},
+				"local_rules": {
+					Type:    config.ImportTypeLocal,
+					Details: config.LocalImportDetails{Path: "local_rules"},
+				},
+			},
+		},
+		Settings: &config.Settings{
+			CacheDir: tmpCacheDir,
+		},
+	}
+
+	tests := map[string]string{
+		"monorepo":    filepath.Join(tmpCacheDir, "monorepo", "packages", "go"),
+		"whole_repo":  filepath.Join(tmpCacheDir, "whole_repo"),
+		"local_rules": filepath.Join(tmpCacheDir, "local_rules"),
+	}
+
+	for name, expectedPath := range tests {
+		t.Run(name, func(t *testing.T) {
+			path, err := cfg.GetImportedPackageRoot(name)
+
+			require.NoError(t, err)
+			assert.Equal(t, expectedPath, path)
+		})
+	}
+}
+
+func TestConfig_ResolvePath(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "project")
+
+	cfg := &config.Config{
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"linked": {
+					Type:    config.ImportTypeLocal,
+					Details: config.LocalImportDetails{Path: "../shared", Mode: config.LocalImportModeLink},
+				},
+			},
+		},
+		Settings: &config.Settings{
+			CacheDir:  ".cache/ajisai",
+			VendorDir: "./.ai/vendor",
+		},
+		Dir: projectDir,
+	}
+
+	root, err := cfg.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, projectDir, root)
+
+	cacheRoot, err := cfg.GetImportedPackageCacheRoot("linked")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectDir, ".cache", "ajisai", "linked"), cacheRoot)
+
+	packageRoot, err := cfg.GetImportedPackageRoot("linked")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(projectDir), "shared"), packageRoot)
+
+	vendorRoot, err := cfg.GetImportedPackageVendorRoot("linked")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectDir, ".ai", "vendor", "linked"), vendorRoot)
+}
@@ -14,6 +14,9 @@ type (
 	Context struct {
 		Config *Config
 
+		// Path is the absolute path of the loaded config file. Empty unless Status is StatusValid.
+		Path string
+
 		// Status represents the state of the configuration.
 		Status Status
 
@@ -39,9 +42,10 @@ const (
 	// Add more status types here as needed...
 )
 
-func StoreInContext(ctx context.Context, cfg *Config) context.Context {
+func StoreInContext(ctx context.Context, cfg *Config, path string) context.Context {
 	return context.WithValue(ctx, configContextKey, &Context{
 		Config: cfg,
+		Path:   path,
 		Status: StatusValid,
 	})
 }
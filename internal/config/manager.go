@@ -66,9 +66,16 @@ func (m *Manager) Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load config file %s: %w", targetPath, err)
 	}
 
+	loadedCfg.Dir = filepath.Dir(targetPath)
 	return m.ApplyDefaults(loadedCfg)
 }
 
+// Path returns the config file that Load reads.
+// It returns a NoFileToReadError if none of the candidate files exist.
+func (m *Manager) Path() (string, error) {
+	return m.getFileToRead()
+}
+
 func (m *Manager) Save(cfg *Config) error {
 	targetPath, err := m.getFileToWrite()
 	if err != nil {
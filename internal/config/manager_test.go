@@ -25,6 +25,7 @@ func TestManager_SaveAndLoad(t *testing.T) {
 				CacheDir:     "/custom/cache/dir",
 				Experimental: true,
 				Namespace:    "test-namespace",
+				VendorDir:    "/custom/vendor/dir",
 			},
 			Workspace: &config.Workspace{
 				Imports: map[string]config.ImportedPackage{
@@ -60,6 +61,7 @@ func TestManager_SaveAndLoad(t *testing.T) {
 					},
 				},
 			},
+			Dir: tempDir,
 		}
 
 		manager, err := config.NewManager(configPath)
@@ -13,14 +13,28 @@ type (
 	}
 
 	serializableSettings struct {
-		CacheDir     string `json:"cacheDir,omitempty"  yaml:"cacheDir,omitempty"`
-		Experimental bool   `json:"experimental"        yaml:"experimental"`
-		Namespace    string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+		CacheDir     string `json:"cacheDir,omitempty"   yaml:"cacheDir,omitempty"`
+		Experimental bool   `json:"experimental"         yaml:"experimental"`
+		Namespace    string `json:"namespace,omitempty"  yaml:"namespace,omitempty"`
+		GitBackend   string `json:"gitBackend,omitempty" yaml:"gitBackend,omitempty"`
+
+		RefreshInterval string `json:"refreshInterval,omitempty" yaml:"refreshInterval,omitempty"`
+		GlobalCache     bool   `json:"globalCache,omitempty"     yaml:"globalCache,omitempty"`
+		VendorDir       string `json:"vendorDir,omitempty"       yaml:"vendorDir,omitempty"`
+
+		GitAuth map[string]serializableGitAuth `json:"gitAuth,omitempty" yaml:"gitAuth,omitempty"`
+		Mirrors map[string]string              `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+	}
+
+	serializableGitAuth struct {
+		TokenEnv string `json:"tokenEnv,omitempty" yaml:"tokenEnv,omitempty"`
+		Username string `json:"username,omitempty" yaml:"username,omitempty"`
+		SSHKey   string `json:"sshKey,omitempty"   yaml:"sshKey,omitempty"`
 	}
 
 	serializablePackage struct {
 		Exports map[string]serializableExportedPresetDefinition `json:"exports,omitempty" yaml:"exports,omitempty"`
-		Name    string                                          `json:"name"              yaml:"name"`
+		Name    string                                          `json:"name,omitempty"    yaml:"name,omitempty"`
 	}
 
 	serializableExportedPresetDefinition struct {
@@ -36,9 +50,20 @@ type (
 	serializableImportedPackage struct {
 		Type       string   `json:"type"                 yaml:"type"`
 		Include    []string `json:"include,omitempty"    yaml:"include,omitempty"`
-		Path       string   `json:"path,omitempty"       yaml:"path,omitempty"`       // only for type: local
+		Path       string   `json:"path,omitempty"       yaml:"path,omitempty"`       // local dir, or git subdirectory
+		Mode       string   `json:"mode,omitempty"       yaml:"mode,omitempty"`       // only for type: local
 		Repository string   `json:"repository,omitempty" yaml:"repository,omitempty"` // only for type: git
 		Revision   string   `json:"revision,omitempty"   yaml:"revision,omitempty"`   // only for type: git
+		Depth      int      `json:"depth,omitempty"      yaml:"depth,omitempty"`      // only for type: git
+		Filter     string   `json:"filter,omitempty"     yaml:"filter,omitempty"`     // only for type: git
+		URL        string   `json:"url,omitempty"        yaml:"url,omitempty"`        // only for type: archive
+		SHA256     string   `json:"sha256,omitempty"     yaml:"sha256,omitempty"`     // only for type: archive
+		Ref        string   `json:"ref,omitempty"        yaml:"ref,omitempty"`        // only for type: oci
+
+		StripComponents int    `json:"stripComponents,omitempty" yaml:"stripComponents,omitempty"` // only for type: archive
+		RefreshInterval string `json:"refreshInterval,omitempty" yaml:"refreshInterval,omitempty"` // not for type: local
+
+		Auth *serializableGitAuth `json:"auth,omitempty" yaml:"auth,omitempty"` // only for type: git
 	}
 
 	serializableAgentIntegration struct {
@@ -117,6 +142,13 @@ func serializeSettings(settings *Settings) *serializableSettings {
 		CacheDir:     settings.CacheDir,
 		Experimental: settings.Experimental,
 		Namespace:    settings.Namespace,
+		GitBackend:   string(settings.GitBackend),
+		GitAuth:      serializeGitAuthByHost(settings.GitAuth),
+		Mirrors:      settings.Mirrors,
+
+		RefreshInterval: string(settings.RefreshInterval),
+		GlobalCache:     settings.GlobalCache,
+		VendorDir:       settings.VendorDir,
 	}
 }
 
@@ -130,10 +162,54 @@ func deserializeSettings(serializableSettings *serializableSettings) *Settings {
 	settings.CacheDir = serializableSettings.CacheDir
 	settings.Experimental = serializableSettings.Experimental
 	settings.Namespace = serializableSettings.Namespace
+	settings.GitBackend = GitBackend(serializableSettings.GitBackend)
+	settings.RefreshInterval = RefreshInterval(serializableSettings.RefreshInterval)
+	settings.GlobalCache = serializableSettings.GlobalCache
+	settings.VendorDir = serializableSettings.VendorDir
+
+	if serializableSettings.GitAuth != nil {
+		settings.GitAuth = make(map[string]GitAuth, len(serializableSettings.GitAuth))
+		for host, auth := range serializableSettings.GitAuth {
+			settings.GitAuth[host] = GitAuth(auth)
+		}
+	}
+
+	settings.Mirrors = serializableSettings.Mirrors
 
 	return &settings
 }
 
+func serializeGitAuthByHost(byHost map[string]GitAuth) map[string]serializableGitAuth {
+	if byHost == nil {
+		return nil
+	}
+
+	s := make(map[string]serializableGitAuth, len(byHost))
+	for host, auth := range byHost {
+		s[host] = serializableGitAuth(auth)
+	}
+
+	return s
+}
+
+// serializeGitAuth returns nil for empty credentials, so that they are omitted.
+func serializeGitAuth(auth GitAuth) *serializableGitAuth {
+	if auth == (GitAuth{}) {
+		return nil
+	}
+
+	s := serializableGitAuth(auth)
+	return &s
+}
+
+func deserializeGitAuth(auth *serializableGitAuth) GitAuth {
+	if auth == nil {
+		return GitAuth{}
+	}
+
+	return GitAuth(*auth)
+}
+
 func serializePackage(pkg *Package) *serializablePackage {
 	if pkg == nil {
 		return nil
@@ -185,6 +261,7 @@ func serializeWorkspace(workspace *Workspace) (*serializableWorkspace, error) {
 				s.Imports[name] = serializableImportedPackage{
 					Type:    string(imp.Type),
 					Path:    details.Path,
+					Mode:    string(details.Mode),
 					Include: imp.Include,
 				}
 			}
@@ -194,7 +271,34 @@ func serializeWorkspace(workspace *Workspace) (*serializableWorkspace, error) {
 					Type:       string(imp.Type),
 					Repository: details.Repository,
 					Revision:   details.Revision,
+					Path:       details.Path,
+					Depth:      details.Depth,
+					Filter:     details.Filter,
+					Auth:       serializeGitAuth(details.Auth),
 					Include:    imp.Include,
+
+					RefreshInterval: string(imp.RefreshInterval),
+				}
+			}
+		case ImportTypeArchive:
+			if details, ok := GetImportDetails[ArchiveImportDetails](imp); ok {
+				s.Imports[name] = serializableImportedPackage{
+					Type:            string(imp.Type),
+					URL:             details.URL,
+					SHA256:          details.SHA256,
+					StripComponents: details.StripComponents,
+					Include:         imp.Include,
+					RefreshInterval: string(imp.RefreshInterval),
+				}
+			}
+		case ImportTypeOCI:
+			if details, ok := GetImportDetails[OCIImportDetails](imp); ok {
+				s.Imports[name] = serializableImportedPackage{
+					Type:    string(imp.Type),
+					Ref:     details.Ref,
+					Include: imp.Include,
+
+					RefreshInterval: string(imp.RefreshInterval),
 				}
 			}
 		default:
@@ -246,6 +350,7 @@ func deserializeWorkspace(sWorkspace *serializableWorkspace) (*Workspace, error)
 				Type: ImportTypeLocal,
 				Details: LocalImportDetails{
 					Path: imp.Path,
+					Mode: LocalImportMode(imp.Mode),
 				},
 				Include: imp.Include,
 			}
@@ -256,13 +361,42 @@ func deserializeWorkspace(sWorkspace *serializableWorkspace) (*Workspace, error)
 				Details: GitImportDetails{
 					Repository: imp.Repository,
 					Revision:   imp.Revision,
+					Path:       imp.Path,
+					Depth:      imp.Depth,
+					Filter:     imp.Filter,
+					Auth:       deserializeGitAuth(imp.Auth),
 				},
-				Include: imp.Include,
+				Include:         imp.Include,
+				RefreshInterval: RefreshInterval(imp.RefreshInterval),
+			}
+			continue
+		case ImportTypeArchive:
+			imports[name] = ImportedPackage{
+				Type: ImportTypeArchive,
+				Details: ArchiveImportDetails{
+					URL:             imp.URL,
+					SHA256:          imp.SHA256,
+					StripComponents: imp.StripComponents,
+				},
+				Include:         imp.Include,
+				RefreshInterval: RefreshInterval(imp.RefreshInterval),
+			}
+			continue
+		case ImportTypeOCI:
+			imports[name] = ImportedPackage{
+				Type:            ImportTypeOCI,
+				Details:         OCIImportDetails{Ref: imp.Ref},
+				Include:         imp.Include,
+				RefreshInterval: RefreshInterval(imp.RefreshInterval),
 			}
 			continue
 		}
 
-		return nil, fmt.Errorf("unsupported import type: %s", imp.Type)
+		// Unsupported types are kept as-is and reported by Config.Validate.
+		imports[name] = ImportedPackage{
+			Type:    ImportType(imp.Type),
+			Include: imp.Include,
+		}
 	}
 	workspace.Imports = imports
 
@@ -1,5 +1,56 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultCacheDir  = "./.cache/ajisai"
+	DefaultNamespace = "ajisai"
+	DefaultVendorDir = "./.ai/vendor"
+)
+
+const (
+	GitBackendAuto GitBackend = "auto" // `git` command if it is found on PATH, built-in implementation otherwise
+	GitBackendCLI  GitBackend = "cli"  // `git` command
+	GitBackendGo   GitBackend = "go"   // Built-in implementation, which does not need `git`
+)
+
+// GitBackend selects the git implementation used to fetch git imports.
+type GitBackend string
+
+// RefreshInterval is how long a fetched git, archive or OCI import is reused before it is fetched again,
+// written as a Go duration (e.g. `30m` or `12h`). Empty and `0` fetch the import on every apply.
+type RefreshInterval string
+
+// Duration returns the parsed interval, or 0 if it is empty or invalid.
+func (i RefreshInterval) Duration() time.Duration {
+	d, err := time.ParseDuration(string(i))
+	if err != nil || d < 0 {
+		return 0
+	}
+
+	return d
+}
+
+// validate returns an error message if the interval is not a non-negative duration.
+func (i RefreshInterval) validate() string {
+	if i == "" {
+		return ""
+	}
+
+	d, err := time.ParseDuration(string(i))
+	if err != nil {
+		return fmt.Sprintf("invalid refresh interval %q (e.g. `30m` or `12h`)", i)
+	}
+	if d < 0 {
+		return "refresh interval must not be negative"
+	}
+
+	return ""
+}
+
 type Settings struct {
 	// Specifies the directory where `ajisai` will store cached data of imported
 	// presets.
@@ -13,6 +64,28 @@ type Settings struct {
 	// For example, ajisai might place presets under `.cursor/prompts/<namespace>/` or
 	// `.cursor/rules/<namespace>/`
 	Namespace string
+
+	// The git implementation used to fetch git imports. Empty means GitBackendAuto.
+	GitBackend GitBackend
+
+	// Credentials for git remotes, keyed by host (e.g. `github.com`).
+	GitAuth map[string]GitAuth
+
+	// URL prefixes of git repositories and archives, mapped to the prefix that replaces them when fetching,
+	// e.g. `https://github.com/` to `https://mirror.example.com/github/`. The longest matching prefix wins.
+	Mirrors map[string]string
+
+	// How long fetched git, archive and OCI imports are reused before they are fetched again.
+	// Imports can override it. Empty means they are fetched on every apply.
+	RefreshInterval RefreshInterval
+
+	// Whether git, archive and OCI imports are fetched through the global cache in the user cache directory,
+	// which is shared by every project. CacheDir then holds links to it.
+	GlobalCache bool
+
+	// Specifies the directory where `ajisai vendor` copies imported presets, to be checked into the repository.
+	// Imports vendored there are applied from it instead of being fetched.
+	VendorDir string
 }
 
 func applyDefaultsToSettings(settings *Settings) *Settings {
@@ -21,11 +94,15 @@ func applyDefaultsToSettings(settings *Settings) *Settings {
 	}
 
 	if settings.CacheDir == "" {
-		settings.CacheDir = "./.cache/ajisai"
+		settings.CacheDir = DefaultCacheDir
 	}
 
 	if settings.Namespace == "" {
-		settings.Namespace = "ajisai"
+		settings.Namespace = DefaultNamespace
+	}
+
+	if settings.VendorDir == "" {
+		settings.VendorDir = DefaultVendorDir
 	}
 
 	return settings
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/versionrange"
+)
+
+type (
+	// ValidationIssue is a single problem found in a config.
+	ValidationIssue struct {
+		// Keys from the document root to the offending value (e.g. `workspace`, `imports`, `foo`).
+		Path []string
+
+		// 1-based position in the config file. Zero if the position is unknown.
+		Line   int
+		Column int
+
+		Message string
+	}
+
+	// ValidationError is returned when a config has one or more problems.
+	// All problems found are reported at once.
+	ValidationError struct {
+		// Path to the config file. Empty if the config was not loaded from a file.
+		File string
+
+		Issues []ValidationIssue
+	}
+)
+
+func (i ValidationIssue) String() string {
+	if len(i.Path) == 0 {
+		return i.Message
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(i.Path, "."), i.Message)
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+
+	if e.File == "" {
+		b.WriteString("invalid config:")
+	} else {
+		fmt.Fprintf(&b, "invalid config file %s:", e.File)
+	}
+
+	for _, issue := range e.Issues {
+		b.WriteString("\n  ")
+		b.WriteString(e.location(issue))
+		b.WriteString(issue.String())
+	}
+
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return nil
+}
+
+// location returns the `file:line:column: ` prefix for the issue, omitting unknown parts.
+func (e *ValidationError) location(issue ValidationIssue) string {
+	switch {
+	case e.File == "":
+		return ""
+	case issue.Line == 0:
+		return e.File + ": "
+	default:
+		return fmt.Sprintf("%s:%d:%d: ", e.File, issue.Line, issue.Column)
+	}
+}
+
+// Validate checks the semantic rules of the config and returns a ValidationError listing every problem.
+func (c *Config) Validate() error {
+	issues := c.validate()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Issues: issues}
+}
+
+func (c *Config) validate() []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+
+	if c.Settings != nil {
+		switch c.Settings.GitBackend {
+		case "", GitBackendAuto, GitBackendCLI, GitBackendGo:
+		default:
+			issues = append(issues, ValidationIssue{
+				Path: []string{"settings", "gitBackend"},
+				Message: fmt.Sprintf(
+					"unsupported git backend %q (supported: %s, %s, %s)",
+					c.Settings.GitBackend,
+					GitBackendAuto,
+					GitBackendCLI,
+					GitBackendGo,
+				),
+			})
+		}
+
+		for _, host := range slices.Sorted(maps.Keys(c.Settings.GitAuth)) {
+			path := []string{"settings", "gitAuth", host}
+			if strings.ContainsAny(host, "/@") {
+				issues = append(issues, ValidationIssue{
+					Path:    path,
+					Message: "key must be a host name (e.g. `github.com`)",
+				})
+			}
+			issues = append(issues, validateGitAuth(path, c.Settings.GitAuth[host])...)
+		}
+
+		if message := c.Settings.RefreshInterval.validate(); message != "" {
+			issues = append(issues, ValidationIssue{Path: []string{"settings", "refreshInterval"}, Message: message})
+		}
+
+		for _, prefix := range slices.Sorted(maps.Keys(c.Settings.Mirrors)) {
+			if c.Settings.Mirrors[prefix] == "" {
+				issues = append(issues, ValidationIssue{
+					Path:    []string{"settings", "mirrors", prefix},
+					Message: "mirror requires the URL prefix that replaces the key",
+				})
+			}
+		}
+	}
+
+	if c.Package != nil {
+		for _, name := range slices.Sorted(maps.Keys(c.Package.Exports)) {
+			export := c.Package.Exports[name]
+			if len(export.Prompts) == 0 && len(export.Rules) == 0 {
+				issues = append(issues, ValidationIssue{
+					Path:    []string{"package", "exports", name},
+					Message: "exported preset must have at least one glob in `prompts` or `rules`",
+				})
+			}
+		}
+	}
+
+	if c.Workspace != nil {
+		for _, name := range slices.Sorted(maps.Keys(c.Workspace.Imports)) {
+			issues = append(issues, validateImportedPackage(name, c.Workspace.Imports[name])...)
+		}
+	}
+
+	return issues
+}
+
+func validateImportedPackage(name string, pkg ImportedPackage) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	path := []string{"workspace", "imports", name}
+
+	switch pkg.Type {
+	case ImportTypeLocal:
+		details, ok := GetImportDetails[LocalImportDetails](pkg)
+		if !ok || details.Path == "" {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "path"),
+				Message: "local import requires `path`",
+			})
+		}
+		if details.Mode != "" && details.Mode != LocalImportModeCopy && details.Mode != LocalImportModeLink {
+			issues = append(issues, ValidationIssue{
+				Path: append(slices.Clone(path), "mode"),
+				Message: fmt.Sprintf(
+					"unsupported local import mode %q (supported: %s, %s)",
+					details.Mode,
+					LocalImportModeCopy,
+					LocalImportModeLink,
+				),
+			})
+		}
+	case ImportTypeGit:
+		details, ok := GetImportDetails[GitImportDetails](pkg)
+		if !ok || details.Repository == "" {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "repository"),
+				Message: "git import requires `repository`",
+			})
+		}
+		if details.Path != "" && !filepath.IsLocal(filepath.FromSlash(details.Path)) {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "path"),
+				Message: "`path` must be a relative path inside the repository",
+			})
+		}
+		if details.Depth < 0 {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "depth"),
+				Message: "`depth` must not be negative",
+			})
+		}
+		if versionrange.IsConstraint(details.Revision) {
+			if _, err := versionrange.Parse(details.Revision); err != nil {
+				issues = append(issues, ValidationIssue{
+					Path:    append(slices.Clone(path), "revision"),
+					Message: err.Error(),
+				})
+			}
+		}
+		if details.Auth != (GitAuth{}) {
+			issues = append(issues, validateGitAuth(append(slices.Clone(path), "auth"), details.Auth)...)
+		}
+	case ImportTypeArchive:
+		issues = append(issues, validateArchiveImportDetails(path, pkg)...)
+	case ImportTypeOCI:
+		details, _ := GetImportDetails[OCIImportDetails](pkg)
+		if details.Ref == "" {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "ref"),
+				Message: "oci import requires `ref`",
+			})
+		} else if _, err := oci.ParseReference(details.Ref); err != nil {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), "ref"),
+				Message: err.Error(),
+			})
+		}
+	default:
+		issues = append(issues, ValidationIssue{
+			Path: append(slices.Clone(path), "type"),
+			Message: fmt.Sprintf(
+				"unsupported import type %q (supported: %s, %s, %s, %s)",
+				pkg.Type,
+				ImportTypeLocal,
+				ImportTypeGit,
+				ImportTypeArchive,
+				ImportTypeOCI,
+			),
+		})
+	}
+
+	if len(pkg.Include) == 0 {
+		issues = append(issues, ValidationIssue{
+			Path:    append(slices.Clone(path), "include"),
+			Message: "`include` must list at least one preset",
+		})
+	}
+
+	if message := pkg.RefreshInterval.validate(); message != "" {
+		issues = append(issues, ValidationIssue{Path: append(slices.Clone(path), "refreshInterval"), Message: message})
+	}
+
+	return issues
+}
+
+func validateGitAuth(path []string, auth GitAuth) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+
+	if auth.TokenEnv == "" && auth.SSHKey == "" {
+		issues = append(issues, ValidationIssue{
+			Path:    path,
+			Message: "git credentials require `tokenEnv` or `sshKey`",
+		})
+	}
+	if auth.Username != "" && auth.TokenEnv == "" {
+		issues = append(issues, ValidationIssue{
+			Path:    append(slices.Clone(path), "username"),
+			Message: "`username` is only used with `tokenEnv`",
+		})
+	}
+
+	return issues
+}
+
+func validateArchiveImportDetails(path []string, pkg ImportedPackage) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	details, _ := GetImportDetails[ArchiveImportDetails](pkg)
+
+	if !isArchiveURL(details.URL) {
+		issues = append(issues, ValidationIssue{
+			Path:    append(slices.Clone(path), "url"),
+			Message: "archive import requires an http(s) or file:// `url`",
+		})
+	}
+	if !isSHA256(details.SHA256) {
+		issues = append(issues, ValidationIssue{
+			Path:    append(slices.Clone(path), "sha256"),
+			Message: "archive import requires `sha256`, the hex-encoded SHA-256 checksum of the archive",
+		})
+	}
+	if details.StripComponents < 0 {
+		issues = append(issues, ValidationIssue{
+			Path:    append(slices.Clone(path), "stripComponents"),
+			Message: "`stripComponents` must not be negative",
+		})
+	}
+
+	return issues
+}
+
+func isArchiveURL(s string) bool {
+	parsed, err := url.Parse(s)
+	return err == nil && slices.Contains([]string{"http", "https", "file"}, parsed.Scheme)
+}
+
+func isSHA256(s string) bool {
+	decoded, err := hex.DecodeString(s)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+func supportedIntegrationKeys() []string {
+	return []string{
+		string(AgentIntegrationTypeCursor),
+		string(AgentIntegrationTypeGitHubCopilot),
+		string(AgentIntegrationTypeWindsurf),
+	}
+}
@@ -0,0 +1,271 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		cfg := &config.Config{
+			Package: &config.Package{
+				Exports: map[string]config.ExportedPresetDefinition{
+					"default": {Rules: []string{"rules/**/*.md"}},
+				},
+			},
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"local": {
+						Type:    config.ImportTypeLocal,
+						Include: []string{"default"},
+						Details: config.LocalImportDetails{Path: "./presets"},
+					},
+				},
+			},
+		}
+
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		cfg := &config.Config{
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"b": {Type: config.ImportTypeLocal, Details: config.LocalImportDetails{}},
+					"a": {Type: config.ImportTypeGit, Include: []string{"default"}, Details: config.GitImportDetails{}},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{Path: []string{"workspace", "imports", "a", "repository"}, Message: "git import requires `repository`"},
+			{Path: []string{"workspace", "imports", "b", "path"}, Message: "local import requires `path`"},
+			{
+				Path:    []string{"workspace", "imports", "b", "include"},
+				Message: "`include` must list at least one preset",
+			},
+		}, validationErr.Issues)
+		assert.Equal(t, "invalid config:\n"+
+			"  workspace.imports.a.repository: git import requires `repository`\n"+
+			"  workspace.imports.b.path: local import requires `path`\n"+
+			"  workspace.imports.b.include: `include` must list at least one preset", validationErr.Error())
+	})
+
+	t.Run("git path outside the repository", func(t *testing.T) {
+		cfg := &config.Config{
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:    config.ImportTypeGit,
+						Include: []string{"default"},
+						Details: config.GitImportDetails{
+							Repository: "https://github.com/example/repo.git",
+							Path:       "../other",
+						},
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"workspace", "imports", "remote", "path"},
+				Message: "`path` must be a relative path inside the repository",
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("unsupported local import mode", func(t *testing.T) {
+		cfg := &config.Config{
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"local": {
+						Type:    config.ImportTypeLocal,
+						Include: []string{"default"},
+						Details: config.LocalImportDetails{Path: "./presets", Mode: "symlink"},
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"workspace", "imports", "local", "mode"},
+				Message: `unsupported local import mode "symlink" (supported: copy, link)`,
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("invalid version range", func(t *testing.T) {
+		cfg := &config.Config{
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:    config.ImportTypeGit,
+						Include: []string{"default"},
+						Details: config.GitImportDetails{
+							Repository: "https://github.com/example/repo.git",
+							Revision:   "^one",
+						},
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"workspace", "imports", "remote", "revision"},
+				Message: `invalid version constraint "^one": "one" is not a version`,
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("archive without url and checksum", func(t *testing.T) {
+		cfg := &config.Config{
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"archive": {
+						Type:    config.ImportTypeArchive,
+						Include: []string{"default"},
+						Details: config.ArchiveImportDetails{
+							URL:             "ftp://example.com/presets.tar.gz",
+							SHA256:          "deadbeef",
+							StripComponents: -1,
+						},
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"workspace", "imports", "archive", "url"},
+				Message: "archive import requires an http(s) or file:// `url`",
+			},
+			{
+				Path:    []string{"workspace", "imports", "archive", "sha256"},
+				Message: "archive import requires `sha256`, the hex-encoded SHA-256 checksum of the archive",
+			},
+			{
+				Path:    []string{"workspace", "imports", "archive", "stripComponents"},
+				Message: "`stripComponents` must not be negative",
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("unsupported git backend", func(t *testing.T) {
+		cfg := &config.Config{Settings: &config.Settings{GitBackend: "libgit2"}}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"settings", "gitBackend"},
+				Message: `unsupported git backend "libgit2" (supported: auto, cli, go)`,
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("invalid git credentials", func(t *testing.T) {
+		cfg := &config.Config{
+			Settings: &config.Settings{
+				GitAuth: map[string]config.GitAuth{
+					"github.com":                 {TokenEnv: "GITHUB_TOKEN"},
+					"https://gitlab.example.com": {SSHKey: "~/.ssh/id_ed25519"},
+				},
+			},
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:    config.ImportTypeGit,
+						Include: []string{"default"},
+						Details: config.GitImportDetails{
+							Repository: "https://github.com/example/repo.git",
+							Auth:       config.GitAuth{Username: "bot"},
+						},
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"settings", "gitAuth", "https://gitlab.example.com"},
+				Message: "key must be a host name (e.g. `github.com`)",
+			},
+			{
+				Path:    []string{"workspace", "imports", "remote", "auth"},
+				Message: "git credentials require `tokenEnv` or `sshKey`",
+			},
+			{
+				Path:    []string{"workspace", "imports", "remote", "auth", "username"},
+				Message: "`username` is only used with `tokenEnv`",
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("invalid refresh interval", func(t *testing.T) {
+		cfg := &config.Config{
+			Settings: &config.Settings{RefreshInterval: "1d"},
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:            config.ImportTypeGit,
+						Include:         []string{"default"},
+						Details:         config.GitImportDetails{Repository: "https://github.com/example/repo.git"},
+						RefreshInterval: "-1h",
+					},
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"settings", "refreshInterval"},
+				Message: "invalid refresh interval \"1d\" (e.g. `30m` or `12h`)",
+			},
+			{
+				Path:    []string{"workspace", "imports", "remote", "refreshInterval"},
+				Message: "refresh interval must not be negative",
+			},
+		}, validationErr.Issues)
+	})
+
+	t.Run("mirror without replacement", func(t *testing.T) {
+		cfg := &config.Config{
+			Settings: &config.Settings{
+				Mirrors: map[string]string{
+					"https://github.com/":  "https://mirror.example.com/github/",
+					"https://example.com/": "",
+				},
+			},
+		}
+
+		var validationErr *config.ValidationError
+		require.ErrorAs(t, cfg.Validate(), &validationErr)
+		assert.Equal(t, []config.ValidationIssue{
+			{
+				Path:    []string{"settings", "mirrors", "https://example.com/"},
+				Message: "mirror requires the URL prefix that replaces the key",
+			},
+		}, validationErr.Issues)
+	})
+}
@@ -1,18 +1,26 @@
 package config
 
 const (
-	ImportTypeLocal ImportType = "local" // Local file system import
-	ImportTypeGit   ImportType = "git"   // Git repository import
+	ImportTypeLocal   ImportType = "local"   // Local file system import
+	ImportTypeGit     ImportType = "git"     // Git repository import
+	ImportTypeArchive ImportType = "archive" // Archive (`.tar.gz`, `.zip`) downloaded from a URL
+	ImportTypeOCI     ImportType = "oci"     // Artifact in an OCI registry
 
 	AgentIntegrationTypeCursor        AgentIntegrationType = "cursor"         // Cursor output target
 	AgentIntegrationTypeGitHubCopilot AgentIntegrationType = "github-copilot" // GitHub Copilot output target
 	AgentIntegrationTypeWindsurf      AgentIntegrationType = "windsurf"       // WindSurf output target
+
+	LocalImportModeCopy LocalImportMode = "copy" // Copy the exported files into the cache directory
+	LocalImportModeLink LocalImportMode = "link" // Read the files from the source directory in place
 )
 
 type (
 	ImportType           string
 	AgentIntegrationType string
 
+	// LocalImportMode selects how a local import is read. Empty means LocalImportModeCopy.
+	LocalImportMode string
+
 	/*
 		Workspace defines a workspace definition.
 	*/
@@ -35,7 +43,7 @@ type (
 	// ImportedPackage defines a package that will be imported into the workspace.
 	ImportedPackage struct {
 		/*
-			Type identifier (e.g., "local", "git").
+			Type identifier (e.g., "local", "git", "archive", "oci").
 		*/
 		Type ImportType
 
@@ -48,6 +56,11 @@ type (
 			Type-specific configuration details.
 		*/
 		Details ImportDetails
+
+		/*
+			Overrides Settings.RefreshInterval for this import if not empty. Ignored for local imports.
+		*/
+		RefreshInterval RefreshInterval
 	}
 
 	// ImportDetails is an interface for type-specific input source configurations.
@@ -56,13 +69,38 @@ type (
 	}
 
 	LocalImportDetails struct {
-		Path string // Path to the local directory
+		Path string          // Path to the local directory
+		Mode LocalImportMode // Optional `copy` (default) or `link`
 	}
 
 	// GitImportDetails holds configuration specific to Git repository inputs.
 	GitImportDetails struct {
-		Repository string // URL of the Git repository
-		Revision   string // Optional branch, tag, or commit SHA (defaults to latest)
+		Repository string  // URL of the Git repository
+		Revision   string  // Optional branch, tag, or commit SHA (defaults to latest)
+		Path       string  // Optional subdirectory of the repository that contains the package
+		Depth      int     // Optional number of commits to fetch (0 fetches the full history)
+		Filter     string  // Optional partial clone filter (e.g. `blob:none`)
+		Auth       GitAuth // Optional credentials, preferred over those for the host in Settings.GitAuth
+	}
+
+	// GitAuth holds credentials for a private git repository.
+	// They are passed to git for each command and never written to the clone or printed.
+	GitAuth struct {
+		TokenEnv string // Environment variable holding an access token for HTTP(S) remotes
+		Username string // User name sent with the token (defaults to `x-access-token`)
+		SSHKey   string // Path to the private key for SSH remotes
+	}
+
+	// ArchiveImportDetails holds configuration specific to archive inputs.
+	ArchiveImportDetails struct {
+		URL             string // http(s) or `file://` URL of a `.tar.gz`, `.tar` or `.zip` archive
+		SHA256          string // Required hex-encoded SHA-256 checksum of the archive
+		StripComponents int    // Optional number of leading path components to remove from each entry
+	}
+
+	// OCIImportDetails holds configuration specific to OCI registry inputs.
+	OCIImportDetails struct {
+		Ref string // Reference to the artifact (e.g. `registry.example.com/ai/presets:1.2.0`)
 	}
 
 	// AgentIntegrations defines specific integrations for each agent.
@@ -85,6 +123,13 @@ type (
 	}
 )
 
+// IsLinked reports whether the package is a local import that is read from its source directory in place,
+// instead of being copied into the cache directory.
+func (p ImportedPackage) IsLinked() bool {
+	details, isLocal := GetImportDetails[LocalImportDetails](p)
+	return isLocal && details.Mode == LocalImportModeLink
+}
+
 // GetImportDetails safely performs a type assertion on UsingPresetPackageSource.Details.
 func GetImportDetails[T ImportDetails](is ImportedPackage) (T, bool) {
 	details, ok := is.Details.(T)
@@ -95,6 +140,10 @@ func (d LocalImportDetails) isImportDetails() {}
 
 func (d GitImportDetails) isImportDetails() {}
 
+func (d ArchiveImportDetails) isImportDetails() {}
+
+func (d OCIImportDetails) isImportDetails() {}
+
 func applyDefaultsToWorkspace(workspace *Workspace) *Workspace {
 	if workspace == nil {
 		workspace = &Workspace{}
@@ -2,10 +2,17 @@ package config
 
 import (
 	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 
 	"github.com/sushichan044/ajisai/utils"
 )
@@ -35,12 +42,28 @@ func (l *yamlLoader) Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file %s: %w", resolvedPath, err)
 	}
 
+	file, parseErr := parser.ParseBytes(body, 0)
+	if parseErr != nil {
+		return nil, &ValidationError{File: resolvedPath, Issues: []ValidationIssue{issueFromYAMLError(nil, parseErr)}}
+	}
+	root := documentBody(file)
+
+	// Values that fail to decode are left out of cfgData, so the remaining ones are still validated below.
 	var cfgData SerializableConfig
-	if yamlErr := yaml.Unmarshal(body, &cfgData); yamlErr != nil {
-		return nil, fmt.Errorf("failed to unmarshal config file %s: %w", resolvedPath, yamlErr)
+	issues := decodeConfig(root, &cfgData)
+	issues = append(issues, findUnknownKeys(root, []string{"workspace", "integrations"}, supportedIntegrationKeys())...)
+
+	cfg, err := l.serializer.Deserialize(cfgData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize config file %s: %w", resolvedPath, err)
 	}
+	issues = append(issues, cfg.validate()...)
 
-	return l.serializer.Deserialize(cfgData)
+	if len(issues) > 0 {
+		return nil, newValidationError(resolvedPath, root, issues)
+	}
+
+	return cfg, nil
 }
 
 func (l *yamlLoader) Save(configPath string, cfg *Config) error {
@@ -64,3 +87,212 @@ func (l *yamlLoader) Save(configPath string, cfg *Config) error {
 
 	return nil
 }
+
+// documentBody returns the root node of the first document, or nil for an empty file.
+func documentBody(file *ast.File) ast.Node {
+	if len(file.Docs) == 0 {
+		return nil
+	}
+
+	return file.Docs[0].Body
+}
+
+// decodeConfig decodes the document into cfg.
+// If the document cannot be decoded as a whole, each section and each import is decoded separately
+// so that every type error is reported instead of only the first one.
+func decodeConfig(root ast.Node, cfg *SerializableConfig) []ValidationIssue {
+	if root == nil {
+		return nil
+	}
+
+	if err := yaml.NodeToValue(root, cfg); err == nil {
+		return nil
+	}
+
+	issues := make([]ValidationIssue, 0)
+
+	rootMap, isMap := root.(ast.MapNode)
+	if !isMap {
+		decodeNode(nil, root, cfg, &issues)
+		return issues
+	}
+
+	for key, value := range mapEntries(rootMap) {
+		switch key {
+		case "settings":
+			decodeNode([]string{key}, value, &cfg.Settings, &issues)
+		case "package":
+			decodeNode([]string{key}, value, &cfg.Package, &issues)
+		case "workspace":
+			cfg.Workspace = &serializableWorkspace{}
+			issues = append(issues, decodeWorkspace(value, cfg.Workspace)...)
+		}
+	}
+
+	return issues
+}
+
+func decodeWorkspace(node ast.Node, workspace *serializableWorkspace) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+
+	workspaceMap, isMap := node.(ast.MapNode)
+	if !isMap {
+		decodeNode([]string{"workspace"}, node, workspace, &issues)
+		return issues
+	}
+
+	for key, value := range mapEntries(workspaceMap) {
+		switch key {
+		case "integrations":
+			decodeNode([]string{"workspace", key}, value, &workspace.Integrations, &issues)
+		case "imports":
+			importsMap, isImportsMap := value.(ast.MapNode)
+			if !isImportsMap {
+				decodeNode([]string{"workspace", key}, value, &workspace.Imports, &issues)
+				continue
+			}
+
+			workspace.Imports = make(map[string]serializableImportedPackage)
+			for name, importNode := range mapEntries(importsMap) {
+				var imp serializableImportedPackage
+				if decodeNode([]string{"workspace", key, name}, importNode, &imp, &issues) {
+					workspace.Imports[name] = imp
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// decodeNode decodes node into v and records an issue on failure.
+func decodeNode(path []string, node ast.Node, v any, issues *[]ValidationIssue) bool {
+	if err := yaml.NodeToValue(node, v); err != nil {
+		*issues = append(*issues, issueFromYAMLError(path, err))
+		return false
+	}
+
+	return true
+}
+
+// findUnknownKeys reports keys of the mapping at path that are not in known.
+func findUnknownKeys(root ast.Node, path []string, known []string) []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+
+	node, found := lookupNode(root, path)
+	if !found {
+		return issues
+	}
+
+	mapNode, isMap := node.(ast.MapNode)
+	if !isMap {
+		return issues
+	}
+
+	for key := range mapEntries(mapNode) {
+		if !slices.Contains(known, key) {
+			issues = append(issues, ValidationIssue{
+				Path:    append(slices.Clone(path), key),
+				Message: fmt.Sprintf("unknown key %q (supported: %s)", key, strings.Join(known, ", ")),
+			})
+		}
+	}
+
+	return issues
+}
+
+// newValidationError resolves the source position of each issue and sorts them in document order.
+func newValidationError(file string, root ast.Node, issues []ValidationIssue) *ValidationError {
+	for i := range issues {
+		if issues[i].Line != 0 {
+			continue
+		}
+		issues[i].Line, issues[i].Column = positionOf(root, issues[i].Path)
+	}
+
+	slices.SortStableFunc(issues, func(a, b ValidationIssue) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	return &ValidationError{File: file, Issues: issues}
+}
+
+// positionOf returns the position of the deepest key of path that exists in the document.
+func positionOf(root ast.Node, path []string) (int, int) {
+	line, column := 0, 0
+
+	node := root
+	for _, key := range path {
+		mapNode, isMap := node.(ast.MapNode)
+		if !isMap {
+			break
+		}
+
+		entry := findEntry(mapNode, key)
+		if entry == nil {
+			break
+		}
+
+		pos := entry.Key.GetToken().Position
+		line, column = pos.Line, pos.Column
+		node = entry.Value
+	}
+
+	return line, column
+}
+
+func lookupNode(root ast.Node, path []string) (ast.Node, bool) {
+	node := root
+	for _, key := range path {
+		mapNode, isMap := node.(ast.MapNode)
+		if !isMap {
+			return nil, false
+		}
+
+		entry := findEntry(mapNode, key)
+		if entry == nil {
+			return nil, false
+		}
+		node = entry.Value
+	}
+
+	return node, node != nil
+}
+
+func findEntry(mapNode ast.MapNode, key string) *ast.MappingValueNode {
+	entries := mapNode.MapRange()
+	for entries.Next() {
+		if entries.Key().GetToken().Value == key {
+			return entries.KeyValue()
+		}
+	}
+
+	return nil
+}
+
+// mapEntries iterates over the keys and values of a mapping node in document order.
+func mapEntries(mapNode ast.MapNode) iter.Seq2[string, ast.Node] {
+	return func(yield func(string, ast.Node) bool) {
+		entries := mapNode.MapRange()
+		for entries.Next() {
+			if !yield(entries.Key().GetToken().Value, entries.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// issueFromYAMLError converts a goccy/go-yaml error into an issue, keeping its source position if available.
+func issueFromYAMLError(path []string, err error) ValidationIssue {
+	issue := ValidationIssue{Path: path, Message: err.Error()}
+
+	var yamlErr yaml.Error
+	if errors.As(err, &yamlErr) {
+		issue.Message = yamlErr.GetMessage()
+		if tk := yamlErr.GetToken(); tk != nil && tk.Position != nil {
+			issue.Line, issue.Column = tk.Position.Line, tk.Position.Column
+		}
+	}
+
+	return issue
+}
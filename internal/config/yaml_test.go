@@ -136,6 +136,8 @@ workspace:
     import1:
       type: local
       path: path/to/import1
+      include:
+        - default
 `,
 			expected: &config.Config{
 				Settings: &config.Settings{},
@@ -145,6 +147,7 @@ workspace:
 						"import1": {
 							Type:    config.ImportTypeLocal,
 							Details: config.LocalImportDetails{Path: "path/to/import1"},
+							Include: []string{"default"},
 						},
 					},
 					Integrations: &config.AgentIntegrations{},
@@ -174,6 +177,139 @@ workspace:
 				},
 			},
 		},
+		{
+			name: "Git credentials",
+			yamlBody: `
+settings:
+  gitAuth:
+    github.com:
+      tokenEnv: GITHUB_TOKEN
+workspace:
+  imports:
+    private:
+      type: git
+      repository: git@git.example.com:org/presets.git
+      auth:
+        sshKey: ~/.ssh/presets
+      include:
+        - default
+`,
+			expected: &config.Config{
+				Settings: &config.Settings{
+					GitAuth: map[string]config.GitAuth{"github.com": {TokenEnv: "GITHUB_TOKEN"}},
+				},
+				Package: &config.Package{},
+				Workspace: &config.Workspace{
+					Imports: map[string]config.ImportedPackage{
+						"private": {
+							Type: config.ImportTypeGit,
+							Details: config.GitImportDetails{
+								Repository: "git@git.example.com:org/presets.git",
+								Auth:       config.GitAuth{SSHKey: "~/.ssh/presets"},
+							},
+							Include: []string{"default"},
+						},
+					},
+					Integrations: &config.AgentIntegrations{},
+				},
+			},
+		},
+		{
+			name: "Mirrors",
+			yamlBody: `
+settings:
+  mirrors:
+    https://github.com/: https://mirror.example.com/github/
+    git@github.com:: https://mirror.example.com/github/
+`,
+			expected: &config.Config{
+				Settings: &config.Settings{
+					Mirrors: map[string]string{
+						"https://github.com/": "https://mirror.example.com/github/",
+						"git@github.com:":     "https://mirror.example.com/github/",
+					},
+				},
+				Package:   &config.Package{},
+				Workspace: &config.Workspace{},
+			},
+		},
+		{
+			name: "Refresh interval",
+			yamlBody: `
+settings:
+  refreshInterval: 12h
+workspace:
+  imports:
+    always:
+      type: archive
+      url: https://example.com/presets.tar.gz
+      sha256: 0f1e2d0000000000000000000000000000000000000000000000000000000000
+      refreshInterval: "0"
+      include:
+        - default
+`,
+			expected: &config.Config{
+				Settings: &config.Settings{RefreshInterval: "12h"},
+				Package:  &config.Package{},
+				Workspace: &config.Workspace{
+					Imports: map[string]config.ImportedPackage{
+						"always": {
+							Type: config.ImportTypeArchive,
+							Details: config.ArchiveImportDetails{
+								URL:    "https://example.com/presets.tar.gz",
+								SHA256: "0f1e2d0000000000000000000000000000000000000000000000000000000000",
+							},
+							Include:         []string{"default"},
+							RefreshInterval: "0",
+						},
+					},
+					Integrations: &config.AgentIntegrations{},
+				},
+			},
+		},
+		{
+			name: "Linked local import",
+			yamlBody: `
+workspace:
+  imports:
+    shared:
+      type: local
+      path: ../shared-presets
+      mode: link
+      include:
+        - default
+`,
+			expected: &config.Config{
+				Settings: &config.Settings{},
+				Package:  &config.Package{},
+				Workspace: &config.Workspace{
+					Imports: map[string]config.ImportedPackage{
+						"shared": {
+							Type: config.ImportTypeLocal,
+							Details: config.LocalImportDetails{
+								Path: "../shared-presets",
+								Mode: config.LocalImportModeLink,
+							},
+							Include: []string{"default"},
+						},
+					},
+					Integrations: &config.AgentIntegrations{},
+				},
+			},
+		},
+		{
+			name: "Global cache",
+			yamlBody: `
+settings:
+  globalCache: true
+  vendorDir: ./vendor/ai
+`,
+			expected: &config.Config{
+				Settings:  &config.Settings{GlobalCache: true, VendorDir: "./vendor/ai"},
+				Package:   &config.Package{},
+				Workspace: &config.Workspace{},
+			},
+		},
 		{
 			name: "Ignores unknown properties",
 			yamlBody: `
@@ -392,3 +528,128 @@ workspace:
 		})
 	}
 }
+
+func TestYamlLoader_Load_ValidationError(t *testing.T) {
+	tmp := t.TempDir()
+
+	tcs := []struct {
+		name     string
+		yamlBody string
+		expected []config.ValidationIssue
+	}{
+		{
+			name: "Collects every problem with positions",
+			yamlBody: `package:
+  exports:
+    empty: {}
+workspace:
+  imports:
+    remote:
+      type: git
+      include:
+        - default
+    local:
+      type: local
+      path: ./presets
+    unknown:
+      type: svn
+      include:
+        - default
+  integrations:
+    cursor:
+      enabled: true
+    vscode:
+      enabled: true
+`,
+			expected: []config.ValidationIssue{
+				{
+					Path:    []string{"package", "exports", "empty"},
+					Line:    3,
+					Column:  5,
+					Message: "exported preset must have at least one glob in `prompts` or `rules`",
+				},
+				{
+					Path:    []string{"workspace", "imports", "remote", "repository"},
+					Line:    6,
+					Column:  5,
+					Message: "git import requires `repository`",
+				},
+				{
+					Path:    []string{"workspace", "imports", "local", "include"},
+					Line:    10,
+					Column:  5,
+					Message: "`include` must list at least one preset",
+				},
+				{
+					Path:    []string{"workspace", "imports", "unknown", "type"},
+					Line:    14,
+					Column:  7,
+					Message: `unsupported import type "svn" (supported: local, git, archive, oci)`,
+				},
+				{
+					Path:    []string{"workspace", "integrations", "vscode"},
+					Line:    20,
+					Column:  5,
+					Message: `unknown key "vscode" (supported: cursor, github-copilot, windsurf)`,
+				},
+			},
+		},
+		{
+			name: "Collects type errors from each import",
+			yamlBody: `workspace:
+  imports:
+    first:
+      type: local
+      path: ./first
+      include: default
+    second:
+      type: local
+      path: [./second]
+      include:
+        - default
+`,
+			expected: []config.ValidationIssue{
+				{
+					Path:    []string{"workspace", "imports", "first"},
+					Line:    6,
+					Column:  16,
+					Message: "string was used where sequence is expected",
+				},
+				{
+					Path:    []string{"workspace", "imports", "second"},
+					Line:    9,
+					Column:  13,
+					Message: "cannot unmarshal []interface {} into Go struct field serializableImportedPackage.Path of type string",
+				},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgPath := filepath.Join(tmp, tc.name+".yaml")
+			require.NoError(t, os.WriteFile(cfgPath, []byte(tc.yamlBody), 0644))
+
+			_, loadErr := config.NewYAMLLoader().Load(cfgPath)
+
+			var validationErr *config.ValidationError
+			require.ErrorAs(t, loadErr, &validationErr)
+			assert.Equal(t, cfgPath, validationErr.File)
+			if res := cmp.Diff(tc.expected, validationErr.Issues); res != "" {
+				t.Errorf("mismatch (-want +got):\n%s", res)
+			}
+		})
+	}
+}
+
+func TestYamlLoader_Load_SyntaxError(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "ajisai.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("workspace:\n  imports: [\n"), 0644))
+
+	_, loadErr := config.NewYAMLLoader().Load(cfgPath)
+
+	var validationErr *config.ValidationError
+	require.ErrorAs(t, loadErr, &validationErr)
+	require.Len(t, validationErr.Issues, 1)
+	assert.NotZero(t, validationErr.Issues[0].Line)
+}
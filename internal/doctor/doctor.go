@@ -0,0 +1,534 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/loader"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+// Doctor diagnoses the environment and the workspace configuration.
+type Doctor struct {
+	cmdRunner utils.CommandRunner
+	lookPath  func(file string) (string, error)
+}
+
+// New creates a Doctor that runs real commands.
+func New() *Doctor {
+	return &Doctor{cmdRunner: &utils.DefaultCommandRunner{}, lookPath: exec.LookPath}
+}
+
+// NewWithRunner creates a Doctor with a custom command runner and executable lookup (for testing).
+func NewWithRunner(runner utils.CommandRunner, lookPath func(file string) (string, error)) *Doctor {
+	return &Doctor{cmdRunner: runner, lookPath: lookPath}
+}
+
+// Diagnose runs every check against the loaded configuration and returns the report.
+// Checks that depend on a valid config are skipped when the config could not be loaded.
+func (d *Doctor) Diagnose(cfgCtx *config.Context) *Report {
+	report := &Report{}
+
+	configGroup := report.group("Config")
+	switch cfgCtx.Status {
+	case config.StatusValid:
+		configGroup.pass("configuration loaded")
+	case config.StatusNotFound:
+		configGroup.fail("config file not found (create ajisai.yml or pass --config)")
+		return report
+	case config.StatusValidationFailed:
+		var validationErr *config.ValidationError
+		if !errors.As(cfgCtx.ValidationError, &validationErr) {
+			configGroup.fail("configuration is invalid: %s", cfgCtx.ValidationError)
+			return report
+		}
+
+		for _, issue := range validationErr.Issues {
+			if issue.Line > 0 {
+				configGroup.fail("%s:%d:%d: %s", validationErr.File, issue.Line, issue.Column, issue)
+			} else {
+				configGroup.fail("%s: %s", validationErr.File, issue)
+			}
+		}
+		return report
+	}
+
+	cfg := cfgCtx.Config
+
+	lock, err := lockfile.Load(lockfile.PathForConfig(cfgCtx.Path))
+	if err != nil {
+		configGroup.fail("could not read lockfile: %s", err)
+		lock = lockfile.New()
+	}
+
+	gitAvailable := d.checkEnvironment(report.group("Environment"), cfg)
+	d.checkImports(report.group("Imports"), cfg, lock, gitAvailable)
+	d.checkOutputs(report.group("Outputs"), cfg)
+
+	return report
+}
+
+// checkEnvironment checks external tools required by the imports and reports whether git is available.
+func (d *Doctor) checkEnvironment(group *Group, cfg *config.Config) bool {
+	requiresGit := slices.ContainsFunc(slices.Collect(maps.Values(cfg.Workspace.Imports)), isGitImport)
+	if !requiresGit {
+		group.pass("git is not required (no git imports)")
+		return false
+	}
+
+	if cfg.Settings.GitBackend == config.GitBackendGo {
+		group.pass("git is not required (git imports use the built-in implementation)")
+		return false
+	}
+
+	gitPath, err := d.lookPath("git")
+	if err != nil && cfg.Settings.GitBackend == config.GitBackendCLI {
+		group.fail("git is required by git imports but was not found on PATH")
+		return false
+	}
+	if err != nil {
+		group.pass("git was not found on PATH; git imports use the built-in implementation")
+		return false
+	}
+
+	group.pass("git found at %s", gitPath)
+	return true
+}
+
+func (d *Doctor) checkImports(group *Group, cfg *config.Config, lock *lockfile.Lockfile, gitAvailable bool) {
+	if len(cfg.Workspace.Imports) == 0 {
+		group.warn("no packages are imported")
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Workspace.Imports)) {
+		if checkVendored(group, cfg, lock, name) {
+			continue
+		}
+		d.checkImport(group, cfg, lock, name, gitAvailable)
+	}
+}
+
+// checkImport checks the source directory or the cache of an import, and the presets it includes.
+func (d *Doctor) checkImport(
+	group *Group,
+	cfg *config.Config,
+	lock *lockfile.Lockfile,
+	name string,
+	gitAvailable bool,
+) {
+	pkg := cfg.Workspace.Imports[name]
+	resultsBefore := len(group.Results)
+
+	sourceExists := checkLocalSource(group, cfg, name)
+	switch {
+	case pkg.IsLinked():
+		// Linked local imports are read from their source directory without a cache.
+		if !sourceExists {
+			return
+		}
+	case !d.checkCache(group, cfg, lock, name, gitAvailable):
+		return
+	}
+
+	checkIncludes(group, cfg, name)
+
+	switch {
+	case len(group.Results) > resultsBefore:
+		// Problems were reported above.
+	case pkg.IsLinked():
+		group.pass("%s: source directory is linked", name)
+	default:
+		group.pass("%s: cache is up to date with the config", name)
+	}
+}
+
+// checkLocalSource checks that the source directory of a local import exists, and reports whether it does.
+// It reports true for other imports.
+func checkLocalSource(group *Group, cfg *config.Config, name string) bool {
+	localDetails, isLocal := config.GetImportDetails[config.LocalImportDetails](cfg.Workspace.Imports[name])
+	if !isLocal {
+		return true
+	}
+
+	srcAbsDir, err := cfg.ResolvePath(localDetails.Path)
+	if err != nil {
+		group.fail("%s: could not resolve source path %s: %s", name, localDetails.Path, err)
+		return false
+	}
+	if exists, _ := utils.IsDirExists(srcAbsDir); !exists {
+		group.fail("%s: source directory %s does not exist", name, srcAbsDir)
+		return false
+	}
+
+	return true
+}
+
+// checkIncludes checks that the package exports every preset the import includes.
+func checkIncludes(group *Group, cfg *config.Config, name string) {
+	manifest, err := loader.NewAgentPresetPackageLoader(cfg).ResolvePackageManifest(name)
+	if err != nil {
+		group.fail("%s: could not load package manifest: %s", name, err)
+		return
+	}
+
+	exported := slices.Sorted(maps.Keys(manifest.Exports))
+	for _, include := range cfg.Workspace.Imports[name].Include {
+		if !slices.Contains(exported, include) {
+			group.fail(
+				"%s: included preset %q is not exported by the package (exported: %s)",
+				name,
+				include,
+				strings.Join(exported, ", "),
+			)
+		}
+	}
+}
+
+// checkVendored checks the vendored copy of a remote import against the config and the lockfile,
+// and reports whether the import is vendored. Vendored imports are applied without their cache.
+func checkVendored(group *Group, cfg *config.Config, lock *lockfile.Lockfile, name string) bool {
+	pkg := cfg.Workspace.Imports[name]
+	if pkg.Type == config.ImportTypeLocal {
+		return false
+	}
+
+	vendorDir, err := cfg.GetImportedPackageVendorRoot(name)
+	if err != nil {
+		group.fail("%s: could not resolve vendor directory: %s", name, err)
+		return true
+	}
+
+	metadata, err := vendoring.Read(vendorDir)
+	if err != nil {
+		group.fail("%s: %s", name, err)
+		return true
+	}
+	if metadata == nil {
+		if exists, _ := utils.IsDirExists(vendorDir); exists {
+			group.warn("%s: %s is not a vendored copy and is ignored (run `ajisai vendor`)", name, vendorDir)
+		}
+		return false
+	}
+
+	resultsBefore := len(group.Results)
+
+	switch {
+	case !slices.Equal(metadata.Import.Include, pkg.Include):
+		group.fail(
+			"%s: vendored copy includes %s but config includes %s (run `ajisai vendor`)",
+			name,
+			strings.Join(metadata.Import.Include, ", "),
+			strings.Join(pkg.Include, ", "),
+		)
+	case !metadata.Import.Matches(pkg):
+		group.fail("%s: vendored copy was made for another source than the config (run `ajisai vendor`)", name)
+	default:
+		if entry, isLocked := lock.Imports[name]; isLocked && entry.Matches(pkg) &&
+			(entry.Commit != metadata.Import.Commit || entry.Digest != metadata.Import.Digest) {
+			group.warn(
+				"%s: vendored copy is not at the revision pinned in %s (run `ajisai vendor`)",
+				name,
+				lockfile.FileName,
+			)
+		}
+	}
+
+	if modified, modifiedErr := metadata.IsModified(vendorDir); modifiedErr != nil {
+		group.fail("%s: could not inspect vendored copy %s: %s", name, vendorDir, modifiedErr)
+	} else if modified {
+		group.warn(
+			"%s: files in %s were changed after vendoring (run `ajisai vendor` to restore them)",
+			name,
+			vendorDir,
+		)
+	}
+
+	manifest, _, err := loader.ExportedFiles(vendorDir)
+	if err != nil {
+		group.fail("%s: could not load vendored package manifest: %s", name, err)
+		return true
+	}
+	exported := slices.Sorted(maps.Keys(manifest.Exports))
+	for _, include := range pkg.Include {
+		if !slices.Contains(exported, include) {
+			group.fail(
+				"%s: included preset %q is not exported by the vendored package (exported: %s)",
+				name,
+				include,
+				strings.Join(exported, ", "),
+			)
+		}
+	}
+
+	if len(group.Results) == resultsBefore {
+		group.pass("%s: vendored copy is up to date with the config", name)
+	}
+
+	return true
+}
+
+// checkCache checks the cache of an import, and reports whether it can be loaded from.
+func (d *Doctor) checkCache(
+	group *Group,
+	cfg *config.Config,
+	lock *lockfile.Lockfile,
+	name string,
+	gitAvailable bool,
+) bool {
+	pkg := cfg.Workspace.Imports[name]
+
+	cacheRoot, err := cfg.GetImportedPackageCacheRoot(name)
+	if err != nil {
+		group.fail("%s: could not resolve cache directory: %s", name, err)
+		return false
+	}
+
+	cached, err := utils.IsDirExists(cacheRoot)
+	if err != nil {
+		group.fail("%s: could not inspect cache %s: %s", name, cacheRoot, err)
+		return false
+	}
+	if !cached {
+		group.warn("%s: not fetched yet (run `ajisai apply`)", name)
+		return false
+	}
+
+	if !checkCacheMetadata(group, lock, name, pkg, cacheRoot) {
+		return false
+	}
+
+	if gitDetails, isGit := config.GetImportDetails[config.GitImportDetails](pkg); isGit {
+		return d.checkGitCache(group, cfg, name, gitDetails, cacheRoot, gitAvailable)
+	}
+
+	return true
+}
+
+// checkCacheMetadata checks that the cache of an import was fetched from the source in the config, and at its
+// revision or reference, and reports whether it was. The cache is fetched again on the next apply otherwise.
+func checkCacheMetadata(
+	group *Group,
+	lock *lockfile.Lockfile,
+	name string,
+	pkg config.ImportedPackage,
+	cacheRoot string,
+) bool {
+	metadata, err := fetcher.ReadCacheMetadata(cacheRoot)
+	if err != nil {
+		group.fail("%s: %s", name, err)
+		return false
+	}
+	if metadata == nil {
+		group.warn("%s: cache has no record of its source (it is fetched again on the next `ajisai apply`)", name)
+		return false
+	}
+
+	if !metadata.MatchesImport(pkg) {
+		group.warn(
+			"%s: cache was fetched from %s but config expects %s (it is fetched again on the next `ajisai apply`)",
+			name,
+			describeSource(*metadata),
+			describeSource(fetcher.NewCacheMetadata(pkg)),
+		)
+		return false
+	}
+
+	if fetched, expected, changed := changedRevision(lock, name, pkg, *metadata); changed {
+		group.warn(
+			"%s: cache was fetched at %s but config expects %s (it is fetched again on the next `ajisai apply`)",
+			name,
+			fetched,
+			expected,
+		)
+		return false
+	}
+
+	return true
+}
+
+// checkGitCache checks that the cache of a git import is a clone of the repository that has the subdirectory
+// of the import, and reports whether it can be loaded from.
+func (d *Doctor) checkGitCache(
+	group *Group,
+	cfg *config.Config,
+	name string,
+	gitDetails config.GitImportDetails,
+	cacheRoot string,
+	gitAvailable bool,
+) bool {
+	if gitDetails.Path != "" {
+		packageRoot, _ := cfg.GetImportedPackageRoot(name)
+		if exists, _ := utils.IsDirExists(packageRoot); !exists {
+			group.fail("%s: path %s does not exist in the repository", name, gitDetails.Path)
+			return false
+		}
+	}
+
+	if !gitAvailable {
+		return true
+	}
+
+	// The cache is cloned from the mirror of the repository, if any.
+	repository := fetcher.NewMirrors(cfg.Settings.Mirrors).Rewrite(gitDetails.Repository)
+	origin, err := d.cmdRunner.OutputInDir(cacheRoot, "git", "remote", "get-url", "origin")
+	switch {
+	case err != nil:
+		group.fail("%s: cache %s is not a git repository (run `ajisai clean --force`)", name, cacheRoot)
+	case origin != repository:
+		group.warn(
+			"%s: cache was cloned from %s but config expects %s (it is cloned again on the next `ajisai apply`)",
+			name,
+			origin,
+			repository,
+		)
+	}
+
+	return true
+}
+
+// changedRevision reports whether the cache of a git or OCI import was fetched at neither the revision or
+// reference in the config nor the commit, tag or digest pinned for it in the lockfile, and returns both to report.
+func changedRevision(
+	lock *lockfile.Lockfile,
+	name string,
+	pkg config.ImportedPackage,
+	metadata fetcher.CacheMetadata,
+) (string, string, bool) {
+	switch pkg.Type {
+	case config.ImportTypeGit:
+		details, _ := config.GetImportDetails[config.GitImportDetails](pkg)
+		pinned := []string{details.Revision}
+		if commit, isPinned := lock.PinnedCommit(name, pkg); isPinned {
+			// A version range is fetched at the tag it resolved to, until it is fetched at the pinned commit.
+			pinned = append(pinned, commit)
+			if version := lock.Imports[name].Version; version != "" {
+				pinned = append(pinned, version)
+			}
+		}
+		changed := !slices.Contains(pinned, metadata.Revision)
+		return describeRevision(metadata.Revision), describeRevision(details.Revision), changed
+	case config.ImportTypeOCI:
+		details, _ := config.GetImportDetails[config.OCIImportDetails](pkg)
+		digest, isPinned := lock.PinnedDigest(name, pkg)
+		changed := metadata.Ref != details.Ref && (!isPinned || metadata.Digest != digest)
+		return metadata.Ref, details.Ref, changed
+	case config.ImportTypeLocal, config.ImportTypeArchive:
+	}
+
+	return "", "", false
+}
+
+func describeRevision(revision string) string {
+	if revision == "" {
+		return "the default branch"
+	}
+	return "revision " + revision
+}
+
+// describeSource returns the repository, directory, archive or reference a cache entry is fetched from.
+func describeSource(metadata fetcher.CacheMetadata) string {
+	switch metadata.Type {
+	case config.ImportTypeGit:
+		if metadata.Path != "" {
+			return fmt.Sprintf("%s (path %s)", metadata.Repository, metadata.Path)
+		}
+		return metadata.Repository
+	case config.ImportTypeLocal:
+		return metadata.Path
+	case config.ImportTypeArchive:
+		return fmt.Sprintf(
+			"%s (sha256 %s, stripComponents %d)",
+			metadata.URL,
+			metadata.SHA256,
+			metadata.StripComponents,
+		)
+	case config.ImportTypeOCI:
+		return metadata.Ref
+	}
+
+	return string(metadata.Type)
+}
+
+func (d *Doctor) checkOutputs(group *Group, cfg *config.Config) {
+	projectRoot, err := cfg.ProjectRoot()
+	if err != nil {
+		group.fail("could not resolve project root: %s", err)
+		return
+	}
+
+	cacheDir, err := cfg.ResolvePath(cfg.Settings.CacheDir)
+	if err != nil {
+		group.fail("could not resolve cache directory %s: %s", cfg.Settings.CacheDir, err)
+	} else {
+		checkGitIgnored(group, projectRoot, cacheDir)
+	}
+
+	integrations, err := engine.GetEnabledIntegrations(cfg)
+	if err != nil {
+		group.fail("could not prepare integrations: %s", err)
+		return
+	}
+	if len(integrations) == 0 {
+		group.warn("no integrations are enabled")
+		return
+	}
+
+	for _, integration := range integrations {
+		for _, dir := range integration.OutputDirs(cfg.Settings.Namespace) {
+			checkGitIgnored(group, projectRoot, dir)
+		}
+	}
+}
+
+func checkGitIgnored(group *Group, projectRoot string, absPath string) {
+	relPath, err := filepath.Rel(projectRoot, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		group.pass("%s is outside the project", absPath)
+		return
+	}
+
+	exists, err := utils.IsDirExists(absPath)
+	if err != nil {
+		group.fail("could not inspect %s: %s", relPath, err)
+		return
+	}
+	if !exists {
+		group.pass("%s has not been generated yet", relPath)
+		return
+	}
+
+	if isGitIgnored(projectRoot, absPath, relPath) {
+		group.pass("%s is gitignored", relPath)
+	} else {
+		group.warn("%s is not gitignored; generated files may be committed", relPath)
+	}
+}
+
+// isGitIgnored reports whether the directory ignores itself via its own `.gitignore`
+// (as written by integrations) or is matched by the `.gitignore` in the project root.
+func isGitIgnored(projectRoot string, absPath string, relPath string) bool {
+	if body, err := os.ReadFile(filepath.Join(absPath, ".gitignore")); err == nil {
+		if slices.Contains(strings.Fields(string(body)), "*") {
+			return true
+		}
+	}
+
+	ignored, err := utils.IsPathGitIgnored(projectRoot, filepath.ToSlash(relPath)+"/")
+	return err == nil && ignored
+}
+
+func isGitImport(pkg config.ImportedPackage) bool {
+	return pkg.Type == config.ImportTypeGit
+}
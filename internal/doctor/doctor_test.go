@@ -0,0 +1,591 @@
+package doctor_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gomock "go.uber.org/mock/gomock"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/doctor"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+	utils "github.com/sushichan044/ajisai/utils/mocks"
+)
+
+func lookPathFound(file string) (string, error) {
+	return "/usr/bin/" + file, nil
+}
+
+func lookPathMissing(_ string) (string, error) {
+	return "", errors.New("not found")
+}
+
+func findGroup(t *testing.T, report *doctor.Report, name string) *doctor.Group {
+	t.Helper()
+
+	for _, group := range report.Groups {
+		if group.Name == name {
+			return group
+		}
+	}
+
+	require.Failf(t, "group not found", "group %s is not in the report", name)
+	return nil
+}
+
+func newConfig(cacheDir string, imports map[string]config.ImportedPackage) *config.Config {
+	return &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: imports,
+			Integrations: &config.AgentIntegrations{
+				Cursor:        &config.CursorIntegration{Enabled: true},
+				GitHubCopilot: &config.GitHubCopilotIntegration{},
+				Windsurf:      &config.WindsurfIntegration{},
+			},
+		},
+	}
+}
+
+// writeCacheMetadata records that the cache of the import was fetched from the import as configured.
+func writeCacheMetadata(t *testing.T, cfg *config.Config, name string) {
+	t.Helper()
+
+	cacheRoot, err := cfg.GetImportedPackageCacheRoot(name)
+	require.NoError(t, err)
+	require.NoError(t, fetcher.WriteCacheMetadata(cacheRoot, cfg.Workspace.Imports[name]))
+}
+
+func TestDoctor_Diagnose_ConfigStatus(t *testing.T) {
+	d := doctor.NewWithRunner(nil, lookPathFound)
+
+	t.Run("not found", func(t *testing.T) {
+		report := d.Diagnose(&config.Context{Status: config.StatusNotFound})
+
+		require.Len(t, report.Groups, 1)
+		assert.Equal(t, doctor.StatusFail, report.Groups[0].Results[0].Status)
+		assert.True(t, report.HasFailure())
+	})
+
+	t.Run("validation failed", func(t *testing.T) {
+		report := d.Diagnose(&config.Context{
+			Status:          config.StatusValidationFailed,
+			ValidationError: errors.New("bad type"),
+		})
+
+		require.Len(t, report.Groups, 1)
+		assert.Contains(t, report.Groups[0].Results[0].Message, "bad type")
+		assert.True(t, report.HasFailure())
+	})
+
+	t.Run("validation issues", func(t *testing.T) {
+		report := d.Diagnose(&config.Context{
+			Status: config.StatusValidationFailed,
+			ValidationError: &config.ValidationError{
+				File: "ajisai.yml",
+				Issues: []config.ValidationIssue{
+					{Path: []string{"workspace", "imports", "a"}, Line: 3, Column: 5, Message: "first"},
+					{Path: []string{"workspace", "imports", "b"}, Line: 7, Column: 5, Message: "second"},
+					{Path: []string{"workspace", "imports", "c"}, Message: "without position"},
+				},
+			},
+		})
+
+		require.Len(t, report.Groups, 1)
+		assert.Equal(t, []doctor.Result{
+			{Status: doctor.StatusFail, Message: "ajisai.yml:3:5: workspace.imports.a: first"},
+			{Status: doctor.StatusFail, Message: "ajisai.yml:7:5: workspace.imports.b: second"},
+			{Status: doctor.StatusFail, Message: "ajisai.yml: workspace.imports.c: without position"},
+		}, report.Groups[0].Results)
+	})
+}
+
+func TestDoctor_Diagnose_Healthy(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	ctrl := gomock.NewController(t)
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	repoURL := "https://github.com/example/repo.git"
+	cacheDir := filepath.Join(tempDir, ".cache", "ajisai")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "remote"), 0750))
+	ruleOutputDir := filepath.Join(tempDir, ".cursor", "rules", "ajisai")
+	require.NoError(t, os.MkdirAll(ruleOutputDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(ruleOutputDir, ".gitignore"), []byte("*\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte(".cache/\n"), 0600))
+
+	mockRunner.EXPECT().
+		OutputInDir(filepath.Join(cacheDir, "remote"), "git", "remote", "get-url", "origin").
+		Return(repoURL, nil)
+
+	cfg := newConfig(cacheDir, map[string]config.ImportedPackage{
+		"remote": {
+			Type:    config.ImportTypeGit,
+			Include: []string{config.DefaultPresetName},
+			Details: config.GitImportDetails{Repository: repoURL},
+		},
+	})
+	writeCacheMetadata(t, cfg, "remote")
+
+	report := doctor.NewWithRunner(mockRunner, lookPathFound).Diagnose(&config.Context{
+		Config: cfg,
+		Status: config.StatusValid,
+	})
+
+	assert.False(t, report.HasFailure())
+	assert.Equal(t, 0, report.Count(doctor.StatusWarn))
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusPass, Message: "git found at /usr/bin/git"},
+	}, findGroup(t, report, "Environment").Results)
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusPass, Message: "remote: cache is up to date with the config"},
+	}, findGroup(t, report, "Imports").Results)
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusPass, Message: filepath.Join(".cache", "ajisai") + " is gitignored"},
+		{Status: doctor.StatusPass, Message: filepath.Join(".cursor", "rules", "ajisai") + " is gitignored"},
+		{
+			Status:  doctor.StatusPass,
+			Message: filepath.Join(".cursor", "prompts", "ajisai") + " has not been generated yet",
+		},
+	}, findGroup(t, report, "Outputs").Results)
+}
+
+func TestDoctor_Diagnose_Problems(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	ctrl := gomock.NewController(t)
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	cacheDir := filepath.Join(tempDir, ".cache", "ajisai")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "local"), 0750))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, ".cursor", "rules", "ajisai"), 0750))
+
+	cfg := newConfig(cacheDir, map[string]config.ImportedPackage{
+		"local": {
+			Type:    config.ImportTypeLocal,
+			Include: []string{config.DefaultPresetName, "missing"},
+			Details: config.LocalImportDetails{Path: filepath.Join(tempDir, "does-not-exist")},
+		},
+		"remote": {
+			Type:    config.ImportTypeGit,
+			Include: []string{config.DefaultPresetName},
+			Details: config.GitImportDetails{Repository: "https://github.com/example/repo.git"},
+		},
+	})
+	cfg.Settings.GitBackend = config.GitBackendCLI
+	writeCacheMetadata(t, cfg, "local")
+
+	report := doctor.NewWithRunner(mockRunner, lookPathMissing).Diagnose(&config.Context{
+		Config: cfg,
+		Status: config.StatusValid,
+	})
+
+	assert.True(t, report.HasFailure())
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusFail, Message: "git is required by git imports but was not found on PATH"},
+	}, findGroup(t, report, "Environment").Results)
+	assert.Equal(t, []doctor.Result{
+		{
+			Status:  doctor.StatusFail,
+			Message: "local: source directory " + filepath.Join(tempDir, "does-not-exist") + " does not exist",
+		},
+		{
+			Status:  doctor.StatusFail,
+			Message: `local: included preset "missing" is not exported by the package (exported: default)`,
+		},
+		{Status: doctor.StatusWarn, Message: "remote: not fetched yet (run `ajisai apply`)"},
+	}, findGroup(t, report, "Imports").Results)
+	assert.Contains(t, findGroup(t, report, "Outputs").Results, doctor.Result{
+		Status:  doctor.StatusWarn,
+		Message: filepath.Join(".cache", "ajisai") + " is not gitignored; generated files may be committed",
+	})
+	assert.Contains(t, findGroup(t, report, "Outputs").Results, doctor.Result{
+		Status:  doctor.StatusWarn,
+		Message: filepath.Join(".cursor", "rules", "ajisai") + " is not gitignored; generated files may be committed",
+	})
+}
+
+func TestDoctor_Diagnose_CacheMetadata(t *testing.T) {
+	gitImport := func(revision string) config.ImportedPackage {
+		return config.ImportedPackage{
+			Type:    config.ImportTypeGit,
+			Include: []string{config.DefaultPresetName},
+			Details: config.GitImportDetails{Repository: "https://github.com/example/repo.git", Revision: revision},
+		}
+	}
+	archiveImport := func(url string) config.ImportedPackage {
+		return config.ImportedPackage{
+			Type:    config.ImportTypeArchive,
+			Include: []string{config.DefaultPresetName},
+			Details: config.ArchiveImportDetails{URL: url, SHA256: "abc"},
+		}
+	}
+	commit := strings.Repeat("a", 40)
+	refetched := " (it is fetched again on the next `ajisai apply`)"
+
+	tests := []struct {
+		name string
+		pkg  config.ImportedPackage
+		// Import the cache was fetched from. The cache has no metadata if it is empty.
+		fetched  config.ImportedPackage
+		pinned   string
+		expected doctor.Result
+	}{
+		{
+			name: "no metadata",
+			pkg:  archiveImport("https://example.com/new.tar.gz"),
+			expected: doctor.Result{
+				Status:  doctor.StatusWarn,
+				Message: "pkg: cache has no record of its source" + refetched,
+			},
+		},
+		{
+			name:    "source changed",
+			pkg:     archiveImport("https://example.com/new.tar.gz"),
+			fetched: archiveImport("https://example.com/old.tar.gz"),
+			expected: doctor.Result{
+				Status: doctor.StatusWarn,
+				Message: "pkg: cache was fetched from https://example.com/old.tar.gz (sha256 abc, stripComponents 0) " +
+					"but config expects https://example.com/new.tar.gz (sha256 abc, stripComponents 0)" + refetched,
+			},
+		},
+		{
+			name:    "revision changed",
+			pkg:     gitImport("v2"),
+			fetched: gitImport("v1"),
+			expected: doctor.Result{
+				Status:  doctor.StatusWarn,
+				Message: "pkg: cache was fetched at revision v1 but config expects revision v2" + refetched,
+			},
+		},
+		{
+			name:     "pinned tag",
+			pkg:      gitImport("^1.0.0"),
+			fetched:  gitImport("v1.2.0"),
+			pinned:   commit,
+			expected: doctor.Result{Status: doctor.StatusPass, Message: "pkg: cache is up to date with the config"},
+		},
+		{
+			name:     "pinned commit",
+			pkg:      gitImport("v2"),
+			fetched:  gitImport(commit),
+			pinned:   commit,
+			expected: doctor.Result{Status: doctor.StatusPass, Message: "pkg: cache is up to date with the config"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Chdir(tempDir)
+
+			cacheDir := filepath.Join(tempDir, "cache")
+			require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "pkg"), 0750))
+			cfg := newConfig(cacheDir, map[string]config.ImportedPackage{"pkg": tt.pkg})
+			if tt.fetched.Type != "" {
+				require.NoError(t, fetcher.WriteCacheMetadata(filepath.Join(cacheDir, "pkg"), tt.fetched))
+			}
+
+			lock := lockfile.New()
+			if tt.pinned != "" {
+				entry := lockfile.NewEntry(tt.pkg, tt.pinned)
+				entry.Version = "v1.2.0"
+				lock.Imports["pkg"] = entry
+			}
+			require.NoError(t, lock.Save(filepath.Join(tempDir, lockfile.FileName)))
+
+			report := doctor.NewWithRunner(nil, lookPathMissing).Diagnose(&config.Context{
+				Config: cfg,
+				Path:   filepath.Join(tempDir, "ajisai.yml"),
+				Status: config.StatusValid,
+			})
+
+			assert.Equal(t, []doctor.Result{tt.expected}, findGroup(t, report, "Imports").Results)
+		})
+	}
+}
+
+func TestDoctor_Diagnose_Vendored(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	archiveImport := func(url string, include ...string) config.ImportedPackage {
+		return config.ImportedPackage{
+			Type:    config.ImportTypeArchive,
+			Include: include,
+			Details: config.ArchiveImportDetails{
+				URL:    url,
+				SHA256: "0f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a6978",
+			},
+		}
+	}
+
+	packageRoot := filepath.Join(tempDir, "package")
+	require.NoError(t, os.MkdirAll(filepath.Join(packageRoot, "rules"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(packageRoot, "rules", "rule.md"), []byte("# Rule"), 0600))
+
+	imports := map[string]config.ImportedPackage{
+		"fresh":   archiveImport("https://example.com/fresh.tar.gz", config.DefaultPresetName),
+		"stale":   archiveImport("https://example.com/stale.tar.gz", config.DefaultPresetName, "go"),
+		"moved":   archiveImport("https://example.com/moved.tar.gz", config.DefaultPresetName),
+		"edited":  archiveImport("https://example.com/edited.tar.gz", config.DefaultPresetName),
+		"relock":  archiveImport("https://example.com/relock.tar.gz", config.DefaultPresetName),
+		"ignored": archiveImport("https://example.com/ignored.tar.gz", config.DefaultPresetName),
+	}
+	cfg := newConfig(filepath.Join(tempDir, ".cache", "ajisai"), imports)
+	cfg.Settings.VendorDir = filepath.Join(tempDir, "vendor")
+
+	vendor := func(name string, pkg config.ImportedPackage) {
+		t.Helper()
+
+		vendorDir := filepath.Join(cfg.Settings.VendorDir, name)
+		require.NoError(t, vendoring.Write(packageRoot, vendorDir, lockfile.NewEntry(pkg, "sha256:vendored")))
+	}
+	vendor("fresh", imports["fresh"])
+	vendor("stale", archiveImport("https://example.com/stale.tar.gz", config.DefaultPresetName))
+	vendor("moved", archiveImport("https://example.com/old.tar.gz", config.DefaultPresetName))
+	vendor("edited", imports["edited"])
+	vendor("relock", imports["relock"])
+	require.NoError(t, os.WriteFile(
+		filepath.Join(cfg.Settings.VendorDir, "edited", "rules", "rule.md"),
+		[]byte("# Edited"),
+		0600,
+	))
+	require.NoError(t, os.MkdirAll(filepath.Join(cfg.Settings.VendorDir, "ignored"), 0750))
+
+	lock := lockfile.New()
+	lock.Imports["relock"] = lockfile.NewEntry(imports["relock"], "sha256:updated")
+	require.NoError(t, lock.Save(filepath.Join(tempDir, lockfile.FileName)))
+
+	report := doctor.NewWithRunner(nil, lookPathFound).Diagnose(&config.Context{
+		Config: cfg,
+		Path:   filepath.Join(tempDir, "ajisai.yml"),
+		Status: config.StatusValid,
+	})
+
+	assert.Equal(t, []doctor.Result{
+		{
+			Status: doctor.StatusWarn,
+			Message: "edited: files in " + filepath.Join(cfg.Settings.VendorDir, "edited") +
+				" were changed after vendoring (run `ajisai vendor` to restore them)",
+		},
+		{Status: doctor.StatusPass, Message: "fresh: vendored copy is up to date with the config"},
+		{
+			Status: doctor.StatusWarn,
+			Message: "ignored: " + filepath.Join(cfg.Settings.VendorDir, "ignored") +
+				" is not a vendored copy and is ignored (run `ajisai vendor`)",
+		},
+		{Status: doctor.StatusWarn, Message: "ignored: not fetched yet (run `ajisai apply`)"},
+		{
+			Status:  doctor.StatusFail,
+			Message: "moved: vendored copy was made for another source than the config (run `ajisai vendor`)",
+		},
+		{
+			Status:  doctor.StatusWarn,
+			Message: "relock: vendored copy is not at the revision pinned in ajisai.lock (run `ajisai vendor`)",
+		},
+		{
+			Status:  doctor.StatusFail,
+			Message: "stale: vendored copy includes default but config includes default, go (run `ajisai vendor`)",
+		},
+		{
+			Status:  doctor.StatusFail,
+			Message: `stale: included preset "go" is not exported by the vendored package (exported: default)`,
+		},
+	}, findGroup(t, report, "Imports").Results)
+}
+
+func TestDoctor_Diagnose_LinkedLocal(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "presets")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "rules"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "rules", "rule.md"), []byte("# Rule"), 0600))
+
+	// Linked imports are not copied into the cache, so a missing cache is not reported.
+	cfg := newConfig(filepath.Join(tempDir, ".cache", "ajisai"), map[string]config.ImportedPackage{
+		"linked": {
+			Type:    config.ImportTypeLocal,
+			Include: []string{config.DefaultPresetName},
+			Details: config.LocalImportDetails{Path: sourceDir, Mode: config.LocalImportModeLink},
+		},
+		"missing": {
+			Type:    config.ImportTypeLocal,
+			Include: []string{config.DefaultPresetName},
+			Details: config.LocalImportDetails{
+				Path: filepath.Join(tempDir, "does-not-exist"),
+				Mode: config.LocalImportModeLink,
+			},
+		},
+	})
+
+	report := doctor.NewWithRunner(nil, lookPathFound).Diagnose(&config.Context{
+		Config: cfg,
+		Status: config.StatusValid,
+	})
+
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusPass, Message: "linked: source directory is linked"},
+		{
+			Status:  doctor.StatusFail,
+			Message: "missing: source directory " + filepath.Join(tempDir, "does-not-exist") + " does not exist",
+		},
+	}, findGroup(t, report, "Imports").Results)
+}
+
+func TestDoctor_Diagnose_BuiltinGit(t *testing.T) {
+	tests := map[string]struct {
+		backend  config.GitBackend
+		lookPath func(string) (string, error)
+		want     string
+	}{
+		"auto without git": {
+			backend:  config.GitBackendAuto,
+			lookPath: lookPathMissing,
+			want:     "git was not found on PATH; git imports use the built-in implementation",
+		},
+		"go": {
+			backend:  config.GitBackendGo,
+			lookPath: lookPathFound,
+			want:     "git is not required (git imports use the built-in implementation)",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig(t.TempDir(), map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: "https://github.com/example/repo.git"},
+				},
+			})
+			cfg.Settings.GitBackend = tt.backend
+
+			report := doctor.NewWithRunner(nil, tt.lookPath).Diagnose(&config.Context{
+				Config: cfg,
+				Status: config.StatusValid,
+			})
+
+			assert.Equal(t, []doctor.Result{
+				{Status: doctor.StatusPass, Message: tt.want},
+			}, findGroup(t, report, "Environment").Results)
+		})
+	}
+}
+
+func TestDoctor_Diagnose_OriginMismatch(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	ctrl := gomock.NewController(t)
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	cacheDir := filepath.Join(tempDir, "cache")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "remote"), 0750))
+
+	mockRunner.EXPECT().
+		OutputInDir(filepath.Join(cacheDir, "remote"), "git", "remote", "get-url", "origin").
+		Return("https://github.com/example/old.git", nil)
+
+	cfg := newConfig(cacheDir, map[string]config.ImportedPackage{
+		"remote": {
+			Type:    config.ImportTypeGit,
+			Include: []string{config.DefaultPresetName},
+			Details: config.GitImportDetails{Repository: "https://github.com/example/new.git"},
+		},
+	})
+	writeCacheMetadata(t, cfg, "remote")
+
+	report := doctor.NewWithRunner(mockRunner, lookPathFound).Diagnose(&config.Context{
+		Config: cfg,
+		Status: config.StatusValid,
+	})
+
+	assert.Equal(t, []doctor.Result{
+		{
+			Status: doctor.StatusWarn,
+			Message: "remote: cache was cloned from https://github.com/example/old.git " +
+				"but config expects https://github.com/example/new.git (it is cloned again on the next `ajisai apply`)",
+		},
+	}, findGroup(t, report, "Imports").Results)
+}
+
+func TestDoctor_Diagnose_Mirror(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	ctrl := gomock.NewController(t)
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	cacheDir := filepath.Join(tempDir, "cache")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "remote"), 0750))
+
+	// The cache is cloned from the mirror, so its origin is the rewritten URL.
+	mockRunner.EXPECT().
+		OutputInDir(filepath.Join(cacheDir, "remote"), "git", "remote", "get-url", "origin").
+		Return("https://git.example.com/mirror/repo.git", nil)
+
+	cfg := newConfig(cacheDir, map[string]config.ImportedPackage{
+		"remote": {
+			Type:    config.ImportTypeGit,
+			Include: []string{config.DefaultPresetName},
+			Details: config.GitImportDetails{Repository: "https://github.com/example/repo.git"},
+		},
+	})
+	cfg.Settings.Mirrors = map[string]string{"https://github.com/example/": "https://git.example.com/mirror/"}
+	writeCacheMetadata(t, cfg, "remote")
+
+	report := doctor.NewWithRunner(mockRunner, lookPathFound).Diagnose(&config.Context{
+		Config: cfg,
+		Status: config.StatusValid,
+	})
+
+	assert.Equal(t, []doctor.Result{
+		{Status: doctor.StatusPass, Message: "remote: cache is up to date with the config"},
+	}, findGroup(t, report, "Imports").Results)
+}
+
+func TestReport_Write(t *testing.T) {
+	report := &doctor.Report{
+		Groups: []*doctor.Group{
+			{
+				Name: "Config",
+				Results: []doctor.Result{
+					{Status: doctor.StatusPass, Message: "configuration loaded"},
+				},
+			},
+			{
+				Name: "Imports",
+				Results: []doctor.Result{
+					{Status: doctor.StatusWarn, Message: "a: not fetched yet"},
+					{Status: doctor.StatusFail, Message: "b: broken"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+
+	assert.Equal(t, `Config
+  [PASS] configuration loaded
+Imports
+  [WARN] a: not fetched yet
+  [FAIL] b: broken
+
+1 passed, 1 warnings, 1 failed
+`, buf.String())
+}
@@ -0,0 +1,117 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// StatusPass indicates the check succeeded.
+	StatusPass Status = iota
+
+	// StatusWarn indicates a problem that does not prevent ajisai from working.
+	StatusWarn
+
+	// StatusFail indicates a problem that must be fixed.
+	StatusFail
+)
+
+type (
+	// Status is the outcome of a single check.
+	Status int
+
+	// Result is the outcome of a single check with a human-readable message.
+	Result struct {
+		Status  Status
+		Message string
+	}
+
+	// Group is a named set of related check results.
+	Group struct {
+		Name    string
+		Results []Result
+	}
+
+	// Report is the full set of check results produced by Doctor.
+	Report struct {
+		Groups []*Group
+	}
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	case StatusFail:
+		return "FAIL"
+	}
+
+	return "UNKNOWN"
+}
+
+func (g *Group) pass(format string, args ...any) {
+	g.add(StatusPass, format, args...)
+}
+
+func (g *Group) warn(format string, args ...any) {
+	g.add(StatusWarn, format, args...)
+}
+
+func (g *Group) fail(format string, args ...any) {
+	g.add(StatusFail, format, args...)
+}
+
+func (g *Group) add(status Status, format string, args ...any) {
+	g.Results = append(g.Results, Result{Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count returns the number of results with the given status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, group := range r.Groups {
+		for _, result := range group.Results {
+			if result.Status == status {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// HasFailure reports whether any check failed.
+func (r *Report) HasFailure() bool {
+	return r.Count(StatusFail) > 0
+}
+
+// Write prints the report grouped by check category, followed by a summary line.
+func (r *Report) Write(w io.Writer) error {
+	for _, group := range r.Groups {
+		if _, err := fmt.Fprintln(w, group.Name); err != nil {
+			return err
+		}
+
+		for _, result := range group.Results {
+			if _, err := fmt.Fprintf(w, "  [%s] %s\n", result.Status, result.Message); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"\n%d passed, %d warnings, %d failed\n",
+		r.Count(StatusPass),
+		r.Count(StatusWarn),
+		r.Count(StatusFail),
+	)
+	return err
+}
+
+func (r *Report) group(name string) *Group {
+	group := &Group{Name: name, Results: []Result{}}
+	r.Groups = append(r.Groups, group)
+	return group
+}
@@ -7,6 +7,10 @@ type (
 	PackageFetcher interface {
 		// Fetch retrieves packages from the source and stores them in the destination directory.
 		Fetch(source config.ImportedPackage, destinationDir string) error
+
+		// Resolve returns an identifier of the content fetched into the destination directory
+		// (e.g. commit SHA for git, content digest for local), which is recorded in the lockfile.
+		Resolve(destinationDir string) (string, error)
 	}
 
 	// AgentBridge is a bridge between the domain and the agent.
@@ -29,6 +33,13 @@ type (
 	AgentIntegration interface {
 		WritePackage(namespace string, pkg *AgentPresetPackage) error
 
+		// ReadPreset reads hand-written rules and prompts from the agent's directories.
+		// Files under the namespace directory are skipped because they are managed by ajisai.
+		ReadPreset(namespace string) (*AgentPreset, error)
+
+		// OutputDirs returns the absolute directories written by WritePackage for the namespace.
+		OutputDirs(namespace string) []string
+
 		Clean(namespace string) error
 	}
 
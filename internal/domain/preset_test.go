@@ -172,6 +172,98 @@ func TestNewPromptItem(t *testing.T) {
 	}
 }
 
+func TestRuleItem_MarshalToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		metadata domain.RuleMetadata
+		expected string
+	}{
+		{
+			name:     "always rule omits description resolved from heading",
+			content:  "# Always\nAlways follow this.\n\n",
+			metadata: domain.RuleMetadata{Attach: domain.AttachTypeAlways},
+			expected: "---\nattach: always\n---\n# Always\nAlways follow this.\n",
+		},
+		{
+			name:    "glob rule writes globs",
+			content: "Use gofmt.",
+			metadata: domain.RuleMetadata{
+				Attach: domain.AttachTypeGlob,
+				Globs:  []string{"**/*.go", "!**/*_test.go"},
+			},
+			expected: "---\nattach: glob\nglobs:\n- \"**/*.go\"\n- \"!**/*_test.go\"\n---\nUse gofmt.\n",
+		},
+		{
+			name:    "agent-requested rule writes description",
+			content: "Prefer CTEs.",
+			metadata: domain.RuleMetadata{
+				Attach:      domain.AttachTypeAgentRequested,
+				Description: "Use when writing SQL",
+			},
+			expected: "---\nattach: agent-requested\ndescription: Use when writing SQL\n---\nPrefer CTEs.\n",
+		},
+		{
+			name:     "manual rule",
+			content:  "Manual only.",
+			metadata: domain.RuleMetadata{Attach: domain.AttachTypeManual},
+			expected: "---\nattach: manual\n---\nManual only.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := domain.NewRuleItem(
+				domain.NewPlaceholderURI("test-rule", domain.RulesPresetType),
+				tt.content,
+				tt.metadata,
+			)
+
+			result, err := rule.MarshalToMarkdown()
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestPromptItem_MarshalToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		metadata domain.PromptMetadata
+		expected string
+	}{
+		{
+			name:     "description equal to heading is omitted",
+			content:  "# Review\nReview the code.",
+			metadata: domain.PromptMetadata{},
+			expected: "# Review\nReview the code.\n",
+		},
+		{
+			name:     "explicit description is written",
+			content:  "Review the code.",
+			metadata: domain.PromptMetadata{Description: "Review code"},
+			expected: "---\ndescription: Review code\n---\nReview the code.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := domain.NewPromptItem(
+				domain.NewPlaceholderURI("test-prompt", domain.PromptsPresetType),
+				tt.content,
+				tt.metadata,
+			)
+
+			result, err := prompt.MarshalToMarkdown()
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
 func TestAgentPreset_ToXML(t *testing.T) {
 	tests := []struct {
 		name     string
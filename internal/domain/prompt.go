@@ -41,6 +41,23 @@ func NewPromptItem(uri URI, content string, metadata PromptMetadata) *PromptItem
 	}
 }
 
+// MarshalToMarkdown converts the PromptItem into a Markdown file in ajisai format.
+// Description is omitted when it equals the first h1 heading, since it is resolved from it on load.
+func (p *PromptItem) MarshalToMarkdown() ([]byte, error) {
+	var frontMatter markdownPromptMetadata
+	if p.Metadata.Description != utils.ExtractH1Heading(p.Content) {
+		frontMatter.Description = p.Metadata.Description
+	}
+
+	return marshalMarkdownWithMetadata(frontMatter, p.Content)
+}
+
+// Markdown marshalling implementation
+
+type markdownPromptMetadata struct {
+	Description string `yaml:"description,omitempty"`
+}
+
 // XML marshalling implementation
 
 type (
@@ -2,6 +2,9 @@ package domain
 
 import (
 	"encoding/xml"
+	"strings"
+
+	"github.com/goccy/go-yaml"
 
 	"github.com/sushichan044/ajisai/utils"
 )
@@ -43,6 +46,50 @@ func NewRuleItem(uri URI, content string, metadata RuleMetadata) *RuleItem {
 	}
 }
 
+// MarshalToMarkdown converts the RuleItem into a Markdown file in ajisai format.
+// Description is only written for agent-requested rules because it is resolved
+// from the first h1 heading otherwise.
+func (r *RuleItem) MarshalToMarkdown() ([]byte, error) {
+	frontMatter := markdownRuleMetadata{
+		Attach: r.Metadata.Attach,
+	}
+
+	switch r.Metadata.Attach {
+	case AttachTypeGlob:
+		frontMatter.Globs = r.Metadata.Globs
+	case AttachTypeAgentRequested:
+		frontMatter.Description = r.Metadata.Description
+	case AttachTypeAlways, AttachTypeManual:
+		// No additional metadata is needed.
+	}
+
+	return marshalMarkdownWithMetadata(frontMatter, r.Content)
+}
+
+// Markdown marshalling implementation
+
+type markdownRuleMetadata struct {
+	Attach      AttachType `yaml:"attach"`
+	Description string     `yaml:"description,omitempty"`
+	Globs       []string   `yaml:"globs,omitempty"`
+}
+
+func marshalMarkdownWithMetadata(metadata any, content string) ([]byte, error) {
+	frontMatterBytes, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	frontMatter := string(frontMatterBytes)
+	body := strings.TrimRight(content, "\n") + "\n"
+
+	if strings.TrimSpace(frontMatter) == "{}" {
+		return []byte(body), nil
+	}
+
+	return []byte("---\n" + frontMatter + "---\n" + body), nil
+}
+
 // XML marshalling implementation
 
 type (
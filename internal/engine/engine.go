@@ -1,43 +1,182 @@
 package engine
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/sushichan044/ajisai/internal/config"
 	"github.com/sushichan044/ajisai/internal/domain"
 	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/globalcache"
 	"github.com/sushichan044/ajisai/internal/integration"
 	"github.com/sushichan044/ajisai/internal/loader"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+	"github.com/sushichan044/ajisai/internal/versionrange"
+	"github.com/sushichan044/ajisai/utils"
 )
 
-type Engine struct {
-	cfg *config.Config
+type (
+	Engine struct {
+		cfg  *config.Config
+		opts Options
 
-	activeIntegrations []domain.AgentIntegration
+		activeIntegrations []domain.AgentIntegration
+
+		// git fetches git imports with the backend and credentials from the config.
+		git fetcher.Git
+
+		// mirrors rewrites the URLs of git and archive imports.
+		mirrors *fetcher.Mirrors
+
+		// store is the global cache that remote imports are fetched through, or nil if it is disabled.
+		store *globalcache.Store
+
+		// lock is the lockfile read at startup, and resolved records what each applied import resolved to.
+		lock     *lockfile.Lockfile
+		resolved *lockfile.Lockfile
+
+		// warnings records problems of applied imports that did not stop them from being applied.
+		warnings map[string]string
+	}
+
+	// Options changes how the engine applies packages.
+	Options struct {
+		// Reject rule and prompt files with invalid front matter instead of loading them leniently.
+		Strict bool
+
+		// Path to the lockfile. If empty, imports are not pinned and no lockfile is written.
+		LockfilePath string
+
+		// Resolve every import again instead of using the commits pinned in the lockfile.
+		Update bool
+
+		// Resolve only these imports again. Other imports stay at the commits pinned in the lockfile.
+		// Ignored when Update is set.
+		UpdateImports []string
+
+		// Apply the cached contents of git, archive and OCI imports as they are, without accessing the network.
+		// Local imports are still copied from their directory.
+		Offline bool
+
+		// Fetch every import, even if it was fetched within its refresh interval.
+		Refresh bool
+	}
+
+	// NotCachedError is returned when an import is applied offline, but has never been fetched into the cache.
+	NotCachedError struct {
+		PackageName string
+		CacheDir    string
+	}
+
+	// CacheMismatchError is returned when an import is applied offline, but its cache was not fetched
+	// from the source in the config.
+	CacheMismatchError struct {
+		PackageName string
+		CacheDir    string
+		// FetchedFrom is the source the cache was fetched from, or empty if the cache has no record of it.
+		FetchedFrom string
+	}
+)
+
+func (e *NotCachedError) Error() string {
+	return fmt.Sprintf(
+		"package %s is not cached in %s and cannot be fetched offline; apply once with network access",
+		e.PackageName,
+		e.CacheDir,
+	)
+}
+
+func (e *NotCachedError) Unwrap() error {
+	return nil
+}
+
+func (e *CacheMismatchError) Error() string {
+	fetchedFrom := "has no record of its source"
+	if e.FetchedFrom != "" {
+		fetchedFrom = "was fetched from " + e.FetchedFrom
+	}
+
+	return fmt.Sprintf(
+		"package %s in %s %s, which does not match the config, and cannot be fetched offline; "+
+			"apply once with network access",
+		e.PackageName,
+		e.CacheDir,
+		fetchedFrom,
+	)
+}
+
+func (e *CacheMismatchError) Unwrap() error {
+	return nil
 }
 
 func NewEngine(cfg *config.Config) (*Engine, error) {
+	return NewEngineWithOptions(cfg, Options{})
+}
+
+// NewEngineWithOptions creates a new Engine with the given options.
+func NewEngineWithOptions(cfg *config.Config, opts Options) (*Engine, error) {
 	if cfg == nil {
 		return nil, errors.New("internal error: config is nil")
 	}
 
-	activeIntegrations, integErr := getEnabledIntegrations(cfg)
+	activeIntegrations, integErr := GetEnabledIntegrations(cfg)
 	if integErr != nil {
 		return nil, fmt.Errorf("failed to get enabled integrations: %w", integErr)
 	}
 
-	return &Engine{cfg: cfg, activeIntegrations: activeIntegrations}, nil
+	lock := lockfile.New()
+	if opts.LockfilePath != "" && !opts.Update {
+		loadedLock, lockErr := lockfile.Load(opts.LockfilePath)
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		lock = loadedLock
+	}
+
+	mirrors := fetcher.NewMirrors(cfg.Settings.Mirrors)
+
+	var store *globalcache.Store
+	if cfg.Settings.GlobalCache {
+		storeDir, storeErr := globalcache.DefaultDir()
+		if storeErr != nil {
+			return nil, storeErr
+		}
+		store = globalcache.New(storeDir)
+	}
+
+	return &Engine{
+		cfg:                cfg,
+		opts:               opts,
+		activeIntegrations: activeIntegrations,
+		git:                fetcher.NewGit(cfg.Settings.GitBackend, fetcher.NewGitCredentials(cfg), mirrors),
+		mirrors:            mirrors,
+		store:              store,
+		lock:               lock,
+		resolved:           lockfile.New(),
+		warnings:           map[string]string{},
+	}, nil
 }
 
+// ApplyPackage fetches the package, or extracts its vendored copy, and writes it to the enabled integrations.
 func (engine *Engine) ApplyPackage(packageName string) error {
-	fetchErr := engine.fetchPackage(packageName)
-	if fetchErr != nil {
-		return fmt.Errorf("failed to fetch package %s: %w", packageName, fetchErr)
+	vendored, vendorErr := engine.extractVendored(packageName)
+	if vendorErr != nil {
+		return fmt.Errorf("failed to extract vendored package %s: %w", packageName, vendorErr)
+	}
+
+	if !vendored {
+		fetchErr := engine.fetchPackage(packageName)
+		if fetchErr != nil {
+			return fmt.Errorf("failed to fetch package %s: %w", packageName, fetchErr)
+		}
 	}
 
 	pkg, loadErr := engine.LoadPackage(packageName)
@@ -54,6 +193,109 @@ func (engine *Engine) ApplyPackage(packageName string) error {
 	return nil
 }
 
+// VendorPackage fetches a remote import at the commit or digest pinned in the lockfile, and replaces its
+// vendored copy with the manifest and the files of the presets it includes.
+func (engine *Engine) VendorPackage(packageName string) error {
+	pkgImport, imported := engine.cfg.Workspace.Imports[packageName]
+	if !imported {
+		return fmt.Errorf("package %s is not imported", packageName)
+	}
+	if pkgImport.Type == config.ImportTypeLocal {
+		return fmt.Errorf("package %s is a local import, which is not vendored", packageName)
+	}
+
+	if fetchErr := engine.fetchPackage(packageName); fetchErr != nil {
+		return fmt.Errorf("failed to fetch package %s: %w", packageName, fetchErr)
+	}
+
+	packageRoot, err := engine.cfg.GetImportedPackageRoot(packageName)
+	if err != nil {
+		return err
+	}
+	vendorDir, err := engine.cfg.GetImportedPackageVendorRoot(packageName)
+	if err != nil {
+		return err
+	}
+
+	if writeErr := vendoring.Write(packageRoot, vendorDir, engine.resolved.Imports[packageName]); writeErr != nil {
+		return fmt.Errorf("failed to vendor package %s: %w", packageName, writeErr)
+	}
+
+	return nil
+}
+
+// extractVendored replaces the cache of a remote import with its vendored copy, and reports whether it did.
+// Imports that are updated are fetched instead, since their vendored copy is at the pinned revision.
+// It returns a vendoring.StaleError if the copy was made for another definition of the import.
+func (engine *Engine) extractVendored(packageName string) (bool, error) {
+	pkgImport, imported := engine.cfg.Workspace.Imports[packageName]
+	if !imported {
+		return false, fmt.Errorf("package %s is not imported", packageName)
+	}
+
+	if pkgImport.Type == config.ImportTypeLocal ||
+		engine.opts.Update ||
+		slices.Contains(engine.opts.UpdateImports, packageName) {
+		return false, nil
+	}
+
+	vendorDir, err := engine.cfg.GetImportedPackageVendorRoot(packageName)
+	if err != nil {
+		return false, err
+	}
+
+	metadata, err := vendoring.Read(vendorDir)
+	if err != nil || metadata == nil {
+		return false, err
+	}
+	if !metadata.Import.Matches(pkgImport) {
+		return false, &vendoring.StaleError{PackageName: packageName, Dir: vendorDir}
+	}
+
+	// The cache root is emptied as a whole, since the package root of git imports with `path` is below it.
+	cacheRoot, err := engine.cfg.GetImportedPackageCacheRoot(packageName)
+	if err != nil {
+		return false, err
+	}
+	if removeErr := os.RemoveAll(cacheRoot); removeErr != nil {
+		return false, fmt.Errorf("failed to remove cache %s: %w", cacheRoot, removeErr)
+	}
+
+	packageRoot, err := engine.cfg.GetImportedPackageRoot(packageName)
+	if err != nil {
+		return false, err
+	}
+	if extractErr := vendoring.Extract(vendorDir, packageRoot); extractErr != nil {
+		return false, extractErr
+	}
+
+	engine.resolved.Imports[packageName] = metadata.Import
+	return true, nil
+}
+
+// CleanPackageOutputs removes the files written for the package by the enabled integrations,
+// so that files removed from the package do not remain when it is applied again.
+func (engine *Engine) CleanPackageOutputs(packageName string) error {
+	for _, dir := range engine.OutputDirs() {
+		packageDir := filepath.Join(dir, packageName)
+		if err := os.RemoveAll(packageDir); err != nil {
+			return fmt.Errorf("failed to remove outputs of package %s in %s: %w", packageName, packageDir, err)
+		}
+	}
+
+	return nil
+}
+
+// OutputDirs returns the directories the enabled integrations write into.
+func (engine *Engine) OutputDirs() []string {
+	dirs := make([]string, 0, len(engine.activeIntegrations)*2)
+	for _, integration := range engine.activeIntegrations {
+		dirs = append(dirs, integration.OutputDirs(engine.cfg.Settings.Namespace)...)
+	}
+
+	return dirs
+}
+
 func (engine *Engine) CleanOutputs() error {
 	eg := errgroup.Group{}
 
@@ -67,17 +309,20 @@ func (engine *Engine) CleanOutputs() error {
 }
 
 func (engine *Engine) CleanCache(force bool) error {
-	cacheDir := engine.cfg.Settings.CacheDir
+	cacheDir, err := engine.cfg.ResolvePath(engine.cfg.Settings.CacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve cache directory: %w", err)
+	}
 
-	if _, err := os.Stat(cacheDir); errors.Is(err, os.ErrNotExist) {
+	if _, err = os.Stat(cacheDir); errors.Is(err, os.ErrNotExist) {
 		// Nothing to clean.
 		return nil
 	}
 
 	if force {
 		// Remove all cache directories.
-		if err := os.RemoveAll(cacheDir); err != nil {
-			return fmt.Errorf("failed to remove cache directory %s: %w", cacheDir, err)
+		if removeErr := os.RemoveAll(cacheDir); removeErr != nil {
+			return fmt.Errorf("failed to remove cache directory %s: %w", cacheDir, removeErr)
 		}
 
 		// Create the cache directory again.
@@ -119,7 +364,11 @@ func (engine *Engine) fetchPackage(packageName string) error {
 		return fmt.Errorf("package %s is not imported", packageName)
 	}
 
-	fetcher, fetcherBuildErr := getFetcher(pkgImport.Type)
+	if pkgImport.IsLinked() {
+		return engine.linkPackage(packageName, pkgImport)
+	}
+
+	fetcher, fetcherBuildErr := getFetcher(pkgImport.Type, engine.cfg.Dir, engine.git, engine.mirrors)
 	if fetcherBuildErr != nil {
 		return fmt.Errorf("failed to get fetcher: %w", fetcherBuildErr)
 	}
@@ -129,12 +378,335 @@ func (engine *Engine) fetchPackage(packageName string) error {
 		return fmt.Errorf("failed to get cache root for package %s: %w", packageName, cacheErr)
 	}
 
-	return fetcher.Fetch(pkgImport, cacheDestination)
+	offline := engine.opts.Offline && pkgImport.Type != config.ImportTypeLocal
+
+	fresh, freshErr := engine.isFresh(packageName, pkgImport, fetcher, cacheDestination)
+	if freshErr != nil {
+		return freshErr
+	}
+
+	version := ""
+	switch {
+	case offline:
+		if cachedErr := engine.requireCached(packageName, pkgImport, cacheDestination); cachedErr != nil {
+			return cachedErr
+		}
+	case fresh:
+		// The cache was fetched within the refresh interval.
+	default:
+		source, sourceVersion, sourceErr := engine.sourceToFetch(packageName, pkgImport)
+		if sourceErr != nil {
+			return sourceErr
+		}
+
+		if fetchErr := engine.fetch(fetcher, source, cacheDestination); fetchErr != nil {
+			return fetchErr
+		}
+		version = sourceVersion
+	}
+
+	resolved, resolveErr := fetcher.Resolve(cacheDestination)
+	if resolveErr != nil {
+		return fmt.Errorf("failed to resolve fetched content of package %s: %w", packageName, resolveErr)
+	}
+
+	pinned, isPinned := engine.pinned(packageName, pkgImport)
+	if offline && isPinned && pinned != resolved {
+		// The cache cannot be moved to the pinned commit or digest offline. Keep the pin, so that the next
+		// apply with network access restores it instead of pinning whatever the cache holds.
+		engine.warnings[packageName] = fmt.Sprintf(
+			"cache is at %s, but %s pins %s; kept the pin (apply with network access to fetch it)",
+			resolved,
+			lockfile.FileName,
+			pinned,
+		)
+		engine.resolved.Imports[packageName] = engine.lock.Imports[packageName]
+		return nil
+	}
+
+	if (offline || fresh) && isPinned && pinned == resolved {
+		// The cache is still at the pinned commit, so the tag it was resolved from is still valid.
+		version = engine.lock.Imports[packageName].Version
+	}
+
+	entry := lockfile.NewEntry(pkgImport, resolved)
+	entry.Version = version
+	engine.resolved.Imports[packageName] = entry
+
+	return nil
+}
+
+// linkPackage prepares a local import with `mode: link`, which is loaded from its source directory.
+// A copy left in the cache by an earlier mode is removed, so that it cannot be edited by mistake.
+func (engine *Engine) linkPackage(packageName string, pkgImport config.ImportedPackage) error {
+	sourceDir, err := engine.cfg.GetImportedPackageRoot(packageName)
+	if err != nil {
+		return err
+	}
+
+	exists, err := utils.IsDirExists(sourceDir)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("source directory '%s' does not exist", sourceDir)
+	}
+
+	cacheRoot, err := engine.cfg.GetImportedPackageCacheRoot(packageName)
+	if err != nil {
+		return err
+	}
+	if removeErr := os.RemoveAll(cacheRoot); removeErr != nil {
+		return fmt.Errorf("failed to remove cache %s: %w", cacheRoot, removeErr)
+	}
+
+	// Nothing is fetched, so there is no digest to pin.
+	engine.resolved.Imports[packageName] = lockfile.NewEntry(pkgImport, "")
+	return nil
+}
+
+// fetch fetches the import into cacheDir, through the global cache if it is enabled.
+// Git branches and tags are resolved to their commit first, so that a stored commit is not fetched again.
+func (engine *Engine) fetch(
+	packageFetcher domain.PackageFetcher,
+	source config.ImportedPackage,
+	cacheDir string,
+) error {
+	if engine.store == nil || source.Type == config.ImportTypeLocal {
+		return packageFetcher.Fetch(source, cacheDir)
+	}
+
+	details, isGit := config.GetImportDetails[config.GitImportDetails](source)
+	if isGit && globalcache.KnownRevision(source) == "" {
+		refs, err := engine.git.ListRemoteRefs(details.Repository)
+		if err != nil {
+			return err
+		}
+		if commit := refs.Commit(details.Revision); commit != "" {
+			source = withRevision(source, commit)
+		}
+	}
+
+	return engine.store.Fetch(packageFetcher, source, cacheDir)
+}
+
+// restore links a remote import that is missing from cacheDir from the global cache, if it is enabled and holds
+// the commit or digest pinned in the lockfile.
+func (engine *Engine) restore(packageName string, pkgImport config.ImportedPackage, cacheDir string) error {
+	if engine.store == nil || pkgImport.Type == config.ImportTypeLocal {
+		return nil
+	}
+
+	exists, err := utils.IsDirExists(cacheDir)
+	if err != nil || exists {
+		return err
+	}
+
+	source := pkgImport
+	if commit, isPinned := engine.lock.PinnedCommit(packageName, pkgImport); isPinned {
+		source = withRevision(pkgImport, commit)
+	}
+	if digest, isPinned := engine.lock.PinnedDigest(packageName, pkgImport); isPinned {
+		source = withDigest(pkgImport, digest)
+	}
+
+	_, err = engine.store.Restore(source, cacheDir)
+	return err
+}
+
+// isFresh reports whether the cache of a remote import was fetched within its refresh interval,
+// so that it does not need to be fetched again. Git and OCI imports must also still be at the commit
+// or digest pinned in the lockfile.
+func (engine *Engine) isFresh(
+	packageName string,
+	pkgImport config.ImportedPackage,
+	packageFetcher domain.PackageFetcher,
+	cacheDir string,
+) (bool, error) {
+	if engine.opts.Refresh || engine.opts.Update || slices.Contains(engine.opts.UpdateImports, packageName) {
+		return false, nil
+	}
+
+	interval := engine.cfg.Settings.RefreshInterval.Duration()
+	if pkgImport.RefreshInterval != "" {
+		interval = pkgImport.RefreshInterval.Duration()
+	}
+	if interval <= 0 {
+		return false, nil
+	}
+
+	fresh, err := fetcher.IsFresh(cacheDir, pkgImport, interval)
+	if err != nil || !fresh {
+		return false, err
+	}
+
+	switch pkgImport.Type {
+	case config.ImportTypeLocal:
+		return false, nil
+	case config.ImportTypeArchive:
+		// The checksum guarantees that the cached archive is the configured one.
+		return true, nil
+	case config.ImportTypeGit, config.ImportTypeOCI:
+		// Reused only while the cache is at the pinned commit or digest, see below.
+	}
+
+	pinned, isPinned := engine.pinned(packageName, pkgImport)
+	if !isPinned {
+		return false, nil
+	}
+
+	resolved, err := packageFetcher.Resolve(cacheDir)
+	if err != nil {
+		// The cache is broken. Fetching it again repairs it.
+		return false, nil //nolint:nilerr // See above.
+	}
+
+	return resolved == pinned, nil
+}
+
+// pinned returns the commit of a git import or the manifest digest of an OCI import pinned in the lockfile.
+func (engine *Engine) pinned(packageName string, pkgImport config.ImportedPackage) (string, bool) {
+	switch pkgImport.Type {
+	case config.ImportTypeGit:
+		return engine.lock.PinnedCommit(packageName, pkgImport)
+	case config.ImportTypeOCI:
+		return engine.lock.PinnedDigest(packageName, pkgImport)
+	case config.ImportTypeLocal, config.ImportTypeArchive:
+		// Not pinned by a commit or digest.
+	}
+
+	return "", false
+}
+
+// requireCached restores the import from the global cache if needed, and returns NotCachedError if it has not
+// been fetched into cacheDir, and CacheMismatchError if it was fetched from another source than pkgImport.
+func (engine *Engine) requireCached(packageName string, pkgImport config.ImportedPackage, cacheDir string) error {
+	if err := engine.restore(packageName, pkgImport, cacheDir); err != nil {
+		return err
+	}
+
+	exists, err := utils.IsDirExists(cacheDir)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotCachedError{PackageName: packageName, CacheDir: cacheDir}
+	}
+
+	metadata, err := fetcher.ReadCacheMetadata(cacheDir)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		return &CacheMismatchError{PackageName: packageName, CacheDir: cacheDir}
+	}
+	if !metadata.MatchesImport(pkgImport) {
+		return &CacheMismatchError{
+			PackageName: packageName,
+			CacheDir:    cacheDir,
+			FetchedFrom: cmp.Or(metadata.Repository, metadata.URL, metadata.Ref),
+		}
+	}
+
+	return nil
+}
+
+// sourceToFetch returns the import with its revision fixed to the commit pinned in the lockfile,
+// or to the highest tag matching its version range. OCI imports are fixed to the pinned manifest digest.
+// The returned version is the tag the range resolved to, or empty if the revision is not a range.
+func (engine *Engine) sourceToFetch(
+	packageName string,
+	pkgImport config.ImportedPackage,
+) (config.ImportedPackage, string, error) {
+	if _, isOCI := config.GetImportDetails[config.OCIImportDetails](pkgImport); isOCI {
+		digest, isPinned := engine.lock.PinnedDigest(packageName, pkgImport)
+		if isPinned && !slices.Contains(engine.opts.UpdateImports, packageName) {
+			return withDigest(pkgImport, digest), "", nil
+		}
+
+		return pkgImport, "", nil
+	}
+
+	details, isGit := config.GetImportDetails[config.GitImportDetails](pkgImport)
+	if !isGit {
+		return pkgImport, "", nil
+	}
+
+	commit, isPinned := engine.lock.PinnedCommit(packageName, pkgImport)
+	if isPinned && !slices.Contains(engine.opts.UpdateImports, packageName) {
+		return withRevision(pkgImport, commit), engine.lock.Imports[packageName].Version, nil
+	}
+
+	if !versionrange.IsConstraint(details.Revision) {
+		return pkgImport, "", nil
+	}
+
+	tag, err := engine.git.ResolveVersion(details.Repository, details.Revision)
+	if err != nil {
+		return pkgImport, "", fmt.Errorf("failed to resolve revision of package %s: %w", packageName, err)
+	}
+
+	return withRevision(pkgImport, tag), tag, nil
+}
+
+// Resolved returns what the import resolved to when it was applied.
+func (engine *Engine) Resolved(packageName string) (lockfile.Entry, bool) {
+	entry, exists := engine.resolved.Imports[packageName]
+	return entry, exists
+}
+
+// Warning returns the problem of the import that was reported while it was applied, if any.
+func (engine *Engine) Warning(packageName string) (string, bool) {
+	warning, exists := engine.warnings[packageName]
+	return warning, exists
+}
+
+// WriteLockfile writes what the applied imports resolved to into the lockfile.
+// Imports that were not applied are dropped from the lockfile.
+func (engine *Engine) WriteLockfile() error {
+	if engine.opts.LockfilePath == "" {
+		return nil
+	}
+
+	return engine.resolved.Save(engine.opts.LockfilePath)
+}
+
+// withRevision returns a copy of the git import that checks out the given revision.
+func withRevision(pkg config.ImportedPackage, revision string) config.ImportedPackage {
+	details, isGit := config.GetImportDetails[config.GitImportDetails](pkg)
+	if !isGit {
+		return pkg
+	}
+
+	details.Revision = revision
+	pkg.Details = details
+	return pkg
+}
+
+// withDigest returns a copy of the OCI import that pulls the manifest with the given digest.
+func withDigest(pkg config.ImportedPackage, digest string) config.ImportedPackage {
+	details, isOCI := config.GetImportDetails[config.OCIImportDetails](pkg)
+	if !isOCI {
+		return pkg
+	}
+
+	ref, err := oci.ParseReference(details.Ref)
+	if err != nil {
+		// Invalid references are reported by the fetcher.
+		return pkg
+	}
+
+	details.Ref = ref.WithDigest(digest).String()
+	pkg.Details = details
+	return pkg
 }
 
 func (engine *Engine) LoadPackage(packageName string) (*domain.AgentPresetPackage, error) {
-	loader := loader.NewAgentPresetPackageLoader(engine.cfg)
-	return loader.LoadAgentPresetPackage(packageName)
+	if engine.opts.Strict {
+		return loader.NewStrictAgentPresetPackageLoader(engine.cfg).LoadAgentPresetPackage(packageName)
+	}
+
+	return loader.NewAgentPresetPackageLoader(engine.cfg).LoadAgentPresetPackage(packageName)
 }
 
 func (engine *Engine) exportPackage(pkg *domain.AgentPresetPackage) error {
@@ -149,17 +721,28 @@ func (engine *Engine) exportPackage(pkg *domain.AgentPresetPackage) error {
 	return eg.Wait()
 }
 
-func getFetcher(inputType config.ImportType) (domain.PackageFetcher, error) {
+// getFetcher returns the fetcher of the import type. Local imports are resolved from configDir.
+func getFetcher(
+	inputType config.ImportType,
+	configDir string,
+	git fetcher.Git,
+	mirrors *fetcher.Mirrors,
+) (domain.PackageFetcher, error) {
 	switch inputType {
 	case config.ImportTypeLocal:
-		return fetcher.NewLocalFetcher(), nil
+		return fetcher.NewLocalFetcherWithBaseDir(configDir), nil
 	case config.ImportTypeGit:
-		return fetcher.NewGitFetcher(), nil
+		return git, nil
+	case config.ImportTypeArchive:
+		return fetcher.NewArchiveFetcherWithMirrors(mirrors), nil
+	case config.ImportTypeOCI:
+		return fetcher.NewOCIFetcher(), nil
 	}
 	return nil, fmt.Errorf("unknown import type: %s", inputType)
 }
 
-func getEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, error) {
+// GetEnabledIntegrations returns the integrations enabled in the workspace config.
+func GetEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, error) {
 	maxIntegrations := 3
 	integrations := make([]domain.AgentIntegration, 0, maxIntegrations)
 
@@ -168,8 +751,13 @@ func getEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, erro
 		return integrations, nil
 	}
 
+	projectRoot, err := cfg.ProjectRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project root: %w", err)
+	}
+
 	if cfg.Workspace.Integrations.Cursor != nil && cfg.Workspace.Integrations.Cursor.Enabled {
-		cursorRepo, cursorErr := getIntegration(config.AgentIntegrationTypeCursor)
+		cursorRepo, cursorErr := GetIntegration(projectRoot, config.AgentIntegrationTypeCursor)
 		if cursorErr != nil {
 			return nil, fmt.Errorf("failed to get cursor repository: %w", cursorErr)
 		}
@@ -177,7 +765,7 @@ func getEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, erro
 	}
 
 	if cfg.Workspace.Integrations.GitHubCopilot != nil && cfg.Workspace.Integrations.GitHubCopilot.Enabled {
-		githubCopilotRepo, githubCopilotErr := getIntegration(config.AgentIntegrationTypeGitHubCopilot)
+		githubCopilotRepo, githubCopilotErr := GetIntegration(projectRoot, config.AgentIntegrationTypeGitHubCopilot)
 		if githubCopilotErr != nil {
 			return nil, fmt.Errorf("failed to get github copilot repository: %w", githubCopilotErr)
 		}
@@ -185,7 +773,7 @@ func getEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, erro
 	}
 
 	if cfg.Workspace.Integrations.Windsurf != nil && cfg.Workspace.Integrations.Windsurf.Enabled {
-		windsurfRepo, windsurfErr := getIntegration(config.AgentIntegrationTypeWindsurf)
+		windsurfRepo, windsurfErr := GetIntegration(projectRoot, config.AgentIntegrationTypeWindsurf)
 		if windsurfErr != nil {
 			return nil, fmt.Errorf("failed to get windsurf repository: %w", windsurfErr)
 		}
@@ -195,14 +783,15 @@ func getEnabledIntegrations(cfg *config.Config) ([]domain.AgentIntegration, erro
 	return integrations, nil
 }
 
-func getIntegration(target config.AgentIntegrationType) (domain.AgentIntegration, error) {
+// GetIntegration returns the integration for the given agent, which reads and writes under projectRoot.
+func GetIntegration(projectRoot string, target config.AgentIntegrationType) (domain.AgentIntegration, error) {
 	switch target {
 	case config.AgentIntegrationTypeCursor:
-		return integration.New(integration.NewCursorAdapter())
+		return integration.New(projectRoot, integration.NewCursorAdapter())
 	case config.AgentIntegrationTypeGitHubCopilot:
-		return integration.New(integration.NewGitHubCopilotAdapter())
+		return integration.New(projectRoot, integration.NewGitHubCopilotAdapter())
 	case config.AgentIntegrationTypeWindsurf:
-		return integration.New(integration.NewWindsurfAdapter())
+		return integration.New(projectRoot, integration.NewWindsurfAdapter())
 	}
 	return nil, fmt.Errorf("unknown agent integration type: %s", target)
 }
@@ -1,8 +1,11 @@
 package engine_test
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,6 +13,12 @@ import (
 
 	"github.com/sushichan044/ajisai/internal/config"
 	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/globalcache"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/oci/ocitest"
+	"github.com/sushichan044/ajisai/internal/packer"
+	"github.com/sushichan044/ajisai/internal/vendoring"
 )
 
 func TestNewEngine(t *testing.T) {
@@ -156,3 +165,833 @@ func TestEngine_CleanCache_SelectiveClean(t *testing.T) {
 	_, err = os.Stat(notImportedFile)
 	assert.True(t, os.IsNotExist(err), "Not imported file should not exist after selective clean")
 }
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v failed: %s", args, out)
+
+	return strings.TrimSpace(string(out))
+}
+
+func commitRule(t *testing.T, repoDir, body string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repoDir, "rules"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "rules", "rule.md"), []byte(body), 0600))
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", body)
+
+	return runGit(t, repoDir, "rev-parse", "HEAD")
+}
+
+func TestEngine_ApplyPackage_Lockfile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	firstCommit := commitRule(t, repoDir, "first")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: repoDir},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	apply := func(opts engine.Options) {
+		t.Helper()
+
+		eng, err := engine.NewEngineWithOptions(cfg, opts)
+		require.NoError(t, err)
+		require.NoError(t, eng.ApplyPackage("remote"))
+		require.NoError(t, eng.WriteLockfile())
+	}
+	lockedCommit := func() string {
+		t.Helper()
+
+		lock, err := lockfile.Load(lockPath)
+		require.NoError(t, err)
+		return lock.Imports["remote"].Commit
+	}
+	cachedRule := func() string {
+		t.Helper()
+
+		body, err := os.ReadFile(filepath.Join(cacheDir, "remote", "rules", "rule.md"))
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	apply(engine.Options{LockfilePath: lockPath})
+	assert.Equal(t, firstCommit, lockedCommit())
+
+	secondCommit := commitRule(t, repoDir, "second")
+
+	// The locked commit is kept even though the remote moved on.
+	apply(engine.Options{LockfilePath: lockPath})
+	assert.Equal(t, firstCommit, lockedCommit())
+	assert.Equal(t, "first", cachedRule())
+
+	apply(engine.Options{LockfilePath: lockPath, Update: true})
+	assert.Equal(t, secondCommit, lockedCommit())
+	assert.Equal(t, "second", cachedRule())
+}
+
+func TestEngine_ApplyPackage_UpdateBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	for _, backend := range []config.GitBackend{config.GitBackendCLI, config.GitBackendGo} {
+		t.Run(string(backend), func(t *testing.T) {
+			tempDir := t.TempDir()
+			repoDir := filepath.Join(tempDir, "repo")
+			require.NoError(t, os.MkdirAll(repoDir, 0750))
+			runGit(t, repoDir, "init", "--initial-branch=main")
+			firstCommit := commitRule(t, repoDir, "first")
+
+			lockPath := filepath.Join(tempDir, lockfile.FileName)
+			cacheDir := filepath.Join(tempDir, "cache")
+			cfg := &config.Config{
+				Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai", GitBackend: backend},
+				Workspace: &config.Workspace{
+					Imports: map[string]config.ImportedPackage{
+						"remote": {
+							Type:    config.ImportTypeGit,
+							Include: []string{config.DefaultPresetName},
+							Details: config.GitImportDetails{Repository: repoDir, Revision: "main"},
+						},
+					},
+					Integrations: &config.AgentIntegrations{},
+				},
+			}
+
+			apply := func(opts engine.Options) string {
+				t.Helper()
+
+				eng, err := engine.NewEngineWithOptions(cfg, opts)
+				require.NoError(t, err)
+				require.NoError(t, eng.ApplyPackage("remote"))
+				require.NoError(t, eng.WriteLockfile())
+
+				lock, err := lockfile.Load(lockPath)
+				require.NoError(t, err)
+				return lock.Imports["remote"].Commit
+			}
+
+			// The first apply checks out the branch, and the second one the commit pinned in the lockfile.
+			assert.Equal(t, firstCommit, apply(engine.Options{LockfilePath: lockPath}))
+			assert.Equal(t, firstCommit, apply(engine.Options{LockfilePath: lockPath}))
+
+			secondCommit := commitRule(t, repoDir, "second")
+
+			assert.Equal(t, secondCommit, apply(engine.Options{LockfilePath: lockPath, Update: true}))
+			body, err := os.ReadFile(filepath.Join(cacheDir, "remote", "rules", "rule.md"))
+			require.NoError(t, err)
+			assert.Equal(t, "second", string(body))
+		})
+	}
+}
+
+func TestEngine_ApplyPackage_Offline(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commit := commitRule(t, repoDir, "cached")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: repoDir},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	offline, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath, Offline: true})
+	require.NoError(t, err)
+
+	var notCachedErr *engine.NotCachedError
+	require.ErrorAs(t, offline.ApplyPackage("remote"), &notCachedErr)
+	assert.Equal(t, "remote", notCachedErr.PackageName)
+
+	online, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, online.ApplyPackage("remote"))
+	require.NoError(t, online.WriteLockfile())
+
+	// The remote is unreachable, but the cache is applied as it is.
+	require.NoError(t, os.RemoveAll(repoDir))
+
+	offline, err = engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath, Offline: true})
+	require.NoError(t, err)
+	require.NoError(t, offline.ApplyPackage("remote"))
+
+	entry, resolved := offline.Resolved("remote")
+	require.True(t, resolved)
+	assert.Equal(t, commit, entry.Commit)
+
+	body, err := os.ReadFile(filepath.Join(cacheDir, "remote", "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(body))
+}
+
+func TestEngine_ApplyPackage_OfflineMismatch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	cachedCommit := commitRule(t, repoDir, "cached")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	newConfig := func(repository string) *config.Config {
+		return &config.Config{
+			Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:    config.ImportTypeGit,
+						Include: []string{config.DefaultPresetName},
+						Details: config.GitImportDetails{Repository: repository},
+					},
+				},
+				Integrations: &config.AgentIntegrations{},
+			},
+		}
+	}
+
+	online, err := engine.NewEngineWithOptions(newConfig(repoDir), engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, online.ApplyPackage("remote"))
+	require.NoError(t, online.WriteLockfile())
+
+	t.Run("source changed", func(t *testing.T) {
+		otherRepo := filepath.Join(tempDir, "other")
+		offline, engineErr := engine.NewEngineWithOptions(
+			newConfig(otherRepo),
+			engine.Options{LockfilePath: lockPath, Offline: true},
+		)
+		require.NoError(t, engineErr)
+
+		var mismatchErr *engine.CacheMismatchError
+		require.ErrorAs(t, offline.ApplyPackage("remote"), &mismatchErr)
+		assert.Equal(t, "remote", mismatchErr.PackageName)
+		assert.Equal(t, repoDir, mismatchErr.FetchedFrom)
+	})
+
+	t.Run("lockfile pins another commit", func(t *testing.T) {
+		// Someone else moved the import to a newer commit, which has not been fetched here.
+		pinnedCommit := commitRule(t, repoDir, "pinned")
+		lock, loadErr := lockfile.Load(lockPath)
+		require.NoError(t, loadErr)
+		pinnedEntry := lock.Imports["remote"]
+		pinnedEntry.Commit = pinnedCommit
+		lock.Imports["remote"] = pinnedEntry
+		require.NoError(t, lock.Save(lockPath))
+
+		offline, engineErr := engine.NewEngineWithOptions(
+			newConfig(repoDir),
+			engine.Options{LockfilePath: lockPath, Offline: true},
+		)
+		require.NoError(t, engineErr)
+		require.NoError(t, offline.ApplyPackage("remote"))
+
+		warning, hasWarning := offline.Warning("remote")
+		require.True(t, hasWarning)
+		assert.Contains(t, warning, "cache is at "+cachedCommit+", but ajisai.lock pins "+pinnedCommit)
+
+		// The cache is applied, but the lockfile keeps the pinned commit.
+		entry, resolved := offline.Resolved("remote")
+		require.True(t, resolved)
+		assert.Equal(t, pinnedEntry, entry)
+
+		body, readErr := os.ReadFile(filepath.Join(cacheDir, "remote", "rules", "rule.md"))
+		require.NoError(t, readErr)
+		assert.Equal(t, "cached", string(body))
+	})
+}
+
+func TestEngine_ApplyPackage_GlobalCache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv(globalcache.DirEnv, filepath.Join(tempDir, "global"))
+
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commit := commitRule(t, repoDir, "shared")
+
+	newProject := func(name string) (*config.Config, string) {
+		t.Helper()
+
+		projectDir := filepath.Join(tempDir, name)
+		return &config.Config{
+			Settings: &config.Settings{
+				CacheDir:    filepath.Join(projectDir, "cache"),
+				Namespace:   "ajisai",
+				GlobalCache: true,
+			},
+			Workspace: &config.Workspace{
+				Imports: map[string]config.ImportedPackage{
+					"remote": {
+						Type:    config.ImportTypeGit,
+						Include: []string{config.DefaultPresetName},
+						Details: config.GitImportDetails{Repository: repoDir},
+					},
+				},
+				Integrations: &config.AgentIntegrations{},
+			},
+		}, filepath.Join(projectDir, lockfile.FileName)
+	}
+	apply := func(cfg *config.Config, opts engine.Options) {
+		t.Helper()
+
+		eng, err := engine.NewEngineWithOptions(cfg, opts)
+		require.NoError(t, err)
+		require.NoError(t, eng.ApplyPackage("remote"))
+		require.NoError(t, eng.WriteLockfile())
+
+		entry, resolved := eng.Resolved("remote")
+		require.True(t, resolved)
+		assert.Equal(t, commit, entry.Commit)
+	}
+	cachedRule := func(cfg *config.Config) string {
+		return filepath.Join(cfg.Settings.CacheDir, "remote", "rules", "rule.md")
+	}
+	readRule := func(cfg *config.Config) string {
+		t.Helper()
+
+		body, err := os.ReadFile(cachedRule(cfg))
+		require.NoError(t, err)
+		return string(body)
+	}
+	copyLockfile := func(from string, to string) {
+		t.Helper()
+
+		body, err := os.ReadFile(from)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(to), 0750))
+		require.NoError(t, os.WriteFile(to, body, 0600))
+	}
+
+	first, firstLock := newProject("first")
+	apply(first, engine.Options{LockfilePath: firstLock})
+
+	// Another project at the same commit is linked from the global cache without accessing the remote.
+	require.NoError(t, os.Rename(repoDir, repoDir+".moved"))
+
+	second, secondLock := newProject("second")
+	copyLockfile(firstLock, secondLock)
+	apply(second, engine.Options{LockfilePath: secondLock})
+
+	firstInfo, err := os.Stat(cachedRule(first))
+	require.NoError(t, err)
+	secondInfo, err := os.Stat(cachedRule(second))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(firstInfo, secondInfo))
+
+	offline, offlineLock := newProject("offline")
+	copyLockfile(firstLock, offlineLock)
+	apply(offline, engine.Options{LockfilePath: offlineLock, Offline: true})
+	assert.Equal(t, "shared", readRule(offline))
+
+	// Without the global cache, the linked files are not written into.
+	require.NoError(t, os.Rename(repoDir+".moved", repoDir))
+	second.Settings.GlobalCache = false
+	apply(second, engine.Options{LockfilePath: secondLock})
+
+	secondInfo, err = os.Stat(cachedRule(second))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(firstInfo, secondInfo))
+	assert.Equal(t, "shared", readRule(first))
+}
+
+func TestEngine_ApplyPackage_Vendored(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commit := commitRule(t, repoDir, "vendored")
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Presets"), 0600))
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "readme")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	vendorDir := filepath.Join(tempDir, "vendor")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai", VendorDir: vendorDir},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: repoDir, Revision: commit},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, eng.VendorPackage("remote"))
+
+	// Only the files of the included presets are vendored.
+	body, err := os.ReadFile(filepath.Join(vendorDir, "remote", "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "vendored", string(body))
+	assert.NoFileExists(t, filepath.Join(vendorDir, "remote", "README.md"))
+
+	// The vendored copy is applied without accessing the remote.
+	require.NoError(t, os.Rename(repoDir, repoDir+".moved"))
+	require.NoError(t, os.RemoveAll(cacheDir))
+
+	eng, err = engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("remote"))
+
+	entry, resolved := eng.Resolved("remote")
+	require.True(t, resolved)
+	assert.Equal(t, commit, entry.Commit)
+
+	body, err = os.ReadFile(filepath.Join(cacheDir, "remote", "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "vendored", string(body))
+
+	// A vendored copy made for another config is not applied.
+	stale := cfg.Workspace.Imports["remote"]
+	stale.Include = []string{config.DefaultPresetName, "go"}
+	cfg.Workspace.Imports["remote"] = stale
+
+	var staleErr *vendoring.StaleError
+	require.ErrorAs(t, eng.ApplyPackage("remote"), &staleErr)
+	assert.Equal(t, "remote", staleErr.PackageName)
+}
+
+func TestEngine_ApplyPackage_LinkedLocal(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "presets")
+	rulePath := filepath.Join(sourceDir, "rules", "rule.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rulePath), 0750))
+	require.NoError(t, os.WriteFile(rulePath, []byte("linked"), 0600))
+
+	// A copy left by `mode: copy` is removed.
+	cacheDir := filepath.Join(tempDir, "cache")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "local", "rules"), 0750))
+
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"local": {
+					Type:    config.ImportTypeLocal,
+					Include: []string{config.DefaultPresetName},
+					Details: config.LocalImportDetails{Path: sourceDir, Mode: config.LocalImportModeLink},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("local"))
+	assert.NoDirExists(t, filepath.Join(cacheDir, "local"))
+
+	entry, resolved := eng.Resolved("local")
+	require.True(t, resolved)
+	assert.Empty(t, entry.Digest)
+
+	pkg, err := eng.LoadPackage("local")
+	require.NoError(t, err)
+	require.Len(t, pkg.Presets, 1)
+	require.Len(t, pkg.Presets[0].Rules, 1)
+	assert.Equal(t, "linked", pkg.Presets[0].Rules[0].Content)
+
+	// Edits to the source directory are read without applying again.
+	require.NoError(t, os.WriteFile(rulePath, []byte("edited"), 0600))
+	pkg, err = eng.LoadPackage("local")
+	require.NoError(t, err)
+	require.Len(t, pkg.Presets, 1)
+	require.Len(t, pkg.Presets[0].Rules, 1)
+	assert.Equal(t, "edited", pkg.Presets[0].Rules[0].Content)
+
+	require.NoError(t, os.RemoveAll(sourceDir))
+	require.ErrorContains(t, eng.ApplyPackage("local"), "does not exist")
+}
+
+func TestEngine_ApplyPackage_ConfigDir(t *testing.T) {
+	projectDir := t.TempDir()
+	rulePath := filepath.Join(projectDir, "presets", "rules", "rule.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rulePath), 0750))
+	require.NoError(t, os.WriteFile(rulePath, []byte("# Rule"), 0600))
+
+	// Relative paths are resolved against the config directory, not the working directory.
+	t.Chdir(t.TempDir())
+
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: "./.cache/ajisai", Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"local": {
+					Type:    config.ImportTypeLocal,
+					Include: []string{config.DefaultPresetName},
+					Details: config.LocalImportDetails{Path: "./presets"},
+				},
+			},
+			Integrations: &config.AgentIntegrations{
+				Cursor: &config.CursorIntegration{Enabled: true},
+			},
+		},
+		Dir: projectDir,
+	}
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("local"))
+
+	assert.FileExists(t, filepath.Join(projectDir, ".cache", "ajisai", "local", "rules", "rule.md"))
+	assert.FileExists(t, filepath.Join(projectDir, ".cursor", "rules", "ajisai", "local", "default", "rule.mdc"))
+
+	require.NoError(t, eng.CleanCache(true))
+	assert.NoDirExists(t, filepath.Join(projectDir, ".cache", "ajisai", "local"))
+}
+
+func TestEngine_ApplyPackage_RefreshInterval(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commitRule(t, repoDir, "first")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cfg := &config.Config{
+		Settings: &config.Settings{
+			CacheDir:        filepath.Join(tempDir, "cache"),
+			Namespace:       "ajisai",
+			RefreshInterval: "1h",
+		},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: repoDir},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	apply := func(opts engine.Options) error {
+		t.Helper()
+
+		eng, err := engine.NewEngineWithOptions(cfg, opts)
+		require.NoError(t, err)
+		if applyErr := eng.ApplyPackage("remote"); applyErr != nil {
+			return applyErr
+		}
+		return eng.WriteLockfile()
+	}
+
+	require.NoError(t, apply(engine.Options{LockfilePath: lockPath}))
+
+	// The remote is not accessed again within the interval.
+	require.NoError(t, os.Rename(repoDir, repoDir+".moved"))
+	require.NoError(t, apply(engine.Options{LockfilePath: lockPath}))
+	require.Error(t, apply(engine.Options{LockfilePath: lockPath, Refresh: true}))
+
+	// An import can opt out of the interval.
+	remote := cfg.Workspace.Imports["remote"]
+	remote.RefreshInterval = "0"
+	cfg.Workspace.Imports["remote"] = remote
+	require.Error(t, apply(engine.Options{LockfilePath: lockPath}))
+
+	require.NoError(t, os.Rename(repoDir+".moved", repoDir))
+	require.NoError(t, apply(engine.Options{LockfilePath: lockPath}))
+}
+
+func TestEngine_ApplyPackage_VersionRange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commitRule(t, repoDir, "v1.4.0")
+	runGit(t, repoDir, "tag", "v1.4.0")
+	matchingCommit := commitRule(t, repoDir, "v1.4.2")
+	runGit(t, repoDir, "tag", "v1.4.2")
+	commitRule(t, repoDir, "v2.0.0")
+	runGit(t, repoDir, "tag", "v2.0.0")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: filepath.Join(tempDir, "cache"), Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{Repository: repoDir, Revision: "^1.4"},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("remote"))
+	require.NoError(t, eng.WriteLockfile())
+
+	entry, resolved := eng.Resolved("remote")
+	require.True(t, resolved)
+	assert.Equal(t, "v1.4.2", entry.Version)
+	assert.Equal(t, matchingCommit, entry.Commit)
+
+	// The version is kept from the lockfile when the pinned commit is reused.
+	eng, err = engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("remote"))
+
+	entry, resolved = eng.Resolved("remote")
+	require.True(t, resolved)
+	assert.Equal(t, "v1.4.2", entry.Version)
+}
+
+func TestEngine_ApplyPackage_GitSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	for path, body := range map[string]string{
+		"packages/go/ajisai.yml":        "package:\n  exports:\n    go:\n      rules: [rules/**/*.md]\n",
+		"packages/go/rules/go.md":       "---\nattach: always\n---\ngo",
+		"packages/frontend/rules/ts.md": "---\nattach: always\n---\nts",
+		"packages/frontend/ajisai.yml":  "package:\n  exports:\n    ts:\n      rules: [rules/**/*.md]\n",
+		"README.md":                     "monorepo",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoDir, path)), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, path), []byte(body), 0600))
+	}
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "init")
+
+	cacheDir := filepath.Join(tempDir, "cache")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"go": {
+					Type:    config.ImportTypeGit,
+					Include: []string{"go"},
+					Details: config.GitImportDetails{
+						Repository: "file://" + filepath.ToSlash(repoDir),
+						Path:       "packages/go",
+					},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	require.NoError(t, eng.ApplyPackage("go"))
+
+	pkg, err := eng.LoadPackage("go")
+	require.NoError(t, err)
+	require.Len(t, pkg.Presets, 1)
+	require.Len(t, pkg.Presets[0].Rules, 1)
+	assert.Equal(t, "go", pkg.Presets[0].Rules[0].Content)
+
+	// Other packages in the repository are not checked out.
+	assert.NoDirExists(t, filepath.Join(cacheDir, "go", "packages", "frontend"))
+}
+
+func TestEngine_ApplyPackage_ShallowClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	commitRule(t, repoDir, "first")
+	secondCommit := commitRule(t, repoDir, "second")
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"remote": {
+					Type:    config.ImportTypeGit,
+					Include: []string{config.DefaultPresetName},
+					Details: config.GitImportDetails{
+						// Local paths ignore --depth, so the file protocol is used.
+						Repository: "file://" + filepath.ToSlash(repoDir),
+						Depth:      1,
+						Filter:     "blob:none",
+					},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+	cacheRoot := filepath.Join(cacheDir, "remote")
+
+	apply := func() {
+		t.Helper()
+
+		eng, err := engine.NewEngineWithOptions(cfg, engine.Options{LockfilePath: lockPath})
+		require.NoError(t, err)
+		require.NoError(t, eng.ApplyPackage("remote"))
+		require.NoError(t, eng.WriteLockfile())
+	}
+
+	apply()
+	assert.Equal(t, "true", runGit(t, cacheRoot, "rev-parse", "--is-shallow-repository"))
+	assert.Equal(t, "1", runGit(t, cacheRoot, "rev-list", "--count", "HEAD"))
+
+	// The locked commit is no longer the tip. The remote does not serve it by SHA,
+	// so the clone is deepened to reach it.
+	commitRule(t, repoDir, "third")
+	require.NoError(t, os.RemoveAll(cacheRoot))
+
+	apply()
+	assert.Equal(t, secondCommit, runGit(t, cacheRoot, "rev-parse", "HEAD"))
+	body, err := os.ReadFile(filepath.Join(cacheRoot, "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(body))
+}
+
+func TestEngine_ApplyPackage_OCILockfile(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	tempDir := t.TempDir()
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:latest")
+	require.NoError(t, err)
+
+	push := func(body string) string {
+		t.Helper()
+
+		pkgDir := filepath.Join(t.TempDir(), "presets")
+		require.NoError(t, os.MkdirAll(filepath.Join(pkgDir, "rules"), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "rules", "rule.md"), []byte(body), 0600))
+
+		var archive bytes.Buffer
+		_, packErr := packer.Pack(pkgDir, &archive)
+		require.NoError(t, packErr)
+
+		digest, pushErr := oci.NewClient().PushPackage(ref, archive.Bytes(), nil)
+		require.NoError(t, pushErr)
+		return digest
+	}
+
+	lockPath := filepath.Join(tempDir, lockfile.FileName)
+	cacheDir := filepath.Join(tempDir, "cache")
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir, Namespace: "ajisai"},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"presets": {
+					Type:    config.ImportTypeOCI,
+					Include: []string{config.DefaultPresetName},
+					Details: config.OCIImportDetails{Ref: ref.String()},
+				},
+			},
+			Integrations: &config.AgentIntegrations{},
+		},
+	}
+
+	apply := func(opts engine.Options) {
+		t.Helper()
+
+		eng, engErr := engine.NewEngineWithOptions(cfg, opts)
+		require.NoError(t, engErr)
+		require.NoError(t, eng.ApplyPackage("presets"))
+		require.NoError(t, eng.WriteLockfile())
+	}
+	lockedDigest := func() string {
+		t.Helper()
+
+		lock, loadErr := lockfile.Load(lockPath)
+		require.NoError(t, loadErr)
+		return lock.Imports["presets"].Digest
+	}
+
+	firstDigest := push("first")
+	apply(engine.Options{LockfilePath: lockPath})
+	assert.Equal(t, firstDigest, lockedDigest())
+
+	// The locked digest is kept even though the tag moved on.
+	secondDigest := push("second")
+	apply(engine.Options{LockfilePath: lockPath})
+	assert.Equal(t, firstDigest, lockedDigest())
+
+	apply(engine.Options{LockfilePath: lockPath, Update: true})
+	assert.Equal(t, secondDigest, lockedDigest())
+
+	body, err := os.ReadFile(filepath.Join(cacheDir, "presets", "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(body))
+}
@@ -0,0 +1,461 @@
+package fetcher
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	archiveFormatTarGzip archiveFormat = iota
+	archiveFormatTar
+	archiveFormatZip
+)
+
+const (
+	// DefaultMaxArchiveSize is the default limit on the size of a downloaded archive.
+	DefaultMaxArchiveSize = 256 << 20
+	// DefaultMaxExtractedSize is the default limit on the total size of the files extracted from an archive.
+	DefaultMaxExtractedSize = 1 << 30
+	// DefaultMaxArchiveEntries is the default limit on the number of entries in an archive.
+	DefaultMaxArchiveEntries = 100_000
+)
+
+type (
+	// ArchiveFetcher downloads a `.tar.gz`, `.tar` or `.zip` archive, verifies its checksum and extracts it.
+	ArchiveFetcher struct {
+		client  *http.Client
+		mirrors *Mirrors
+		limits  ArchiveLimits
+	}
+
+	// ArchiveLimits bounds the resources an archive may use, so that a huge or malicious archive
+	// cannot fill up the disk.
+	ArchiveLimits struct {
+		// MaxDownloadSize is the maximum size of the archive itself in bytes.
+		MaxDownloadSize int64
+		// MaxExtractedSize is the maximum total size of the extracted files in bytes.
+		MaxExtractedSize int64
+		// MaxEntries is the maximum number of entries in the archive.
+		MaxEntries int
+	}
+
+	// archiveExtractor extracts the entries of an archive into dest while keeping track of the limits.
+	archiveExtractor struct {
+		dest    string
+		strip   int
+		limits  ArchiveLimits
+		written int64
+		entries int
+	}
+
+	archiveFormat int
+
+	// ChecksumMismatchError is returned when a downloaded archive does not match the configured checksum.
+	ChecksumMismatchError struct {
+		URL      string
+		Expected string
+		Actual   string
+	}
+
+	// UnsafeArchivePathError is returned when an archive entry would be extracted outside the destination.
+	UnsafeArchivePathError struct {
+		Name string
+	}
+
+	// ArchiveTooLargeError is returned when an archive exceeds one of its ArchiveLimits.
+	ArchiveTooLargeError struct {
+		// Limit describes the exceeded limit, e.g. "1073741824 bytes of extracted files".
+		Limit string
+	}
+)
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.URL, e.Expected, e.Actual)
+}
+
+func (e *ChecksumMismatchError) Unwrap() error {
+	return nil
+}
+
+func (e *UnsafeArchivePathError) Error() string {
+	return fmt.Sprintf("archive entry %q points outside the extraction directory", e.Name)
+}
+
+func (e *UnsafeArchivePathError) Unwrap() error {
+	return nil
+}
+
+func (e *ArchiveTooLargeError) Error() string {
+	return "archive exceeds the limit of " + e.Limit
+}
+
+func (e *ArchiveTooLargeError) Unwrap() error {
+	return nil
+}
+
+var _ domain.PackageFetcher = (*ArchiveFetcher)(nil)
+
+// NewArchiveFetcher creates a new ArchiveFetcher.
+func NewArchiveFetcher() *ArchiveFetcher {
+	return NewArchiveFetcherWithClient(http.DefaultClient)
+}
+
+// NewArchiveFetcherWithClient creates a new ArchiveFetcher that downloads with the given HTTP client.
+func NewArchiveFetcherWithClient(client *http.Client) *ArchiveFetcher {
+	return &ArchiveFetcher{client: client, limits: DefaultArchiveLimits()}
+}
+
+// NewArchiveFetcherWithMirrors creates a new ArchiveFetcher that downloads archives from their mirrors.
+func NewArchiveFetcherWithMirrors(mirrors *Mirrors) *ArchiveFetcher {
+	return &ArchiveFetcher{client: http.DefaultClient, mirrors: mirrors, limits: DefaultArchiveLimits()}
+}
+
+// NewArchiveFetcherWithLimits creates a new ArchiveFetcher that refuses archives exceeding the given limits.
+func NewArchiveFetcherWithLimits(limits ArchiveLimits) *ArchiveFetcher {
+	return &ArchiveFetcher{client: http.DefaultClient, limits: limits}
+}
+
+// DefaultArchiveLimits returns the limits used unless others are given.
+func DefaultArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{
+		MaxDownloadSize:  DefaultMaxArchiveSize,
+		MaxExtractedSize: DefaultMaxExtractedSize,
+		MaxEntries:       DefaultMaxArchiveEntries,
+	}
+}
+
+// Fetch downloads the archive, verifies its SHA-256 checksum and extracts it into destinationDir.
+// The download is skipped if destinationDir already holds the same archive,
+// since the checksum guarantees that its content has not changed.
+func (f *ArchiveFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
+	archiveDetails, ok := config.GetImportDetails[config.ArchiveImportDetails](source)
+	if !ok {
+		return &InvalidSourceTypeError{
+			expectedType: config.ImportTypeArchive,
+			actualType:   source.Type,
+			err:          fmt.Errorf("cannot fetch from source type: %s", source.Type),
+		}
+	}
+
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return err
+	}
+
+	// The archive is downloaded from its mirror, but is still identified by its own URL.
+	remote := archiveDetails
+	remote.URL = f.mirrors.Rewrite(archiveDetails.URL)
+	metadata := NewCacheMetadata(source).withMirror(remote.URL)
+
+	if cached, _ := ReadCacheMetadata(destAbsDir); cached != nil && cached.sameSource(metadata) {
+		return nil
+	}
+
+	parentDir := filepath.Dir(destAbsDir)
+	if err = utils.EnsureDir(parentDir); err != nil {
+		return err
+	}
+
+	archive, err := f.download(remote, parentDir)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+
+	// Extract next to the destination first, so that a broken archive does not destroy the current cache.
+	staging, err := os.MkdirTemp(parentDir, "."+filepath.Base(destAbsDir)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	extractor := &archiveExtractor{dest: staging, strip: archiveDetails.StripComponents, limits: f.limits}
+	if err = extractor.extract(archive); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", remote.URL, err)
+	}
+
+	if err = writeCacheMetadata(staging, metadata); err != nil {
+		return err
+	}
+
+	if err = utils.EmptyDir(destAbsDir); err != nil {
+		return err
+	}
+
+	return os.Rename(staging, destAbsDir)
+}
+
+// Resolve returns the content digest of destinationDir.
+func (f *ArchiveFetcher) Resolve(destinationDir string) (string, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.DigestDir(destAbsDir, CacheMetadataFileName)
+}
+
+// download saves the archive into a temporary file in dir and verifies its checksum.
+// It returns the path of the temporary file, which the caller must remove.
+func (f *ArchiveFetcher) download(details config.ArchiveImportDetails, dir string) (string, error) {
+	body, err := f.open(details.URL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(dir, ".ajisai-archive-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer tmp.Close()
+
+	hash := sha256.New()
+	limit := f.limits.MaxDownloadSize
+	written, copyErr := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, limit+1))
+	if copyErr == nil && written > limit {
+		copyErr = &ArchiveTooLargeError{Limit: fmt.Sprintf("%d bytes of download", limit)}
+	}
+	if copyErr != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to download %s: %w", details.URL, copyErr)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, details.SHA256) {
+		os.Remove(tmp.Name())
+		return "", &ChecksumMismatchError{URL: details.URL, Expected: details.SHA256, Actual: actual}
+	}
+
+	return tmp.Name(), nil
+}
+
+// open returns the content at an http(s) or `file://` URL.
+func (f *ArchiveFetcher) open(rawURL string) (io.ReadCloser, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive url %s: %w", rawURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		return os.Open(filepath.FromSlash(parsed.Path))
+	case "http", "https":
+		req, reqErr := http.NewRequestWithContext(context.Background(), http.MethodGet, rawURL, nil)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+
+		resp, respErr := f.client.Do(req)
+		if respErr != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", rawURL, respErr)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+		}
+
+		return resp.Body, nil
+	}
+
+	return nil, fmt.Errorf("unsupported archive url scheme %q", parsed.Scheme)
+}
+
+// extract extracts the archive at archivePath into dest, removing the first strip components of each entry.
+// Entries other than regular files and directories, such as symlinks, are skipped.
+func (x *archiveExtractor) extract(archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format, err := detectArchiveFormat(file)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case archiveFormatTarGzip:
+		gz, gzErr := gzip.NewReader(file)
+		if gzErr != nil {
+			return gzErr
+		}
+		defer gz.Close()
+
+		return x.extractTar(gz)
+	case archiveFormatTar:
+		return x.extractTar(file)
+	case archiveFormatZip:
+		return x.extractZip(archivePath)
+	}
+
+	return nil
+}
+
+// detectArchiveFormat detects the format from the leading bytes of the file, since release asset URLs
+// do not always end with the file extension.
+func detectArchiveFormat(file *os.File) (archiveFormat, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	header = header[:n]
+
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return 0, seekErr
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return archiveFormatTarGzip, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return archiveFormatZip, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return archiveFormatTar, nil
+	}
+
+	return 0, errors.New("unsupported archive format (supported: .tar.gz, .tar, .zip)")
+}
+
+func (x *archiveExtractor) extractTar(reader io.Reader) error {
+	tr := tar.NewReader(reader)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, ok, targetErr := x.entryTarget(header.Name)
+		if targetErr != nil {
+			return targetErr
+		}
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if mkdirErr := os.MkdirAll(target, 0750); mkdirErr != nil {
+				return mkdirErr
+			}
+		case tar.TypeReg:
+			if writeErr := x.writeFile(target, tr); writeErr != nil {
+				return writeErr
+			}
+		}
+	}
+}
+
+func (x *archiveExtractor) extractZip(archivePath string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		target, ok, targetErr := x.entryTarget(file.Name)
+		if targetErr != nil {
+			return targetErr
+		}
+		if !ok {
+			continue
+		}
+
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if mkdirErr := os.MkdirAll(target, 0750); mkdirErr != nil {
+				return mkdirErr
+			}
+		case mode.IsRegular():
+			if writeErr := x.writeZipFile(target, file); writeErr != nil {
+				return writeErr
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *archiveExtractor) writeZipFile(target string, file *zip.File) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return x.writeFile(target, reader)
+}
+
+// writeFile writes the content of an entry to target, failing once the extracted files exceed MaxExtractedSize.
+func (x *archiveExtractor) writeFile(target string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	remaining := x.limits.MaxExtractedSize - x.written
+	written, err := io.Copy(file, io.LimitReader(reader, remaining+1))
+	x.written += written
+	if err != nil {
+		return err
+	}
+	if written > remaining {
+		return &ArchiveTooLargeError{Limit: fmt.Sprintf("%d bytes of extracted files", x.limits.MaxExtractedSize)}
+	}
+
+	return file.Close()
+}
+
+// entryTarget returns the path the archive entry is extracted to.
+// It returns false if the entry is removed by strip, and an error if the entry points outside dest
+// or the archive has more than MaxEntries entries.
+func (x *archiveExtractor) entryTarget(name string) (string, bool, error) {
+	x.entries++
+	if x.entries > x.limits.MaxEntries {
+		return "", false, &ArchiveTooLargeError{Limit: fmt.Sprintf("%d entries", x.limits.MaxEntries)}
+	}
+
+	cleaned := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if !filepath.IsLocal(filepath.FromSlash(cleaned)) {
+		return "", false, &UnsafeArchivePathError{Name: name}
+	}
+
+	parts := strings.Split(cleaned, "/")
+	if cleaned == "." || len(parts) <= x.strip {
+		return "", false, nil
+	}
+
+	return filepath.Join(x.dest, filepath.FromSlash(path.Join(parts[x.strip:]...))), true, nil
+}
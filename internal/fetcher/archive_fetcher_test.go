@@ -0,0 +1,247 @@
+package fetcher_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+)
+
+type archiveEntry struct {
+	name    string
+	content string
+}
+
+func tarGzArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveArchive serves the archive and counts the requests.
+func serveArchive(t *testing.T, body []byte) (string, *int) {
+	t.Helper()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/presets.tar.gz", &requests
+}
+
+func archiveImport(url string, sha string, strip int) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeArchive,
+		Include: []string{"default"},
+		Details: config.ArchiveImportDetails{URL: url, SHA256: sha, StripComponents: strip},
+	}
+}
+
+func TestArchiveFetcher_Fetch_TarGz(t *testing.T) {
+	body := tarGzArchive(t,
+		archiveEntry{name: "presets-1.0.0/rules/style.md", content: "# Style"},
+		archiveEntry{name: "presets-1.0.0/prompts/review.md", content: "# Review"},
+	)
+	url, requests := serveArchive(t, body)
+	destDir := filepath.Join(t.TempDir(), "cache", "presets")
+
+	archiveFetcher := fetcher.NewArchiveFetcher()
+	require.NoError(t, archiveFetcher.Fetch(archiveImport(url, checksum(body), 1), destDir))
+
+	content, err := os.ReadFile(filepath.Join(destDir, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style", string(content))
+	assert.FileExists(t, filepath.Join(destDir, "prompts", "review.md"))
+
+	// The same archive is not downloaded again.
+	require.NoError(t, archiveFetcher.Fetch(archiveImport(url, checksum(body), 1), destDir))
+	assert.Equal(t, 1, *requests)
+}
+
+func TestArchiveFetcher_Fetch_ZipFromFileURL(t *testing.T) {
+	body := zipArchive(t, archiveEntry{name: "rules/style.md", content: "# Style"})
+	archivePath := filepath.Join(t.TempDir(), "presets.zip")
+	require.NoError(t, os.WriteFile(archivePath, body, 0600))
+	destDir := filepath.Join(t.TempDir(), "presets")
+
+	url := "file://" + filepath.ToSlash(archivePath)
+	require.NoError(t, fetcher.NewArchiveFetcher().Fetch(archiveImport(url, checksum(body), 0), destDir))
+
+	content, err := os.ReadFile(filepath.Join(destDir, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style", string(content))
+}
+
+func TestArchiveFetcher_Fetch_ChecksumMismatch(t *testing.T) {
+	body := tarGzArchive(t, archiveEntry{name: "rules/style.md", content: "# Style"})
+	url, _ := serveArchive(t, body)
+	destDir := filepath.Join(t.TempDir(), "presets")
+	require.NoError(t, os.MkdirAll(destDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "previous.md"), []byte("previous"), 0600))
+
+	wrong := checksum([]byte("something else"))
+	err := fetcher.NewArchiveFetcher().Fetch(archiveImport(url, wrong, 0), destDir)
+
+	var mismatchErr *fetcher.ChecksumMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, wrong, mismatchErr.Expected)
+	assert.Equal(t, checksum(body), mismatchErr.Actual)
+
+	// The previous cache is kept.
+	assert.FileExists(t, filepath.Join(destDir, "previous.md"))
+}
+
+func TestArchiveFetcher_Fetch_PathTraversal(t *testing.T) {
+	body := tarGzArchive(t,
+		archiveEntry{name: "rules/style.md", content: "# Style"},
+		archiveEntry{name: "rules/../../escape.md", content: "escaped"},
+	)
+	url, _ := serveArchive(t, body)
+	parentDir := t.TempDir()
+	destDir := filepath.Join(parentDir, "presets")
+
+	err := fetcher.NewArchiveFetcher().Fetch(archiveImport(url, checksum(body), 0), destDir)
+
+	var unsafeErr *fetcher.UnsafeArchivePathError
+	require.ErrorAs(t, err, &unsafeErr)
+	assert.Equal(t, "rules/../../escape.md", unsafeErr.Name)
+	assert.NoFileExists(t, filepath.Join(parentDir, "escape.md"))
+	assert.NoDirExists(t, destDir)
+}
+
+func TestArchiveFetcher_Fetch_TooLarge(t *testing.T) {
+	body := tarGzArchive(t,
+		archiveEntry{name: "rules/style.md", content: "# Style"},
+		archiveEntry{name: "rules/review.md", content: "# Review"},
+	)
+
+	tests := []struct {
+		name   string
+		limits fetcher.ArchiveLimits
+		want   string
+	}{
+		{
+			name:   "download",
+			limits: fetcher.ArchiveLimits{MaxDownloadSize: 16, MaxExtractedSize: 1 << 20, MaxEntries: 10},
+			want:   "16 bytes of download",
+		},
+		{
+			name:   "extracted files",
+			limits: fetcher.ArchiveLimits{MaxDownloadSize: 1 << 20, MaxExtractedSize: 10, MaxEntries: 10},
+			want:   "10 bytes of extracted files",
+		},
+		{
+			name:   "entries",
+			limits: fetcher.ArchiveLimits{MaxDownloadSize: 1 << 20, MaxExtractedSize: 1 << 20, MaxEntries: 1},
+			want:   "1 entries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, _ := serveArchive(t, body)
+			destDir := filepath.Join(t.TempDir(), "presets")
+			require.NoError(t, os.MkdirAll(destDir, 0750))
+			require.NoError(t, os.WriteFile(filepath.Join(destDir, "previous.md"), []byte("previous"), 0600))
+
+			err := fetcher.NewArchiveFetcherWithLimits(tt.limits).Fetch(archiveImport(url, checksum(body), 0), destDir)
+
+			var tooLargeErr *fetcher.ArchiveTooLargeError
+			require.ErrorAs(t, err, &tooLargeErr)
+			assert.Equal(t, tt.want, tooLargeErr.Limit)
+
+			// The previous cache is kept, and nothing is left next to it.
+			assert.FileExists(t, filepath.Join(destDir, "previous.md"))
+			siblings, readErr := os.ReadDir(filepath.Dir(destDir))
+			require.NoError(t, readErr)
+			assert.Len(t, siblings, 1)
+		})
+	}
+}
+
+func TestArchiveFetcher_Fetch_UnsupportedFormat(t *testing.T) {
+	body := []byte("not an archive")
+	url, _ := serveArchive(t, body)
+
+	err := fetcher.NewArchiveFetcher().Fetch(archiveImport(url, checksum(body), 0), t.TempDir())
+
+	require.ErrorContains(t, err, "unsupported archive format")
+}
+
+func TestArchiveFetcher_Fetch_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	err := fetcher.NewArchiveFetcher().Fetch(
+		archiveImport(server.URL+"/missing.tar.gz", checksum(nil), 0),
+		t.TempDir(),
+	)
+
+	require.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestArchiveFetcher_InvalidSourceType(t *testing.T) {
+	source := config.ImportedPackage{
+		Type:    config.ImportTypeGit,
+		Details: config.GitImportDetails{Repository: "https://github.com/example/repo.git"},
+	}
+
+	err := fetcher.NewArchiveFetcher().Fetch(source, t.TempDir())
+
+	var sourceTypeErr *fetcher.InvalidSourceTypeError
+	require.ErrorAs(t, err, &sourceTypeErr)
+	assert.Equal(t, config.ImportTypeArchive, sourceTypeErr.ExpectedType())
+}
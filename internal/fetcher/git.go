@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/versionrange"
+)
+
+// Git fetches git imports and inspects their remote repositories.
+// GitFetcher implements it with the `git` command, and GoGitFetcher without it.
+type Git interface {
+	domain.PackageFetcher
+
+	// ListRemoteRefs returns the branches and tags of the remote repository without fetching it.
+	ListRemoteRefs(repository string) (*RemoteRefs, error)
+
+	// ResolveVersion returns the highest tag of the remote repository that satisfies the version range constraint.
+	ResolveVersion(repository string, constraint string) (string, error)
+
+	// ChangedFiles returns the files that differ between two commits of the repository in destinationDir.
+	ChangedFiles(destinationDir string, from string, to string) ([]FileChange, error)
+}
+
+var (
+	_ Git = (*GitFetcher)(nil)
+	_ Git = (*GoGitFetcher)(nil)
+)
+
+// NewGit returns the git implementation selected by the backend setting, authenticating with the credentials
+// and fetching from the mirrors. Both may be nil.
+// GitBackendAuto uses the `git` command if it is found on PATH, and the built-in implementation otherwise.
+func NewGit(backend config.GitBackend, credentials *GitCredentials, mirrors *Mirrors) Git {
+	switch backend {
+	case config.GitBackendCLI:
+		return newGitFetcher(credentials, mirrors)
+	case config.GitBackendGo:
+		return newGoGitFetcher(credentials, mirrors)
+	case config.GitBackendAuto:
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return newGoGitFetcher(credentials, mirrors)
+	}
+
+	return newGitFetcher(credentials, mirrors)
+}
+
+func newGitFetcher(credentials *GitCredentials, mirrors *Mirrors) *GitFetcher {
+	fetcher := NewGitFetcherWithCredentials(credentials)
+	fetcher.mirrors = mirrors
+	return fetcher
+}
+
+func newGoGitFetcher(credentials *GitCredentials, mirrors *Mirrors) *GoGitFetcher {
+	fetcher := NewGoGitFetcherWithCredentials(credentials)
+	fetcher.mirrors = mirrors
+	return fetcher
+}
+
+// resolveVersion returns the highest tag listed by listRemoteRefs that satisfies the version range constraint.
+func resolveVersion(
+	listRemoteRefs func(repository string) (*RemoteRefs, error),
+	repository string,
+	constraint string,
+) (string, error) {
+	parsed, err := versionrange.Parse(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	refs, err := listRemoteRefs(repository)
+	if err != nil {
+		return "", err
+	}
+
+	tag, found := parsed.Highest(slices.Collect(maps.Keys(refs.Tags)))
+	if !found {
+		return "", fmt.Errorf("no tag of %s satisfies %s", repository, constraint)
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,242 @@
+package fetcher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	// defaultTokenUsername is sent with tokens if no user name is configured. GitHub accepts any user name,
+	// and GitLab and Gitea accept tokens as the password of any user.
+	defaultTokenUsername = "x-access-token"
+
+	defaultSSHUser = "git"
+)
+
+type (
+	// GitCredentials finds the credentials configured for git remotes.
+	GitCredentials struct {
+		repositories map[string]config.GitAuth
+		hosts        map[string]config.GitAuth
+
+		// configDir is the directory relative SSH key paths are resolved from.
+		configDir string
+	}
+
+	// MissingTokenError is returned when the environment variable that should hold a token is not set.
+	MissingTokenError struct {
+		Repository string
+		TokenEnv   string
+	}
+)
+
+func (e *MissingTokenError) Error() string {
+	return fmt.Sprintf("environment variable %s holding the token for %s is not set", e.TokenEnv, e.Repository)
+}
+
+func (e *MissingTokenError) Unwrap() error {
+	return nil
+}
+
+// NewGitCredentials collects the credentials of the git imports and of the hosts in the settings.
+func NewGitCredentials(cfg *config.Config) *GitCredentials {
+	credentials := &GitCredentials{
+		repositories: make(map[string]config.GitAuth),
+		hosts:        make(map[string]config.GitAuth),
+		configDir:    cfg.Dir,
+	}
+
+	if cfg.Settings != nil {
+		for host, auth := range cfg.Settings.GitAuth {
+			credentials.hosts[strings.ToLower(host)] = resolveSSHKey(cfg.Dir, auth)
+		}
+	}
+
+	if cfg.Workspace != nil {
+		for _, pkg := range cfg.Workspace.Imports {
+			if details, isGit := config.GetImportDetails[config.GitImportDetails](pkg); isGit &&
+				details.Auth != (config.GitAuth{}) {
+				credentials.repositories[details.Repository] = resolveSSHKey(cfg.Dir, details.Auth)
+			}
+		}
+	}
+
+	return credentials
+}
+
+// resolveSSHKey makes a relative SSH key path of auth relative to configDir, the directory of the config file.
+// Absolute paths and paths in the home directory (`~/`) are left as is.
+func resolveSSHKey(configDir string, auth config.GitAuth) config.GitAuth {
+	if auth.SSHKey == "" || filepath.IsAbs(auth.SSHKey) || strings.HasPrefix(auth.SSHKey, "~/") {
+		return auth
+	}
+
+	if key, err := utils.ResolveAbsPathFrom(configDir, auth.SSHKey); err == nil {
+		auth.SSHKey = key
+	}
+	return auth
+}
+
+// Lookup returns the credentials of the import that fetches the repository, or else those of its host.
+func (c *GitCredentials) Lookup(repository string) (config.GitAuth, bool) {
+	return c.lookup(repository, repository)
+}
+
+// lookup returns the credentials of the import that fetches the repository, or else those of the host
+// of remoteURL, which is the repository rewritten by the mirror rules.
+func (c *GitCredentials) lookup(repository string, remoteURL string) (config.GitAuth, bool) {
+	if c == nil {
+		return config.GitAuth{}, false
+	}
+
+	if auth, found := c.repositories[repository]; found {
+		return auth, true
+	}
+
+	for _, host := range remoteHosts(remoteURL) {
+		if auth, found := c.hosts[host]; found {
+			return auth, true
+		}
+	}
+
+	return config.GitAuth{}, false
+}
+
+// ForImport returns the credentials of the import itself, or else those found for its repository
+// fetched from remoteURL. A relative SSH key path of the import is resolved from the directory of the config file.
+func (c *GitCredentials) ForImport(details config.GitImportDetails, remoteURL string) config.GitAuth {
+	if details.Auth != (config.GitAuth{}) {
+		if c == nil {
+			return details.Auth
+		}
+		return resolveSSHKey(c.configDir, details.Auth)
+	}
+
+	auth, _ := c.lookup(details.Repository, remoteURL)
+	return auth
+}
+
+// remoteHosts returns the lowercase host of a git remote, e.g. `github.com` for `git@github.com:org/repo.git`.
+// If the remote has a port, the host with the port comes first. It returns nothing for local paths.
+func remoteHosts(repository string) []string {
+	endpoint, err := transport.NewEndpoint(repository)
+	if err != nil || endpoint.Host == "" {
+		return nil
+	}
+
+	host := strings.ToLower(endpoint.Host)
+	if endpoint.Port == 0 {
+		return []string{host}
+	}
+
+	return []string{net.JoinHostPort(host, strconv.Itoa(endpoint.Port)), host}
+}
+
+// gitCommandEnv returns the environment variables that pass the credentials to the `git` command.
+// The token is passed as configuration in the environment, so that it is neither stored in the clone
+// nor visible in the command line.
+func gitCommandEnv(auth config.GitAuth, repository string) ([]string, error) {
+	env := make([]string, 0)
+
+	if auth.TokenEnv != "" {
+		token, err := readToken(auth, repository)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials := base64.StdEncoding.EncodeToString([]byte(tokenUsername(auth) + ":" + token))
+
+		env = append(env, gitConfigEnv("http.extraHeader", "Authorization: Basic "+credentials)...)
+	}
+
+	if auth.SSHKey != "" {
+		key, err := utils.ResolveAbsPath(auth.SSHKey)
+		if err != nil {
+			return nil, err
+		}
+
+		env = append(env, "GIT_SSH_COMMAND=ssh -i "+shellQuote(key)+" -o IdentitiesOnly=yes")
+	}
+
+	return env, nil
+}
+
+// gitConfigEnv returns the environment variables that add a configuration entry to the `git` command.
+// The entry is appended after the entries the user already sets with `GIT_CONFIG_COUNT`, instead of replacing them.
+func gitConfigEnv(key string, value string) []string {
+	index, err := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	if err != nil || index < 0 {
+		index = 0
+	}
+
+	return []string{
+		"GIT_CONFIG_COUNT=" + strconv.Itoa(index+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", index, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", index, value),
+	}
+}
+
+// goGitAuth returns the credentials for the built-in git implementation, or nil if none apply to the remote.
+func goGitAuth(auth config.GitAuth, repository string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case endpoint.Protocol == "ssh" && auth.SSHKey != "":
+		key, keyErr := utils.ResolveAbsPath(auth.SSHKey)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+
+		user := endpoint.User
+		if user == "" {
+			user = defaultSSHUser
+		}
+		return gitssh.NewPublicKeysFromFile(user, key, "")
+	case (endpoint.Protocol == "http" || endpoint.Protocol == "https") && auth.TokenEnv != "":
+		token, tokenErr := readToken(auth, repository)
+		if tokenErr != nil {
+			return nil, tokenErr
+		}
+
+		return &githttp.BasicAuth{Username: tokenUsername(auth), Password: token}, nil
+	}
+
+	return nil, nil //nolint:nilnil // No credentials apply to the remote.
+}
+
+func readToken(auth config.GitAuth, repository string) (string, error) {
+	token := os.Getenv(auth.TokenEnv)
+	if token == "" {
+		return "", &MissingTokenError{Repository: repository, TokenEnv: auth.TokenEnv}
+	}
+
+	return token, nil
+}
+
+func tokenUsername(auth config.GitAuth) string {
+	if auth.Username == "" {
+		return defaultTokenUsername
+	}
+
+	return auth.Username
+}
+
+// shellQuote quotes s for the shell that runs GIT_SSH_COMMAND.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
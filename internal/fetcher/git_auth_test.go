@@ -0,0 +1,274 @@
+package fetcher_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gomock "go.uber.org/mock/gomock"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	utils "github.com/sushichan044/ajisai/utils/mocks"
+)
+
+// serveRepository serves the repository over smart HTTP with `git http-backend`,
+// accepting only requests with the given basic auth credentials.
+func serveRepository(t *testing.T, repoDir string, username string, password string) string {
+	t.Helper()
+
+	gitPath, err := exec.LookPath("git")
+	require.NoError(t, err)
+
+	backend := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(repoDir), "GIT_HTTP_EXPORT_ALL=1"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != username || pass != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/" + filepath.Base(repoDir)
+}
+
+func TestGitCredentials_Lookup(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := &config.Config{
+		Settings: &config.Settings{
+			GitAuth: map[string]config.GitAuth{
+				"GitHub.com":       {TokenEnv: "GITHUB_TOKEN"},
+				"git.example.com":  {SSHKey: "~/.ssh/example"},
+				"deploy.local.dev": {SSHKey: "keys/deploy"},
+				"localhost:8080":   {TokenEnv: "LOCAL_TOKEN"},
+				"unused.local.dev": {TokenEnv: "UNUSED_TOKEN"},
+			},
+		},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"private": {
+					Type: config.ImportTypeGit,
+					Details: config.GitImportDetails{
+						Repository: "https://github.com/example/private.git",
+						Auth:       config.GitAuth{TokenEnv: "PRIVATE_TOKEN"},
+					},
+				},
+			},
+		},
+		Dir: projectDir,
+	}
+	credentials := fetcher.NewGitCredentials(cfg)
+
+	tests := map[string]struct {
+		repository string
+		want       config.GitAuth
+		found      bool
+	}{
+		"import":    {"https://github.com/example/private.git", config.GitAuth{TokenEnv: "PRIVATE_TOKEN"}, true},
+		"https":     {"https://github.com/example/public.git", config.GitAuth{TokenEnv: "GITHUB_TOKEN"}, true},
+		"scp-like":  {"git@git.example.com:org/repo.git", config.GitAuth{SSHKey: "~/.ssh/example"}, true},
+		"ssh":       {"ssh://git@git.example.com/org/repo.git", config.GitAuth{SSHKey: "~/.ssh/example"}, true},
+		"with port": {"http://localhost:8080/repo.git", config.GitAuth{TokenEnv: "LOCAL_TOKEN"}, true},
+		"relative key": {
+			"git@deploy.local.dev:org/repo.git",
+			config.GitAuth{SSHKey: filepath.Join(projectDir, "keys", "deploy")},
+			true,
+		},
+		"unknown": {"https://gitlab.com/example/repo.git", config.GitAuth{}, false},
+		"local":   {"/srv/git/repo", config.GitAuth{}, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			auth, found := credentials.Lookup(tt.repository)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.want, auth)
+		})
+	}
+}
+
+func TestGitCredentials_ForImport_RelativeSSHKey(t *testing.T) {
+	rootDir := t.TempDir()
+	projectDir := filepath.Join(rootDir, "project")
+	otherDir := filepath.Join(rootDir, "other")
+	require.NoError(t, os.MkdirAll(projectDir, 0750))
+	require.NoError(t, os.MkdirAll(otherDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, "ajisai.yml"), []byte(`workspace:
+  imports:
+    private:
+      type: git
+      include: [default]
+      repository: git@github.com:example/private.git
+      auth:
+        sshKey: keys/deploy
+`), 0600))
+
+	// Like `ajisai -c ../other/ajisai.yml`, which is run in another directory than the config.
+	t.Chdir(projectDir)
+	configPath, err := filepath.Abs(filepath.Join("..", "other", "ajisai.yml"))
+	require.NoError(t, err)
+	manager, err := config.NewManager(configPath)
+	require.NoError(t, err)
+	cfg, err := manager.Load()
+	require.NoError(t, err)
+
+	details, ok := config.GetImportDetails[config.GitImportDetails](cfg.Workspace.Imports["private"])
+	require.True(t, ok)
+
+	auth := fetcher.NewGitCredentials(cfg).ForImport(details, details.Repository)
+
+	assert.Equal(t, filepath.Join(otherDir, "keys", "deploy"), auth.SSHKey)
+}
+
+func TestGitFetcher_Fetch_Token(t *testing.T) {
+	authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte("bot:s3cret"))
+
+	tests := map[string]struct {
+		userConfigCount string
+		want            []string
+	}{
+		"without git config in the environment": {
+			want: []string{
+				"GIT_CONFIG_COUNT=1",
+				"GIT_CONFIG_KEY_0=http.extraHeader",
+				"GIT_CONFIG_VALUE_0=Authorization: " + authorization,
+			},
+		},
+		"after git config in the environment": {
+			userConfigCount: "2",
+			want: []string{
+				"GIT_CONFIG_COUNT=3",
+				"GIT_CONFIG_KEY_2=http.extraHeader",
+				"GIT_CONFIG_VALUE_2=Authorization: " + authorization,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			destDir := filepath.Join(t.TempDir(), "dest")
+			repoURL := "https://github.com/example/private.git"
+			t.Setenv("PRESETS_TOKEN", "s3cret")
+			t.Setenv("GIT_CONFIG_COUNT", tt.userConfigCount)
+
+			authRunner := utils.NewMockCommandRunner(ctrl)
+			authRunner.EXPECT().Run("git", gomock.Any()).Return(nil)
+
+			mockRunner := utils.NewMockCommandRunner(ctrl)
+			mockRunner.EXPECT().WithEnv(tt.want[0], tt.want[1], tt.want[2]).Return(authRunner)
+
+			source := gitImport(config.GitImportDetails{
+				Repository: repoURL,
+				Auth:       config.GitAuth{TokenEnv: "PRESETS_TOKEN", Username: "bot"},
+			})
+
+			require.NoError(t, fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir))
+		})
+	}
+}
+
+func TestGitFetcher_Fetch_SSHKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	keyPath := filepath.Join(t.TempDir(), "deploy's key")
+
+	authRunner := utils.NewMockCommandRunner(ctrl)
+	authRunner.EXPECT().Run("git", gomock.Any()).Return(nil)
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	mockRunner.EXPECT().WithEnv(
+		"GIT_SSH_COMMAND=ssh -i '" + filepath.Dir(keyPath) + `/deploy'\''s key' -o IdentitiesOnly=yes`,
+	).Return(authRunner)
+
+	source := gitImport(config.GitImportDetails{
+		Repository: "git@github.com:example/private.git",
+		Auth:       config.GitAuth{SSHKey: keyPath},
+	})
+
+	require.NoError(t, fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir))
+}
+
+func TestGitFetcher_Fetch_MissingToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	t.Setenv("PRESETS_TOKEN", "")
+	source := gitImport(config.GitImportDetails{
+		Repository: "https://github.com/example/private.git",
+		Auth:       config.GitAuth{TokenEnv: "PRESETS_TOKEN"},
+	})
+
+	err := fetcher.NewGitFetcherWithRunner(utils.NewMockCommandRunner(ctrl)).Fetch(source, t.TempDir())
+
+	var missingErr *fetcher.MissingTokenError
+	require.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, "PRESETS_TOKEN", missingErr.TokenEnv)
+}
+
+func TestGit_Fetch_PrivateRepository(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commit := commitFile(t, repoDir, "rules/rule.md", "private")
+	repoURL := serveRepository(t, repoDir, "bot", "s3cret")
+	t.Setenv("PRESETS_TOKEN", "s3cret")
+
+	cfg := &config.Config{
+		Settings: &config.Settings{
+			GitAuth: map[string]config.GitAuth{
+				remoteHostOf(t, repoURL): {TokenEnv: "PRESETS_TOKEN", Username: "bot"},
+			},
+		},
+	}
+
+	for _, backend := range []config.GitBackend{config.GitBackendCLI, config.GitBackendGo} {
+		t.Run(string(backend), func(t *testing.T) {
+			destDir := filepath.Join(t.TempDir(), "dest")
+			source := gitImport(config.GitImportDetails{Repository: repoURL})
+
+			// Without credentials, the server rejects the request.
+			require.Error(t, fetcher.NewGit(backend, nil, nil).Fetch(source, filepath.Join(t.TempDir(), "dest")))
+
+			git := fetcher.NewGit(backend, fetcher.NewGitCredentials(cfg), nil)
+			require.NoError(t, git.Fetch(source, destDir))
+
+			resolved, err := git.Resolve(destDir)
+			require.NoError(t, err)
+			assert.Equal(t, commit, resolved)
+
+			refs, err := git.ListRemoteRefs(repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, commit, refs.Head)
+
+			// The token is not stored in the clone.
+			gitConfig, err := os.ReadFile(filepath.Join(destDir, ".git", "config"))
+			require.NoError(t, err)
+			assert.NotContains(t, string(gitConfig), "s3cret")
+			assert.NotContains(t, string(gitConfig), base64.StdEncoding.EncodeToString([]byte("bot:s3cret")))
+		})
+	}
+}
+
+func remoteHostOf(t *testing.T, repoURL string) string {
+	t.Helper()
+
+	parsed, err := url.Parse(repoURL)
+	require.NoError(t, err)
+	return parsed.Host
+}
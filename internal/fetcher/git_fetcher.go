@@ -3,30 +3,92 @@ package fetcher
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/sushichan044/ajisai/internal/config"
-	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/versionrange"
 	"github.com/sushichan044/ajisai/utils"
 )
 
 type GitFetcher struct {
-	cmdRunner utils.CommandRunner
+	cmdRunner   utils.CommandRunner
+	credentials *GitCredentials
+	mirrors     *Mirrors
+}
+
+type (
+	// RemoteRefs are the refs advertised by a remote repository, mapped to the commit SHA they point to.
+	RemoteRefs struct {
+		// Commit of the remote default branch.
+		Head     string
+		Branches map[string]string
+		// Annotated tags are peeled to the commit they point to.
+		Tags map[string]string
+	}
+
+	// FileChange is a file added, modified, deleted or renamed between two commits.
+	FileChange struct {
+		// Status letter reported by `git diff --name-status` (A, M, D, R, ...).
+		Status string
+		Path   string
+		// Path before the rename. Empty unless Status is R or C.
+		OldPath string
+	}
+)
+
+// Commit returns the commit the branch or tag points to, or the commit of the default branch if revision is empty.
+// It is empty if the revision is neither, e.g. a commit SHA or a version range.
+func (r *RemoteRefs) Commit(revision string) string {
+	_, commit := r.find(revision)
+	return commit
+}
+
+// find returns the advertised ref and commit the revision points to.
+// Both are empty if the revision is not a branch or tag, e.g. a commit SHA.
+func (r *RemoteRefs) find(revision string) (plumbing.ReferenceName, string) {
+	if revision == "" {
+		return plumbing.HEAD, r.Head
+	}
+
+	if commit, isBranch := r.Branches[revision]; isBranch {
+		return plumbing.NewBranchReferenceName(revision), commit
+	}
+
+	if commit, isTag := r.Tags[revision]; isTag {
+		return plumbing.NewTagReferenceName(revision), commit
+	}
+
+	return "", ""
 }
 
 // NewGitFetcher creates a new GitFetcherImpl with the default command runner.
-func NewGitFetcher() domain.PackageFetcher {
+func NewGitFetcher() *GitFetcher {
 	return &GitFetcher{cmdRunner: &utils.DefaultCommandRunner{}}
 }
 
+// NewGitFetcherWithCredentials creates a new GitFetcher that authenticates to remotes with the given credentials.
+// Credentials set on an import are used even without them.
+func NewGitFetcherWithCredentials(credentials *GitCredentials) *GitFetcher {
+	return &GitFetcher{cmdRunner: &utils.DefaultCommandRunner{}, credentials: credentials}
+}
+
 // NewGitFetcherWithRunner creates a new GitFetcherImpl with a custom command runner (for testing).
-func NewGitFetcherWithRunner(runner utils.CommandRunner) domain.PackageFetcher {
+func NewGitFetcherWithRunner(runner utils.CommandRunner) *GitFetcher {
 	return &GitFetcher{cmdRunner: runner}
 }
 
 // Fetch retrieves content from a Git repository.
 // It clones the repo if destinationDir doesn't exist, otherwise updates it.
+// An existing clone is replaced if it was cloned from another repository or subdirectory.
 // If a specific revision is provided in the source, it checks out that revision.
-// Otherwise, it pulls the latest changes from the default branch.
+// A version range revision (e.g. `^1.4`) checks out the highest matching tag.
+// Otherwise, it checks out the latest commit of the remote default branch.
 func (f *GitFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
 	gitDetails, ok := config.GetImportDetails[config.GitImportDetails](source)
 	if !ok {
@@ -46,36 +108,354 @@ func (f *GitFetcher) Fetch(source config.ImportedPackage, destinationDir string)
 		return err
 	}
 
+	// The import is fetched from its mirror, but is still identified by its own repository.
+	remote := gitDetails
+	remote.Repository = f.mirrors.Rewrite(gitDetails.Repository)
+	metadata := NewCacheMetadata(source).withMirror(remote.Repository)
+
+	git, err := f.withAuth(f.credentials.ForImport(gitDetails, remote.Repository), remote.Repository)
+	if err != nil {
+		return err
+	}
+
+	revision := gitDetails.Revision
+	if versionrange.IsConstraint(revision) {
+		if revision, err = resolveVersion(git.listRemoteRefs, remote.Repository, revision); err != nil {
+			return err
+		}
+	}
+
 	shouldPull, err := utils.IsDirExists(destAbsDir)
 	if err != nil {
 		return err
 	}
 
-	if !shouldPull {
-		cmdArgs := []string{"clone", gitDetails.Repository, destAbsDir}
-		return f.cmdRunner.Run("git", cmdArgs...)
+	if shouldPull {
+		reclone, recloneErr := needsReclone(destAbsDir, metadata)
+		if recloneErr != nil {
+			return recloneErr
+		}
+
+		if reclone {
+			// The cache was cloned from another source. Updating it in place would keep the old content.
+			if removeErr := os.RemoveAll(destAbsDir); removeErr != nil {
+				return fmt.Errorf("failed to remove outdated cache %s: %w", destAbsDir, removeErr)
+			}
+			shouldPull = false
+		}
+	}
+
+	if shouldPull {
+		err = git.update(remote, revision, destAbsDir)
+	} else {
+		err = git.clone(remote, revision, destAbsDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeCacheMetadata(destAbsDir, metadata)
+}
+
+// clone clones the repository into destAbsDir and checks out the revision.
+func (f *GitFetcher) clone(details config.GitImportDetails, revision string, destAbsDir string) error {
+	if cloneErr := f.cmdRunner.Run("git", cloneArgs(details, destAbsDir)...); cloneErr != nil {
+		return cloneErr
+	}
+
+	if sparseErr := f.setSparseCheckout(destAbsDir, details.Path); sparseErr != nil {
+		return sparseErr
+	}
+
+	if details.Depth > 0 {
+		return f.checkoutShallow(destAbsDir, revision, details.Depth)
+	}
+
+	if revision == "" {
+		return nil
+	}
+
+	return f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", revision)
+}
+
+// update discards local changes in the existing clone, fetches the remote and checks out the revision.
+func (f *GitFetcher) update(details config.GitImportDetails, revision string, destAbsDir string) error {
+	if resetErr := f.cmdRunner.RunInDir(destAbsDir, "git", "reset", "--hard"); resetErr != nil {
+		return fmt.Errorf("failed to reset %s: %w", destAbsDir, resetErr)
+	}
+
+	// Remove untracked and ignored files too, except the cache metadata.
+	cleanErr := f.cmdRunner.RunInDir(destAbsDir, "git", "clean", "-fdx", "-e", CacheMetadataFileName)
+	if cleanErr != nil {
+		return fmt.Errorf("failed to clear untracked files in %s: %w", destAbsDir, cleanErr)
+	}
+
+	if sparseErr := f.setSparseCheckout(destAbsDir, details.Path); sparseErr != nil {
+		return sparseErr
+	}
+
+	if details.Depth > 0 {
+		return f.checkoutShallow(destAbsDir, revision, details.Depth)
+	}
+
+	fetchArgs := []string{"fetch", "origin"}
+	if err := f.cmdRunner.RunInDir(destAbsDir, "git", fetchArgs...); err != nil {
+		return fmt.Errorf(
+			"failed to fetch updates for repository in %s: %w",
+			destAbsDir,
+			err,
+		)
+	}
+
+	if revision == "" {
+		// Checkout the latest commit of the default branch.
+		// `git pull` can not be used here because HEAD is detached after checking out a pinned commit.
+		return f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", "--detach", "origin/HEAD")
+	}
+
+	if f.isRemoteBranch(destAbsDir, revision) {
+		// Fetching does not move the local branch, so the commit fetched for the remote branch is checked out.
+		return f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", "--detach", "origin/"+revision)
+	}
+
+	// Checkout specific revision
+	checkoutArgs := []string{"checkout", revision}
+	return f.cmdRunner.RunInDir(destAbsDir, "git", checkoutArgs...)
+}
+
+// isRemoteBranch reports whether the revision is a branch of the remote fetched into the clone.
+func (f *GitFetcher) isRemoteBranch(destAbsDir string, revision string) bool {
+	_, err := f.cmdRunner.OutputInDir(
+		destAbsDir,
+		"git",
+		"rev-parse",
+		"--verify",
+		"--quiet",
+		"refs/remotes/origin/"+revision+"^{commit}",
+	)
+	return err == nil
+}
+
+// cloneArgs returns the `git clone` arguments for the import.
+func cloneArgs(details config.GitImportDetails, destAbsDir string) []string {
+	args := []string{"clone"}
+
+	if details.Depth > 0 {
+		// The revision is fetched and checked out by checkoutShallow.
+		args = append(args, "--depth="+strconv.Itoa(details.Depth), "--no-checkout")
+	}
+	if details.Filter != "" {
+		args = append(args, "--filter="+details.Filter)
+	}
+	if details.Path != "" {
+		// Only check out files at the repository root until the sparse checkout is set.
+		args = append(args, "--sparse")
+	}
+
+	return append(args, details.Repository, destAbsDir)
+}
+
+// checkoutShallow fetches only the history of the revision up to depth and checks it out.
+// If the remote refuses to serve the revision directly (e.g. an unadvertised commit SHA),
+// the clone is deepened to the full history instead.
+func (f *GitFetcher) checkoutShallow(destAbsDir string, revision string, depth int) error {
+	ref := revision
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// Output is used to keep the expected error of an unadvertised SHA off the terminal.
+	_, fetchErr := f.cmdRunner.OutputInDir(destAbsDir, "git", "fetch", "--depth="+strconv.Itoa(depth), "origin", ref)
+	if fetchErr == nil {
+		return f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", "--detach", "FETCH_HEAD")
+	}
+
+	if revision == "" {
+		return fmt.Errorf("failed to fetch the default branch into %s: %w", destAbsDir, fetchErr)
+	}
+
+	if deepenErr := f.deepen(destAbsDir); deepenErr != nil {
+		return fmt.Errorf("failed to fetch %s into %s: %w", revision, destAbsDir, errors.Join(fetchErr, deepenErr))
+	}
+
+	return f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", "--detach", revision)
+}
+
+// deepen fetches the full history of every branch and tag, so that any revision becomes reachable.
+func (f *GitFetcher) deepen(destAbsDir string) error {
+	shallow, err := f.cmdRunner.OutputInDir(destAbsDir, "git", "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return err
+	}
+
+	// A shallow clone only tracks the default branch, so other branches are fetched explicitly.
+	args := []string{"fetch", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*"}
+	if shallow == "true" {
+		args = slices.Insert(args, 1, "--unshallow")
+	}
+
+	return f.cmdRunner.RunInDir(destAbsDir, "git", args...)
+}
+
+func (f *GitFetcher) hasCommit(destAbsDir string, commit string) bool {
+	_, err := f.cmdRunner.OutputInDir(destAbsDir, "git", "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// setSparseCheckout limits the working tree to the package subdirectory.
+// It does nothing if the whole repository is the package.
+func (f *GitFetcher) setSparseCheckout(destAbsDir string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := f.cmdRunner.RunInDir(destAbsDir, "git", "sparse-checkout", "set", path); err != nil {
+		return fmt.Errorf("failed to set sparse checkout to %s in %s: %w", path, destAbsDir, err)
+	}
+
+	return nil
+}
+
+// Resolve returns the commit SHA checked out in destinationDir.
+func (f *GitFetcher) Resolve(destinationDir string) (string, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := f.cmdRunner.OutputInDir(destAbsDir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD of %s: %w", destAbsDir, err)
+	}
+
+	return commit, nil
+}
+
+// ListRemoteRefs returns the branches and tags of the remote repository without fetching it.
+func (f *GitFetcher) ListRemoteRefs(repository string) (*RemoteRefs, error) {
+	remoteURL := f.mirrors.Rewrite(repository)
+	auth, _ := f.credentials.lookup(repository, remoteURL)
+
+	git, err := f.withAuth(auth, remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return git.listRemoteRefs(remoteURL)
+}
+
+func (f *GitFetcher) listRemoteRefs(repository string) (*RemoteRefs, error) {
+	out, err := f.cmdRunner.OutputInDir(".", "git", "ls-remote", repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs of %s: %w", repository, err)
+	}
+
+	refs := &RemoteRefs{Branches: make(map[string]string), Tags: make(map[string]string)}
+	peeled := make(map[string]string)
+
+	for line := range strings.Lines(out) {
+		commit, ref, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+
+		switch {
+		case ref == "HEAD":
+			refs.Head = commit
+		case strings.HasPrefix(ref, "refs/heads/"):
+			refs.Branches[strings.TrimPrefix(ref, "refs/heads/")] = commit
+		case strings.HasPrefix(ref, "refs/tags/") && strings.HasSuffix(ref, "^{}"):
+			peeled[strings.TrimSuffix(strings.TrimPrefix(ref, "refs/tags/"), "^{}")] = commit
+		case strings.HasPrefix(ref, "refs/tags/"):
+			refs.Tags[strings.TrimPrefix(ref, "refs/tags/")] = commit
+		}
+	}
+
+	// Annotated tags point to a tag object. Use the commit it was peeled to instead.
+	maps.Copy(refs.Tags, peeled)
+
+	return refs, nil
+}
+
+// ResolveVersion returns the highest tag of the remote repository that satisfies the version range constraint.
+func (f *GitFetcher) ResolveVersion(repository string, constraint string) (string, error) {
+	return resolveVersion(f.ListRemoteRefs, repository, constraint)
+}
+
+// ChangedFiles returns the files that differ between two commits of the repository in destinationDir.
+// If destinationDir is a subdirectory of the repository, only files inside it are returned,
+// with paths relative to it.
+func (f *GitFetcher) ChangedFiles(destinationDir string, from string, to string) ([]FileChange, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, commit := range []string{from, to} {
+		if f.hasCommit(destAbsDir, commit) {
+			continue
+		}
+
+		// The commit is outside the history of a shallow clone.
+		git, authErr := f.withOriginAuth(destAbsDir)
+		if authErr != nil {
+			return nil, authErr
+		}
+		if deepenErr := git.deepen(destAbsDir); deepenErr != nil {
+			return nil, fmt.Errorf("failed to fetch %s into %s: %w", commit, destAbsDir, deepenErr)
+		}
+		break
 	}
 
-	// Reset hard to the latest commit
-	if cleanErr := f.cmdRunner.RunInDir(destAbsDir, "git", "checkout", "."); cleanErr != nil {
-		return fmt.Errorf("failed to clear dirty files in %s: %w", destAbsDir, cleanErr)
+	out, err := f.cmdRunner.OutputInDir(destAbsDir, "git", "diff", "--name-status", "--relative", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s in %s: %w", from, to, destAbsDir, err)
 	}
 
-	if gitDetails.Revision != "" {
-		// Checkout specific revision
-		fetchArgs := []string{"fetch", "origin"}
-		if err = f.cmdRunner.RunInDir(destAbsDir, "git", fetchArgs...); err != nil {
-			return fmt.Errorf(
-				"failed to fetch updates for repository in %s: %w",
-				destAbsDir,
-				err,
-			)
+	changes := make([]FileChange, 0)
+	for line := range strings.Lines(out) {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		// Renames and copies are reported with a similarity score (e.g. R100) and both paths.
+		change := FileChange{Status: fields[0][:1], Path: fields[len(fields)-1]}
+		if len(fields) == 3 {
+			change.OldPath = fields[1]
 		}
+		changes = append(changes, change)
+	}
 
-		checkoutArgs := []string{"checkout", gitDetails.Revision}
-		return f.cmdRunner.RunInDir(destAbsDir, "git", checkoutArgs...)
+	return changes, nil
+}
+
+// withAuth returns a copy of the fetcher that passes the credentials to every git command.
+func (f *GitFetcher) withAuth(auth config.GitAuth, repository string) (*GitFetcher, error) {
+	if auth == (config.GitAuth{}) {
+		return f, nil
+	}
+
+	env, err := gitCommandEnv(auth, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitFetcher{cmdRunner: f.cmdRunner.WithEnv(env...), credentials: f.credentials, mirrors: f.mirrors}, nil
+}
+
+// withOriginAuth returns a copy of the fetcher that passes the credentials for the origin of the clone
+// in destAbsDir to every git command.
+func (f *GitFetcher) withOriginAuth(destAbsDir string) (*GitFetcher, error) {
+	if f.credentials == nil {
+		return f, nil
+	}
+
+	origin, err := f.cmdRunner.OutputInDir(destAbsDir, "git", "remote", "get-url", "origin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the origin of %s: %w", destAbsDir, err)
 	}
 
-	// Pull latest changes from default branch
-	return f.cmdRunner.RunInDir(destAbsDir, "git", "pull")
+	auth, _ := f.credentials.Lookup(origin)
+	return f.withAuth(auth, origin)
 }
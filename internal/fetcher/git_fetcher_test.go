@@ -42,6 +42,195 @@ func TestGitFetcher_Fetch_InitialClone(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGitFetcher_Fetch_InitialClone_WithRevision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL, Revision: "v1.0.0"},
+	}
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	gomock.InOrder(
+		mockRunner.EXPECT().Run("git", "clone", repoURL, absoluteDestDir).Return(nil),
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", "v1.0.0").Return(nil),
+	)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+
+	require.NoError(t, err)
+}
+
+func TestGitFetcher_Fetch_InitialClone_WithPath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL, Path: "packages/go"},
+	}
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	gomock.InOrder(
+		mockRunner.EXPECT().Run("git", "clone", "--sparse", repoURL, absoluteDestDir).Return(nil),
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "sparse-checkout", "set", "packages/go").Return(nil),
+	)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+
+	require.NoError(t, err)
+}
+
+func TestGitFetcher_Fetch_Shallow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	source := config.ImportedPackage{
+		Type: "git",
+		Details: config.GitImportDetails{
+			Repository: repoURL,
+			Revision:   "v1.0.0",
+			Depth:      1,
+			Filter:     "blob:none",
+		},
+	}
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	gomock.InOrder(
+		mockRunner.EXPECT().
+			Run("git", "clone", "--depth=1", "--no-checkout", "--filter=blob:none", repoURL, absoluteDestDir).
+			Return(nil),
+		mockRunner.EXPECT().
+			OutputInDir(absoluteDestDir, "git", "fetch", "--depth=1", "origin", "v1.0.0").
+			Return("", nil),
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", "--detach", "FETCH_HEAD").Return(nil),
+	)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+
+	require.NoError(t, err)
+}
+
+func TestGitFetcher_Fetch_Shallow_Deepen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := t.TempDir()
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL, Revision: commit, Depth: 1},
+	}
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	gomock.InOrder(
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil),
+		mockRunner.EXPECT().
+			RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+			Return(nil),
+		mockRunner.EXPECT().
+			OutputInDir(absoluteDestDir, "git", "fetch", "--depth=1", "origin", commit).
+			Return("", errors.New("server does not allow request for unadvertised object")),
+		mockRunner.EXPECT().
+			OutputInDir(absoluteDestDir, "git", "rev-parse", "--is-shallow-repository").
+			Return("true", nil),
+		mockRunner.EXPECT().
+			RunInDir(
+				absoluteDestDir,
+				"git", "fetch", "--unshallow", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*",
+			).
+			Return(nil),
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", "--detach", commit).Return(nil),
+	)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+
+	require.NoError(t, err)
+}
+
+func TestGitFetcher_Fetch_VersionRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL, Revision: "^1.4"},
+	}
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	gomock.InOrder(
+		mockRunner.EXPECT().
+			OutputInDir(".", "git", "ls-remote", repoURL).
+			Return(
+				"aaa\trefs/tags/v1.3.0\nbbb\trefs/tags/v1.4.2\nccc\trefs/tags/v1.5.0-rc.1\nddd\trefs/tags/v2.0.0",
+				nil,
+			),
+		mockRunner.EXPECT().Run("git", "clone", repoURL, absoluteDestDir).Return(nil),
+		mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", "v1.4.2").Return(nil),
+	)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+
+	require.NoError(t, err)
+}
+
+func TestGitFetcher_ResolveVersion_NoMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoURL := "https://github.com/example/repo.git"
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	mockRunner.EXPECT().
+		OutputInDir(".", "git", "ls-remote", repoURL).
+		Return("aaa\trefs/tags/v1.3.0", nil)
+
+	_, err := fetcher.NewGitFetcherWithRunner(mockRunner).ResolveVersion(repoURL, "^2")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no tag of "+repoURL+" satisfies ^2")
+}
+
+func TestGitFetcher_Resolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := t.TempDir()
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	mockRunner.EXPECT().OutputInDir(destDir, "git", "rev-parse", "HEAD").Return("0123abcd", nil)
+
+	commit, err := fetcher.NewGitFetcherWithRunner(mockRunner).Resolve(destDir)
+
+	require.NoError(t, err)
+	assert.Equal(t, "0123abcd", commit)
+}
+
 func TestGitFetcher_Fetch_InitialClone_Failure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -92,10 +281,18 @@ func TestGitFetcher_Fetch_CheckoutRevision(t *testing.T) {
 
 	fetcherInstance := fetcher.NewGitFetcherWithRunner(mockRunner)
 
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", ".").Return(nil)
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil)
+	mockRunner.EXPECT().
+		RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+		Return(nil)
 
 	expectedFetchArgs := []string{"fetch", "origin"}
 	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedFetchArgs).Return(nil)
+	remoteBranch := "refs/remotes/origin/" + revision + "^{commit}"
+	mockRunner.EXPECT().
+		OutputInDir(absoluteDestDir, "git", "rev-parse", "--verify", "--quiet", remoteBranch).
+		Return("", errors.New("not a branch"))
 
 	expectedCheckoutArgs := []string{"checkout", revision}
 	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedCheckoutArgs).Return(nil)
@@ -128,7 +325,11 @@ func TestGitFetcher_Fetch_CheckoutRevision_FetchFailure(t *testing.T) {
 
 	fetcherInstance := fetcher.NewGitFetcherWithRunner(mockRunner)
 
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", ".").Return(nil)
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil)
+	mockRunner.EXPECT().
+		RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+		Return(nil)
 
 	fetchErr := errors.New("git fetch failed")
 	expectedFetchArgs := []string{"fetch", "origin"}
@@ -163,10 +364,18 @@ func TestGitFetcher_Fetch_CheckoutRevision_CheckoutFailure(t *testing.T) {
 
 	fetcherInstance := fetcher.NewGitFetcherWithRunner(mockRunner)
 
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", ".").Return(nil)
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil)
+	mockRunner.EXPECT().
+		RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+		Return(nil)
 
 	expectedFetchArgs := []string{"fetch", "origin"}
 	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedFetchArgs).Return(nil)
+	remoteBranch := "refs/remotes/origin/" + revision + "^{commit}"
+	mockRunner.EXPECT().
+		OutputInDir(absoluteDestDir, "git", "rev-parse", "--verify", "--quiet", remoteBranch).
+		Return("", errors.New("not a branch"))
 
 	checkoutErr := errors.New("git checkout failed")
 	expectedCheckoutArgs := []string{"checkout", revision}
@@ -198,10 +407,15 @@ func TestGitFetcher_Fetch_PullLatest(t *testing.T) {
 
 	fetcherInstance := fetcher.NewGitFetcherWithRunner(mockRunner)
 
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", ".").Return(nil)
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil)
+	mockRunner.EXPECT().
+		RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+		Return(nil)
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "fetch", "origin").Return(nil)
 
-	expectedPullArgs := []string{"pull"}
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedPullArgs).Return(nil)
+	expectedCheckoutArgs := []string{"checkout", "--detach", "origin/HEAD"}
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedCheckoutArgs).Return(nil)
 
 	err = fetcherInstance.Fetch(source, destDir)
 
@@ -229,15 +443,83 @@ func TestGitFetcher_Fetch_PullLatest_Failure(t *testing.T) {
 
 	fetcherInstance := fetcher.NewGitFetcherWithRunner(mockRunner)
 
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "checkout", ".").Return(nil)
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, source))
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "reset", "--hard").Return(nil)
+	mockRunner.EXPECT().
+		RunInDir(absoluteDestDir, "git", "clean", "-fdx", "-e", fetcher.CacheMetadataFileName).
+		Return(nil)
 
-	pullErr := errors.New("git pull failed")
-	expectedPullArgs := []string{"pull"}
-	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedPullArgs).Return(pullErr)
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", "fetch", "origin").Return(nil)
+
+	checkoutErr := errors.New("git checkout failed")
+	expectedCheckoutArgs := []string{"checkout", "--detach", "origin/HEAD"}
+	mockRunner.EXPECT().RunInDir(absoluteDestDir, "git", expectedCheckoutArgs).Return(checkoutErr)
 
 	err = fetcherInstance.Fetch(source, destDir)
 
-	require.ErrorIs(t, err, pullErr)
+	require.ErrorIs(t, err, checkoutErr)
+}
+
+func TestGitFetcher_Fetch_RepositoryChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	testDir := t.TempDir()
+	destDir := filepath.Join(testDir, "existing-repo-moved")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	oldSource := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: "https://github.com/example/old.git"},
+	}
+	require.NoError(t, fetcher.WriteCacheMetadata(destDir, oldSource))
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "stale.md"), []byte("stale"), 0600))
+
+	repoURL := "https://github.com/example/new.git"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL},
+	}
+
+	mockRunner.EXPECT().Run("git", []string{"clone", repoURL, absoluteDestDir}).Return(nil)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(destDir, "stale.md"))
+
+	metadata, err := fetcher.ReadCacheMetadata(destDir)
+	require.NoError(t, err)
+	require.NotNil(t, metadata)
+	assert.Equal(t, repoURL, metadata.Repository)
+}
+
+func TestGitFetcher_Fetch_NoMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+
+	testDir := t.TempDir()
+	destDir := filepath.Join(testDir, "existing-repo-legacy")
+	require.NoError(t, os.MkdirAll(destDir, 0755))
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	repoURL := "https://github.com/example/repo.git"
+	source := config.ImportedPackage{
+		Type:    "git",
+		Details: config.GitImportDetails{Repository: repoURL},
+	}
+
+	// A cache entry without metadata has an unknown origin, so it is cloned again.
+	mockRunner.EXPECT().Run("git", []string{"clone", repoURL, absoluteDestDir}).Return(nil)
+
+	err = fetcher.NewGitFetcherWithRunner(mockRunner).Fetch(source, destDir)
+	require.NoError(t, err)
 }
 
 func TestGitFetcher_InvalidSourceType(t *testing.T) {
@@ -285,3 +567,58 @@ func TestGitFetcher_EmptyRepository(t *testing.T) {
 	require.Error(t, err)
 	assert.EqualError(t, err, "git repository URL cannot be empty")
 }
+
+func TestGitFetcher_ListRemoteRefs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoURL := "https://github.com/example/repo.git"
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	mockRunner.EXPECT().
+		OutputInDir(".", "git", "ls-remote", repoURL).
+		Return(
+			"aaa\tHEAD\n"+
+				"aaa\trefs/heads/main\n"+
+				"bbb\trefs/heads/dev\n"+
+				"ccc\trefs/tags/v1.0.0\n"+
+				"ddd\trefs/tags/v1.1.0\n"+
+				"eee\trefs/tags/v1.1.0^{}\n"+
+				"fff\trefs/pull/1/head",
+			nil,
+		)
+
+	refs, err := fetcher.NewGitFetcherWithRunner(mockRunner).ListRemoteRefs(repoURL)
+
+	require.NoError(t, err)
+	assert.Equal(t, &fetcher.RemoteRefs{
+		Head:     "aaa",
+		Branches: map[string]string{"main": "aaa", "dev": "bbb"},
+		Tags:     map[string]string{"v1.0.0": "ccc", "v1.1.0": "eee"},
+	}, refs)
+}
+
+func TestGitFetcher_ChangedFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	absoluteDestDir, err := filepath.Abs(destDir)
+	require.NoError(t, err)
+
+	mockRunner := utils.NewMockCommandRunner(ctrl)
+	mockRunner.EXPECT().OutputInDir(absoluteDestDir, "git", "cat-file", "-e", "aaa^{commit}").Return("", nil)
+	mockRunner.EXPECT().OutputInDir(absoluteDestDir, "git", "cat-file", "-e", "bbb^{commit}").Return("", nil)
+	mockRunner.EXPECT().
+		OutputInDir(absoluteDestDir, "git", "diff", "--name-status", "--relative", "aaa", "bbb").
+		Return("M\trules/go.md\nA\tprompts/new.md\nD\trules/old.md\nR087\trules/a.md\trules/b.md", nil)
+
+	changes, err := fetcher.NewGitFetcherWithRunner(mockRunner).ChangedFiles(destDir, "aaa", "bbb")
+
+	require.NoError(t, err)
+	assert.Equal(t, []fetcher.FileChange{
+		{Status: "M", Path: "rules/go.md"},
+		{Status: "A", Path: "prompts/new.md"},
+		{Status: "D", Path: "rules/old.md"},
+		{Status: "R", Path: "rules/b.md", OldPath: "rules/a.md"},
+	}, changes)
+}
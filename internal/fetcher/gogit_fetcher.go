@@ -0,0 +1,503 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/versionrange"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	originRemote = "origin"
+
+	// allBranchesRefSpec fetches every branch of the remote into remote-tracking branches.
+	allBranchesRefSpec gitconfig.RefSpec = "+refs/heads/*:refs/remotes/origin/*"
+)
+
+// GoGitFetcher fetches git imports with a built-in git implementation, so that the `git` command is not needed.
+// It clones the same repositories into the same layout as GitFetcher, so both can use the same cache.
+// Partial clone filters are not supported and ignored.
+type GoGitFetcher struct {
+	credentials *GitCredentials
+	mirrors     *Mirrors
+}
+
+// NewGoGitFetcher creates a new GoGitFetcher.
+func NewGoGitFetcher() *GoGitFetcher {
+	return &GoGitFetcher{}
+}
+
+// NewGoGitFetcherWithCredentials creates a new GoGitFetcher that authenticates to remotes with the given credentials.
+// Credentials set on an import are used even without them.
+func NewGoGitFetcherWithCredentials(credentials *GitCredentials) *GoGitFetcher {
+	return &GoGitFetcher{credentials: credentials}
+}
+
+// Fetch retrieves content from a Git repository like GitFetcher does.
+// It clones the repo if destinationDir doesn't exist, otherwise updates it.
+// An existing clone is replaced if it was cloned from another repository or subdirectory.
+// The revision may be a branch, a tag, a commit SHA or a version range. If it is empty,
+// the latest commit of the remote default branch is checked out.
+func (f *GoGitFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
+	gitDetails, ok := config.GetImportDetails[config.GitImportDetails](source)
+	if !ok {
+		return &InvalidSourceTypeError{
+			expectedType: config.ImportTypeGit,
+			actualType:   source.Type,
+			err:          fmt.Errorf("cannot fetch from source type: %s", source.Type),
+		}
+	}
+
+	if gitDetails.Repository == "" {
+		return errors.New("git repository URL cannot be empty")
+	}
+
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return err
+	}
+
+	// The import is fetched from its mirror, but is still identified by its own repository.
+	remote := gitDetails
+	remote.Repository = f.mirrors.Rewrite(gitDetails.Repository)
+	metadata := NewCacheMetadata(source).withMirror(remote.Repository)
+
+	auth, err := goGitAuth(f.credentials.ForImport(gitDetails, remote.Repository), remote.Repository)
+	if err != nil {
+		return err
+	}
+
+	refs, headBranch, err := listRemote(remote.Repository, auth)
+	if err != nil {
+		return err
+	}
+
+	revision := gitDetails.Revision
+	if versionrange.IsConstraint(revision) {
+		if revision, err = resolveVersion(listed(refs), remote.Repository, revision); err != nil {
+			return err
+		}
+	}
+
+	exists, err := utils.IsDirExists(destAbsDir)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		reclone, recloneErr := needsReclone(destAbsDir, metadata)
+		if recloneErr != nil {
+			return recloneErr
+		}
+
+		if reclone {
+			// The cache was cloned from another source. Updating it in place would keep the old content.
+			if removeErr := os.RemoveAll(destAbsDir); removeErr != nil {
+				return fmt.Errorf("failed to remove outdated cache %s: %w", destAbsDir, removeErr)
+			}
+			exists = false
+		}
+	}
+
+	var repo *git.Repository
+	if exists {
+		repo, err = git.PlainOpen(destAbsDir)
+	} else {
+		repo, err = initRepository(destAbsDir, remote.Repository)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open repository in %s: %w", destAbsDir, err)
+	}
+
+	if err = f.checkout(repo, remote, auth, refs, revision, destAbsDir); err != nil {
+		return err
+	}
+
+	if err = setOriginHead(repo, headBranch); err != nil {
+		return fmt.Errorf("failed to set the default branch of %s: %w", destAbsDir, err)
+	}
+
+	return writeCacheMetadata(destAbsDir, metadata)
+}
+
+// checkout fetches the revision into repo and checks it out, discarding local changes and untracked files.
+func (f *GoGitFetcher) checkout(
+	repo *git.Repository,
+	details config.GitImportDetails,
+	auth transport.AuthMethod,
+	refs *RemoteRefs,
+	revision string,
+	destAbsDir string,
+) error {
+	ref, commit := refs.find(revision)
+	if ref == "" && plumbing.IsHash(revision) {
+		// A full commit SHA can be fetched directly if the remote allows it.
+		ref, commit = plumbing.ReferenceName(revision), revision
+	}
+
+	var fetchErr error
+	if details.Depth == 0 || ref != "" {
+		fetchErr = fetchRevision(repo, ref, details.Depth, auth)
+	}
+
+	if details.Depth > 0 && (ref == "" || fetchErr != nil) {
+		// The revision can not be fetched shallowly, e.g. an abbreviated or unadvertised commit SHA.
+		// Clone the full history again instead, since a shallow clone can not be deepened.
+		if removeErr := os.RemoveAll(destAbsDir); removeErr != nil {
+			return fmt.Errorf("failed to remove shallow clone %s: %w", destAbsDir, removeErr)
+		}
+
+		var initErr error
+		if repo, initErr = initRepository(destAbsDir, details.Repository); initErr != nil {
+			return initErr
+		}
+		fetchErr = fetchRevision(repo, "", 0, auth)
+	}
+	if fetchErr != nil {
+		return fmt.Errorf("failed to fetch %s into %s: %w", details.Repository, destAbsDir, fetchErr)
+	}
+
+	hash := plumbing.NewHash(commit)
+	if commit == "" {
+		resolved, resolveErr := repo.ResolveRevision(plumbing.Revision(revision))
+		if resolveErr != nil {
+			return fmt.Errorf("failed to resolve revision %s in %s: %w", revision, destAbsDir, resolveErr)
+		}
+		hash = *resolved
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	opts := &git.CheckoutOptions{Hash: hash, Force: true}
+	if details.Path != "" {
+		opts.SparseCheckoutDirectories = []string{details.Path}
+	}
+	if checkoutErr := worktree.Checkout(opts); checkoutErr != nil {
+		return fmt.Errorf("failed to check out %s in %s: %w", hash, destAbsDir, checkoutErr)
+	}
+
+	// Unlike `git clean -x`, files ignored by .gitignore are kept.
+	if cleanErr := worktree.Clean(&git.CleanOptions{Dir: true}); cleanErr != nil {
+		return fmt.Errorf("failed to clear untracked files in %s: %w", destAbsDir, cleanErr)
+	}
+
+	return nil
+}
+
+// Resolve returns the commit SHA checked out in destinationDir.
+func (f *GoGitFetcher) Resolve(destinationDir string) (string, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return "", err
+	}
+
+	repo, err := git.PlainOpen(destAbsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD of %s: %w", destAbsDir, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD of %s: %w", destAbsDir, err)
+	}
+
+	return head.Hash().String(), nil
+}
+
+// ListRemoteRefs returns the branches and tags of the remote repository without fetching it.
+func (f *GoGitFetcher) ListRemoteRefs(repository string) (*RemoteRefs, error) {
+	remoteURL := f.mirrors.Rewrite(repository)
+	auth, err := f.authFor(repository, remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, _, err := listRemote(remoteURL, auth)
+	return refs, err
+}
+
+// ResolveVersion returns the highest tag of the remote repository that satisfies the version range constraint.
+func (f *GoGitFetcher) ResolveVersion(repository string, constraint string) (string, error) {
+	return resolveVersion(f.ListRemoteRefs, repository, constraint)
+}
+
+// ChangedFiles returns the files that differ between two commits of the repository in destinationDir.
+// If destinationDir is a subdirectory of the repository, only files inside it are returned,
+// with paths relative to it.
+func (f *GoGitFetcher) ChangedFiles(destinationDir string, from string, to string) ([]FileChange, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpenWithOptions(destAbsDir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository in %s: %w", destAbsDir, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := filepath.Rel(worktree.Filesystem.Root(), destAbsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTree, toTree, err := commitTrees(repo, from, to)
+	if errors.Is(err, plumbing.ErrObjectNotFound) || errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// The commit is outside the history of a shallow clone. Diff a full clone in memory instead.
+		full, cloneErr := f.cloneInMemory(repo)
+		if cloneErr != nil {
+			return nil, fmt.Errorf("failed to fetch history of %s: %w", destAbsDir, cloneErr)
+		}
+		fromTree, toTree, err = commitTrees(full, from, to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s in %s: %w", from, to, destAbsDir, err)
+	}
+
+	diff, err := object.DiffTreeWithOptions(context.Background(), fromTree, toTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s in %s: %w", from, to, destAbsDir, err)
+	}
+
+	changes := make([]FileChange, 0, len(diff))
+	for _, change := range diff {
+		if fileChange, inside := relativeChange(filepath.ToSlash(prefix), change); inside {
+			changes = append(changes, fileChange)
+		}
+	}
+
+	// Sort like `git diff` does.
+	slices.SortFunc(changes, func(a, b FileChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return changes, nil
+}
+
+// listed returns a function that lists the given refs, to resolve a version range without listing them again.
+func listed(refs *RemoteRefs) func(string) (*RemoteRefs, error) {
+	return func(string) (*RemoteRefs, error) {
+		return refs, nil
+	}
+}
+
+// listRemote returns the refs of the remote repository and the branch its HEAD points to.
+// The branch is empty if the remote does not advertise it.
+func listRemote(repository string, auth transport.AuthMethod) (*RemoteRefs, plumbing.ReferenceName, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: originRemote,
+		URLs: []string{repository},
+	})
+
+	advertised, err := remote.List(&git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list refs of %s: %w", repository, err)
+	}
+
+	refs := &RemoteRefs{Branches: make(map[string]string), Tags: make(map[string]string)}
+	peeled := make(map[string]string)
+	var headTarget plumbing.ReferenceName
+
+	for _, ref := range advertised {
+		name := ref.Name()
+
+		switch {
+		case name == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference:
+			headTarget = ref.Target()
+		case name == plumbing.HEAD:
+			refs.Head = ref.Hash().String()
+		case name.IsBranch():
+			refs.Branches[name.Short()] = ref.Hash().String()
+		case name.IsTag() && strings.HasSuffix(name.String(), "^{}"):
+			peeled[strings.TrimSuffix(name.Short(), "^{}")] = ref.Hash().String()
+		case name.IsTag():
+			refs.Tags[name.Short()] = ref.Hash().String()
+		}
+	}
+
+	if refs.Head == "" && headTarget.IsBranch() {
+		refs.Head = refs.Branches[headTarget.Short()]
+	}
+
+	// Annotated tags point to a tag object. Use the commit it was peeled to instead.
+	maps.Copy(refs.Tags, peeled)
+
+	return refs, headTarget, nil
+}
+
+// setOriginHead points origin/HEAD to the remote-tracking branch of the remote default branch, like `git clone` does,
+// so that the clone can be updated by GitFetcher as well.
+func setOriginHead(repo *git.Repository, headBranch plumbing.ReferenceName) error {
+	if !headBranch.IsBranch() {
+		return nil
+	}
+
+	tracking := plumbing.NewRemoteReferenceName(originRemote, headBranch.Short())
+	_, err := repo.Reference(tracking, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// Shallow fetches of another ref do not fetch the default branch.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return repo.Storer.SetReference(
+		plumbing.NewSymbolicReference(plumbing.NewRemoteHEADReferenceName(originRemote), tracking),
+	)
+}
+
+// initRepository creates an empty repository in dir with the origin remote set to repository.
+func initRepository(dir string, repository string) (*git.Repository, error) {
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize repository in %s: %w", dir, err)
+	}
+
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: originRemote, URLs: []string{repository}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add remote %s in %s: %w", repository, dir, err)
+	}
+
+	return repo, nil
+}
+
+// fetchRevision fetches ref up to depth commits. A depth of zero fetches the full history of every branch and tag.
+// ref is HEAD, a branch, a tag or a full commit SHA.
+func fetchRevision(repo *git.Repository, ref plumbing.ReferenceName, depth int, auth transport.AuthMethod) error {
+	opts := &git.FetchOptions{RemoteName: originRemote, Auth: auth, Depth: depth, Force: true, Tags: git.NoTags}
+
+	switch {
+	case depth == 0:
+		opts.RefSpecs = []gitconfig.RefSpec{allBranchesRefSpec}
+		opts.Tags = git.AllTags
+	case ref == plumbing.HEAD:
+		opts.RefSpecs = []gitconfig.RefSpec{"+HEAD:refs/remotes/origin/HEAD"}
+	case ref.IsBranch():
+		opts.RefSpecs = []gitconfig.RefSpec{
+			gitconfig.RefSpec(fmt.Sprintf("+%s:refs/remotes/%s/%s", ref, originRemote, ref.Short())),
+		}
+	case ref.IsTag():
+		opts.RefSpecs = []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))}
+	default:
+		opts.RefSpecs = []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:refs/ajisai/checkout", ref))}
+	}
+
+	err := repo.Fetch(opts)
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+
+	return err
+}
+
+// commitTrees returns the trees of the two commits.
+func commitTrees(repo *git.Repository, from string, to string) (*object.Tree, *object.Tree, error) {
+	trees := make([]*object.Tree, 0, 2)
+
+	for _, revision := range []string{from, to} {
+		hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		commit, err := repo.CommitObject(*hash)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, nil, err
+		}
+		trees = append(trees, tree)
+	}
+
+	return trees[0], trees[1], nil
+}
+
+// cloneInMemory clones the full history of the origin remote of repo into memory.
+func (f *GoGitFetcher) cloneInMemory(repo *git.Repository) (*git.Repository, error) {
+	remote, err := repo.Remote(originRemote)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := remote.Config().URLs[0]
+	auth, err := f.authFor(origin, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	return git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:        origin,
+		Auth:       auth,
+		NoCheckout: true,
+		Tags:       git.AllTags,
+	})
+}
+
+// authFor returns the credentials found for the repository fetched from remoteURL.
+func (f *GoGitFetcher) authFor(repository string, remoteURL string) (transport.AuthMethod, error) {
+	auth, found := f.credentials.lookup(repository, remoteURL)
+	if !found {
+		return nil, nil //nolint:nilnil // No credentials are configured for the repository.
+	}
+
+	return goGitAuth(auth, remoteURL)
+}
+
+// relativeChange converts a tree change to a FileChange with paths relative to prefix, like `git diff --relative`.
+// It reports false if the change is outside prefix.
+func relativeChange(prefix string, change *object.Change) (FileChange, bool) {
+	fromPath, fromInside := relativePath(prefix, change.From.Name)
+	toPath, toInside := relativePath(prefix, change.To.Name)
+
+	switch {
+	case fromInside && toInside && fromPath != toPath:
+		return FileChange{Status: "R", Path: toPath, OldPath: fromPath}, true
+	case fromInside && toInside:
+		return FileChange{Status: "M", Path: toPath}, true
+	case toInside:
+		return FileChange{Status: "A", Path: toPath}, true
+	case fromInside:
+		return FileChange{Status: "D", Path: fromPath}, true
+	}
+
+	return FileChange{}, false
+}
+
+// relativePath returns name relative to prefix, or false if name is empty or outside prefix.
+func relativePath(prefix string, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+
+	if prefix == "." {
+		return name, true
+	}
+
+	return strings.CutPrefix(name, prefix+"/")
+}
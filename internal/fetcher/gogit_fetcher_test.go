@@ -0,0 +1,282 @@
+package fetcher_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+)
+
+// newRemoteRepository creates a repository to clone from.
+// Local remotes are served by `git-upload-pack`, so the tests are skipped without git.
+func newRemoteRepository(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, os.MkdirAll(repoDir, 0750))
+	runGit(t, repoDir, "init", "--initial-branch=main")
+	// Allow shallow fetches of unadvertised commits, like most hosting services do.
+	runGit(t, repoDir, "config", "uploadpack.allowReachableSHA1InWant", "true")
+
+	return repoDir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v failed: %s", args, out)
+
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes the file in the repository and commits it, returning the commit SHA.
+func commitFile(t *testing.T, repoDir string, path string, body string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoDir, path)), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, path), []byte(body), 0600))
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", path+": "+body)
+
+	return runGit(t, repoDir, "rev-parse", "HEAD")
+}
+
+func gitImport(details config.GitImportDetails) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeGit,
+		Include: []string{"default"},
+		Details: details,
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	body, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestGoGitFetcher_Fetch_DefaultBranch(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	firstCommit := commitFile(t, repoDir, "rules/rule.md", "first")
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	source := gitImport(config.GitImportDetails{Repository: repoDir})
+	git := fetcher.NewGoGitFetcher()
+
+	require.NoError(t, git.Fetch(source, destDir))
+	assert.Equal(t, "first", readFile(t, filepath.Join(destDir, "rules", "rule.md")))
+	assert.FileExists(t, filepath.Join(destDir, fetcher.CacheMetadataFileName))
+
+	commit, err := git.Resolve(destDir)
+	require.NoError(t, err)
+	assert.Equal(t, firstCommit, commit)
+
+	// Local changes are discarded when the remote moved on.
+	secondCommit := commitFile(t, repoDir, "rules/rule.md", "second")
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "rules", "rule.md"), []byte("edited"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "rules", "untracked.md"), []byte("untracked"), 0600))
+
+	require.NoError(t, git.Fetch(source, destDir))
+	assert.Equal(t, "second", readFile(t, filepath.Join(destDir, "rules", "rule.md")))
+	assert.NoFileExists(t, filepath.Join(destDir, "rules", "untracked.md"))
+
+	commit, err = git.Resolve(destDir)
+	require.NoError(t, err)
+	assert.Equal(t, secondCommit, commit)
+}
+
+func TestGoGitFetcher_Fetch_UpdatedByGitFetcher(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commitFile(t, repoDir, "rules/rule.md", "first")
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	source := gitImport(config.GitImportDetails{Repository: repoDir})
+	require.NoError(t, fetcher.NewGoGitFetcher().Fetch(source, destDir))
+
+	// The cache can be updated after switching the backend.
+	secondCommit := commitFile(t, repoDir, "rules/rule.md", "second")
+	require.NoError(t, fetcher.NewGitFetcher().Fetch(source, destDir))
+
+	commit, err := fetcher.NewGitFetcher().Resolve(destDir)
+	require.NoError(t, err)
+	assert.Equal(t, secondCommit, commit)
+}
+
+func TestGoGitFetcher_Fetch_Revision(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	firstCommit := commitFile(t, repoDir, "rules/rule.md", "first")
+	runGit(t, repoDir, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+	secondCommit := commitFile(t, repoDir, "rules/rule.md", "second")
+	runGit(t, repoDir, "branch", "stable", firstCommit)
+	commitFile(t, repoDir, "rules/rule.md", "third")
+
+	tests := map[string]struct {
+		revision string
+		depth    int
+		want     string
+	}{
+		"branch":                  {revision: "stable", want: firstCommit},
+		"annotated tag":           {revision: "v1.0.0", want: firstCommit},
+		"commit":                  {revision: secondCommit, want: secondCommit},
+		"abbreviated commit":      {revision: secondCommit[:7], want: secondCommit},
+		"shallow branch":          {revision: "stable", depth: 1, want: firstCommit},
+		"shallow tag":             {revision: "v1.0.0", depth: 1, want: firstCommit},
+		"shallow commit":          {revision: secondCommit, depth: 1, want: secondCommit},
+		"shallow abbreviated SHA": {revision: secondCommit[:7], depth: 1, want: secondCommit},
+		"version range":           {revision: "^1.0", want: firstCommit},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			destDir := filepath.Join(t.TempDir(), "dest")
+			source := gitImport(config.GitImportDetails{Repository: repoDir, Revision: tt.revision, Depth: tt.depth})
+			git := fetcher.NewGoGitFetcher()
+
+			require.NoError(t, git.Fetch(source, destDir))
+
+			commit, err := git.Resolve(destDir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, commit)
+
+			// Fetching again keeps the revision checked out.
+			require.NoError(t, git.Fetch(source, destDir))
+
+			commit, err = git.Resolve(destDir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, commit)
+		})
+	}
+}
+
+func TestGoGitFetcher_Fetch_Path(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commitFile(t, repoDir, "README.md", "readme")
+	commitFile(t, repoDir, "presets/ai/rules/rule.md", "rule")
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	source := gitImport(config.GitImportDetails{Repository: repoDir, Path: "presets/ai"})
+
+	require.NoError(t, fetcher.NewGoGitFetcher().Fetch(source, destDir))
+	assert.Equal(t, "rule", readFile(t, filepath.Join(destDir, "presets", "ai", "rules", "rule.md")))
+	assert.NoFileExists(t, filepath.Join(destDir, "README.md"))
+}
+
+func TestGoGitFetcher_Fetch_RepositoryChanged(t *testing.T) {
+	oldRepoDir := newRemoteRepository(t)
+	commitFile(t, oldRepoDir, "old.md", "old")
+	newRepoDir := newRemoteRepository(t)
+	commitFile(t, newRepoDir, "new.md", "new")
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	git := fetcher.NewGoGitFetcher()
+
+	require.NoError(t, git.Fetch(gitImport(config.GitImportDetails{Repository: oldRepoDir}), destDir))
+	require.NoError(t, git.Fetch(gitImport(config.GitImportDetails{Repository: newRepoDir}), destDir))
+
+	assert.NoFileExists(t, filepath.Join(destDir, "old.md"))
+	assert.FileExists(t, filepath.Join(destDir, "new.md"))
+}
+
+func TestGoGitFetcher_Fetch_UnknownRevision(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commitFile(t, repoDir, "rules/rule.md", "first")
+
+	source := gitImport(config.GitImportDetails{Repository: repoDir, Revision: "missing"})
+
+	err := fetcher.NewGoGitFetcher().Fetch(source, filepath.Join(t.TempDir(), "dest"))
+	require.ErrorContains(t, err, "failed to resolve revision missing")
+}
+
+func TestGoGitFetcher_InvalidSourceType(t *testing.T) {
+	source := config.ImportedPackage{
+		Type:    config.ImportTypeLocal,
+		Details: config.LocalImportDetails{Path: "./presets"},
+	}
+
+	err := fetcher.NewGoGitFetcher().Fetch(source, t.TempDir())
+
+	var invalidTypeErr *fetcher.InvalidSourceTypeError
+	require.ErrorAs(t, err, &invalidTypeErr)
+	assert.Equal(t, config.ImportTypeGit, invalidTypeErr.ExpectedType())
+}
+
+func TestGoGitFetcher_ListRemoteRefs(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	firstCommit := commitFile(t, repoDir, "rules/rule.md", "first")
+	runGit(t, repoDir, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+	runGit(t, repoDir, "tag", "v1.1.0")
+	runGit(t, repoDir, "branch", "stable")
+	secondCommit := commitFile(t, repoDir, "rules/rule.md", "second")
+
+	refs, err := fetcher.NewGoGitFetcher().ListRemoteRefs(repoDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, &fetcher.RemoteRefs{
+		Head:     secondCommit,
+		Branches: map[string]string{"main": secondCommit, "stable": firstCommit},
+		Tags:     map[string]string{"v1.0.0": firstCommit, "v1.1.0": firstCommit},
+	}, refs)
+
+	tag, err := fetcher.NewGoGitFetcher().ResolveVersion(repoDir, "~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+}
+
+func TestGoGitFetcher_ChangedFiles(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commitFile(t, repoDir, "README.md", "readme")
+	commitFile(t, repoDir, "presets/rules/kept.md", "kept")
+	commitFile(t, repoDir, "presets/rules/old.md", "renamed content that is long enough to be detected")
+	firstCommit := commitFile(t, repoDir, "presets/rules/removed.md", "removed")
+
+	runGit(t, repoDir, "mv", "presets/rules/old.md", "presets/rules/new.md")
+	runGit(t, repoDir, "rm", "presets/rules/removed.md")
+	commitFile(t, repoDir, "presets/rules/kept.md", "modified")
+	commitFile(t, repoDir, "README.md", "changed outside the package")
+	secondCommit := commitFile(t, repoDir, "presets/prompts/added.md", "added")
+
+	// The first commit is outside the shallow history, so the full history is fetched to diff it.
+	destDir := filepath.Join(t.TempDir(), "dest")
+	source := gitImport(config.GitImportDetails{Repository: repoDir, Path: "presets", Depth: 1})
+	git := fetcher.NewGoGitFetcher()
+	require.NoError(t, git.Fetch(source, destDir))
+
+	changes, err := git.ChangedFiles(filepath.Join(destDir, "presets"), firstCommit, secondCommit)
+	require.NoError(t, err)
+
+	assert.Equal(t, []fetcher.FileChange{
+		{Status: "A", Path: "prompts/added.md"},
+		{Status: "M", Path: "rules/kept.md"},
+		{Status: "R", Path: "rules/new.md", OldPath: "rules/old.md"},
+		{Status: "D", Path: "rules/removed.md"},
+	}, changes)
+}
+
+func TestNewGit(t *testing.T) {
+	assert.IsType(t, &fetcher.GitFetcher{}, fetcher.NewGit(config.GitBackendCLI, nil, nil))
+	assert.IsType(t, &fetcher.GoGitFetcher{}, fetcher.NewGit(config.GitBackendGo, nil, nil))
+
+	// Without git on PATH, the built-in implementation is used.
+	t.Setenv("PATH", t.TempDir())
+	assert.IsType(t, &fetcher.GoGitFetcher{}, fetcher.NewGit(config.GitBackendAuto, nil, nil))
+}
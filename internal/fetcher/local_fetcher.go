@@ -1,24 +1,40 @@
 package fetcher
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-
-	cp "github.com/otiai10/copy"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/sushichan044/ajisai/internal/config"
 	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/loader"
 	"github.com/sushichan044/ajisai/utils"
 )
 
-type LocalFetcher struct{}
+type LocalFetcher struct {
+	// Directory that relative source paths are resolved from. Empty means the current working directory.
+	baseDir string
+}
 
 func NewLocalFetcher() domain.PackageFetcher {
-	return &LocalFetcher{}
+	return NewLocalFetcherWithBaseDir("")
+}
+
+// NewLocalFetcherWithBaseDir creates a LocalFetcher that resolves relative source paths from baseDir,
+// which is usually the directory of the config file.
+func NewLocalFetcherWithBaseDir(baseDir string) domain.PackageFetcher {
+	return &LocalFetcher{baseDir: baseDir}
 }
 
-// Fetch copies content from the source local directory (defined in source.Details)
-// to the destinationDir.
+// Fetch copies the package manifest and the rule and prompt files matched by its exports from the source
+// local directory (defined in source.Details) to the destinationDir. Files ignored by `.gitignore` or
+// `.ajisaiignore` are not copied, and files that are unchanged since the last fetch are not copied again.
 // It expects source.Details to be of type domain.LocalInputSourceDetails.
 func (f *LocalFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
 	localDetails, ok := config.GetImportDetails[config.LocalImportDetails](source)
@@ -30,7 +46,7 @@ func (f *LocalFetcher) Fetch(source config.ImportedPackage, destinationDir strin
 		}
 	}
 
-	srcAbsDir, err := utils.ResolveAbsPath(localDetails.Path)
+	srcAbsDir, err := utils.ResolveAbsPathFrom(f.baseDir, localDetails.Path)
 	if err != nil {
 		return err
 	}
@@ -43,19 +59,37 @@ func (f *LocalFetcher) Fetch(source config.ImportedPackage, destinationDir strin
 		return err
 	}
 
-	// Clean destination directory
-	if err = utils.EmptyDir(destAbsDir); err != nil {
+	files, err := localPackageFiles(srcAbsDir)
+	if err != nil {
 		return err
 	}
-	if err = utils.EnsureDir(destAbsDir); err != nil {
+
+	if err = prepareLocalDestination(destAbsDir); err != nil {
 		return err
 	}
 
-	if err = cp.Copy(srcAbsDir, destAbsDir); err != nil {
+	for _, file := range files {
+		src := filepath.Join(srcAbsDir, filepath.FromSlash(file))
+		if err = syncFile(src, filepath.Join(destAbsDir, filepath.FromSlash(file))); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", src, err)
+		}
+	}
+
+	if err = removeUnlisted(destAbsDir, files); err != nil {
 		return err
 	}
 
-	return nil
+	return WriteCacheMetadata(destAbsDir, source)
+}
+
+// Resolve returns the content digest of destinationDir.
+func (f *LocalFetcher) Resolve(destinationDir string) (string, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.DigestDir(destAbsDir, CacheMetadataFileName)
 }
 
 // TODO: add directory structure check
@@ -75,3 +109,187 @@ func isValidSource(sourceDir string) error {
 
 	return nil
 }
+
+// localPackageFiles returns the manifest and the exported files of the package in dir that are not ignored,
+// as slash-separated paths relative to dir.
+func localPackageFiles(dir string) ([]string, error) {
+	_, exported, err := loader.ExportedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := NewIgnoreMatcher(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(exported)+1)
+	for _, file := range exported {
+		if !matcher.Match(strings.Split(file, "/"), false) {
+			files = append(files, file)
+		}
+	}
+
+	manifestPath, hasManifest, err := loader.ManifestFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	if hasManifest {
+		files = append(files, filepath.Base(manifestPath))
+	}
+
+	return files, nil
+}
+
+// prepareLocalDestination makes sure that files can be copied into destinationDir. It is emptied if it is not a
+// directory, or if it was linked from the global cache, whose files must not be written.
+func prepareLocalDestination(destinationDir string) error {
+	info, err := os.Stat(destinationDir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return utils.EnsureDir(destinationDir)
+	case err != nil:
+		return err
+	case !info.IsDir():
+		if removeErr := os.Remove(destinationDir); removeErr != nil {
+			return removeErr
+		}
+		return utils.EnsureDir(destinationDir)
+	}
+
+	cached, err := ReadCacheMetadata(destinationDir)
+	if err != nil {
+		return err
+	}
+	if cached != nil && cached.Store != "" {
+		return utils.EmptyDir(destinationDir)
+	}
+
+	return nil
+}
+
+// syncFile copies src to dest unless dest has the same content already. Files of the same size and modification
+// time are assumed to be the same. Otherwise their contents are compared.
+func syncFile(src string, dest string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if destInfo, statErr := os.Lstat(dest); statErr == nil && destInfo.Mode().IsRegular() &&
+		destInfo.Size() == srcInfo.Size() {
+		if destInfo.ModTime().Equal(srcInfo.ModTime()) {
+			return nil
+		}
+
+		same, compareErr := sameContent(src, dest)
+		if compareErr != nil {
+			return compareErr
+		}
+		if same {
+			return os.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
+		}
+	}
+
+	if ensureErr := utils.EnsureDir(filepath.Dir(dest)); ensureErr != nil {
+		return ensureErr
+	}
+	if removeErr := os.RemoveAll(dest); removeErr != nil {
+		return removeErr
+	}
+
+	// The file is written next to dest and renamed, so that dest is either the old or the new file.
+	if copyErr := copyFileAtomically(src, dest, srcInfo.Mode().Perm()); copyErr != nil {
+		return copyErr
+	}
+
+	return os.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
+}
+
+func copyFileAtomically(src string, dest string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
+
+// sameContent reports whether both files have the same SHA-256 hash.
+func sameContent(a string, b string) (bool, error) {
+	hashA, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(hashA, hashB), nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// removeUnlisted removes everything in dir except files, the directories holding them and the cache metadata.
+func removeUnlisted(dir string, files []string) error {
+	keep := map[string]bool{CacheMetadataFileName: true}
+	for _, file := range files {
+		for path := file; path != "."; path = filepath.ToSlash(filepath.Dir(path)) {
+			keep[path] = true
+		}
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, relErr := filepath.Rel(dir, path)
+		if relErr != nil {
+			return relErr
+		}
+		if rel == "." || keep[filepath.ToSlash(rel)] {
+			return nil
+		}
+
+		if removeErr := os.RemoveAll(path); removeErr != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, removeErr)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
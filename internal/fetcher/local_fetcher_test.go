@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,8 +77,8 @@ func TestLocalFetcher_Fetch_DestinationHandling(t *testing.T) {
 	destPath := filepath.Join(tempDir, "dest")
 
 	// Create source directory
-	require.NoError(t, os.MkdirAll(sourcePath, 0755))
-	require.NoError(t, os.WriteFile(filepath.Join(sourcePath, "file1.txt"), []byte("source"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourcePath, "rules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourcePath, "rules", "rule1.md"), []byte("source"), 0644))
 
 	source := config.ImportedPackage{
 		Type:    "local",
@@ -119,10 +120,10 @@ func TestLocalFetcher_Fetch_DestinationHandling(t *testing.T) {
 				require.NoError(t, os.WriteFile(filepath.Join(destPath, "existing.txt"), []byte("old"), 0644))
 			},
 			assertAfter: func(t *testing.T) {
-				// Check the directory exists and *will contain the copied file* (file1.txt)
-				// We don't check for emptiness anymore, but rather the presence of the copied file.
-				copiedFilePath := filepath.Join(destPath, "file1.txt")
+				// Check the directory contains the copied file (rules/rule1.md) and nothing else.
+				copiedFilePath := filepath.Join(destPath, "rules", "rule1.md")
 				require.FileExists(t, copiedFilePath, "Copied file should exist in cleared directory")
+				assert.NoFileExists(t, filepath.Join(destPath, "existing.txt"), "Stale file should be removed")
 			},
 		},
 	}
@@ -149,10 +150,10 @@ func TestLocalFetcher_Fetch_CopySuccess(t *testing.T) {
 	defer os.RemoveAll(sourceDir) // Clean up source
 
 	// Create some source files and directories
-	subDir := filepath.Join(sourceDir, "subdir")
-	require.NoError(t, os.Mkdir(subDir, 0755))
-	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "file1.txt"), []byte("content1"), 0644))
-	require.NoError(t, os.WriteFile(filepath.Join(subDir, "file2.txt"), []byte("content2"), 0644))
+	subDir := filepath.Join(sourceDir, "rules", "subdir")
+	require.NoError(t, os.MkdirAll(subDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "rules", "file1.md"), []byte("content1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(subDir, "file2.md"), []byte("content2"), 0644))
 
 	// --- Setup Destination ---
 	destDir := t.TempDir()
@@ -172,18 +173,99 @@ func TestLocalFetcher_Fetch_CopySuccess(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify file1 exists in destination
-	destFile1Path := filepath.Join(destDir, "file1.txt")
+	destFile1Path := filepath.Join(destDir, "rules", "file1.md")
 	require.FileExists(t, destFile1Path)
 	content1, err := os.ReadFile(destFile1Path)
 	require.NoError(t, err)
 	assert.Equal(t, "content1", string(content1))
 
 	// Verify subdir and file2 exist in destination
-	destSubDirPath := filepath.Join(destDir, "subdir")
+	destSubDirPath := filepath.Join(destDir, "rules", "subdir")
 	require.DirExists(t, destSubDirPath)
-	destFile2Path := filepath.Join(destSubDirPath, "file2.txt")
+	destFile2Path := filepath.Join(destSubDirPath, "file2.md")
 	require.FileExists(t, destFile2Path)
 	content2, err := os.ReadFile(destFile2Path)
 	require.NoError(t, err)
 	assert.Equal(t, "content2", string(content2))
+
+	// Verify the cache metadata records the source
+	metadata, err := os.ReadFile(filepath.Join(destDir, ".ajisai-cache.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), `"type": "local"`)
+	assert.Contains(t, string(metadata), sourceDir)
+}
+
+func TestLocalFetcher_Fetch_ExportedFilesOnly(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFile := func(path string, body string) {
+		t.Helper()
+
+		fullPath := filepath.Join(sourceDir, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(body), 0644))
+	}
+
+	writeFile("ajisai.yml", `package:
+  name: presets
+  exports:
+    go:
+      rules:
+        - "**/*.md"
+`)
+	writeFile("rules/go.md", "# Go")
+	writeFile("rules/draft.md", "# Draft")
+	writeFile("rules/generated/output.md", "# Generated")
+	writeFile("node_modules/pkg/README.md", "# Dependency")
+	writeFile("main.go", "package main")
+	writeFile(".gitignore", "node_modules/\n")
+	writeFile("rules/.gitignore", "generated/\n")
+	writeFile(".ajisaiignore", "# Not ready yet\ndraft.md\n")
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	source := config.ImportedPackage{
+		Type:    config.ImportTypeLocal,
+		Details: config.LocalImportDetails{Path: sourceDir},
+	}
+
+	localFetcher := fetcher.NewLocalFetcher()
+	require.NoError(t, localFetcher.Fetch(source, destDir))
+
+	assert.FileExists(t, filepath.Join(destDir, "ajisai.yml"))
+	assert.FileExists(t, filepath.Join(destDir, "rules", "go.md"))
+	assert.NoFileExists(t, filepath.Join(destDir, "rules", "draft.md"))
+	assert.NoDirExists(t, filepath.Join(destDir, "rules", "generated"))
+	assert.NoDirExists(t, filepath.Join(destDir, "node_modules"))
+	assert.NoFileExists(t, filepath.Join(destDir, "main.go"))
+
+	goRule := filepath.Join(destDir, "rules", "go.md")
+	stat := func() os.FileInfo {
+		t.Helper()
+
+		info, err := os.Stat(goRule)
+		require.NoError(t, err)
+		return info
+	}
+
+	// Unchanged files are not copied again, even if only their modification time changed.
+	before := stat()
+	require.NoError(t, localFetcher.Fetch(source, destDir))
+	assert.True(t, os.SameFile(before, stat()))
+
+	touched := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(sourceDir, "rules", "go.md"), touched, touched))
+	require.NoError(t, localFetcher.Fetch(source, destDir))
+	assert.True(t, os.SameFile(before, stat()))
+
+	// Changed files are copied again.
+	writeFile("rules/go.md", "# Go, edited")
+	require.NoError(t, localFetcher.Fetch(source, destDir))
+	body, err := os.ReadFile(goRule)
+	require.NoError(t, err)
+	assert.Equal(t, "# Go, edited", string(body))
+
+	// Files that are no longer copied are removed.
+	writeFile(".ajisaiignore", "go.md\n")
+	require.NoError(t, localFetcher.Fetch(source, destDir))
+	assert.NoFileExists(t, goRule)
+	assert.FileExists(t, filepath.Join(destDir, "rules", "draft.md"))
 }
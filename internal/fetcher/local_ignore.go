@@ -0,0 +1,49 @@
+package fetcher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the name of the file in the root of a local package that lists files local imports do not
+// copy, in the format of `.gitignore`.
+const IgnoreFileName = ".ajisaiignore"
+
+// NewIgnoreMatcher returns a matcher of the files in dir that local imports do not copy: those ignored by the
+// `.gitignore` files in dir and its subdirectories, and by `.ajisaiignore` in dir, which takes precedence.
+func NewIgnoreMatcher(dir string) (gitignore.Matcher, error) {
+	patterns, err := gitignore.ReadPatterns(osfs.New(dir), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore files in %s: %w", dir, err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return gitignore.NewMatcher(patterns), nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, scanErr)
+	}
+
+	return gitignore.NewMatcher(patterns), nil
+}
@@ -0,0 +1,201 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+// CacheMetadataFileName is the name of the file in each cache entry that records where it was fetched from.
+const CacheMetadataFileName = ".ajisai-cache.json"
+
+// CacheMetadata records the import definition a cache entry was fetched from.
+// Fetchers compare it with the current definition to detect a cache that no longer matches the config.
+type CacheMetadata struct {
+	Type config.ImportType `json:"type"`
+
+	// Repository, Path and Revision of git imports, or Path of local imports.
+	Repository string `json:"repository,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+
+	// URL, SHA256 and StripComponents of archive imports.
+	URL             string `json:"url,omitempty"`
+	SHA256          string `json:"sha256,omitempty"`
+	StripComponents int    `json:"stripComponents,omitempty"`
+
+	// Ref of OCI imports, and Digest of the manifest that was pulled.
+	Ref    string `json:"ref,omitempty"`
+	Digest string `json:"digest,omitempty"`
+
+	// URL the git repository or archive was fetched from, if a mirror rule rewrote Repository or URL.
+	// Repository and URL stay the identity of the import.
+	Mirror string `json:"mirror,omitempty"`
+
+	// Entry of the global cache that the files of this entry are hard links to. Fetchers must not write into
+	// such an entry, since the files are shared with other projects.
+	Store string `json:"store,omitempty"`
+
+	// When the entry was last fetched successfully. Set when the metadata is written.
+	FetchedAt time.Time `json:"fetchedAt,omitzero"`
+}
+
+// NewCacheMetadata creates the metadata for a cache entry fetched from pkg.
+func NewCacheMetadata(pkg config.ImportedPackage) CacheMetadata {
+	metadata := CacheMetadata{Type: pkg.Type}
+
+	switch pkg.Type {
+	case config.ImportTypeGit:
+		details, _ := config.GetImportDetails[config.GitImportDetails](pkg)
+		metadata.Repository = details.Repository
+		metadata.Path = details.Path
+		metadata.Revision = details.Revision
+	case config.ImportTypeLocal:
+		details, _ := config.GetImportDetails[config.LocalImportDetails](pkg)
+		metadata.Path = details.Path
+	case config.ImportTypeArchive:
+		details, _ := config.GetImportDetails[config.ArchiveImportDetails](pkg)
+		metadata.URL = details.URL
+		metadata.SHA256 = strings.ToLower(details.SHA256)
+		metadata.StripComponents = details.StripComponents
+	case config.ImportTypeOCI:
+		details, _ := config.GetImportDetails[config.OCIImportDetails](pkg)
+		metadata.Ref = details.Ref
+	}
+
+	return metadata
+}
+
+// withMirror returns a copy of the metadata that records remoteURL as the mirror,
+// unless it is the URL of the import itself.
+func (m CacheMetadata) withMirror(remoteURL string) CacheMetadata {
+	if remoteURL != m.Repository && remoteURL != m.URL {
+		m.Mirror = remoteURL
+	}
+
+	return m
+}
+
+// sameSource reports whether both entries were fetched from the same source, regardless of when.
+func (m CacheMetadata) sameSource(other CacheMetadata) bool {
+	m.FetchedAt, other.FetchedAt = time.Time{}, time.Time{}
+	return m == other
+}
+
+// MatchesImport reports whether the entry was fetched from the repository, subdirectory or archive of pkg.
+// The revision of git imports and the reference of OCI imports are not compared, since they may have been
+// pinned to a commit or digest. Callers check what the entry resolves to instead.
+func (m CacheMetadata) MatchesImport(pkg config.ImportedPackage) bool {
+	expected := NewCacheMetadata(pkg)
+	return m.Type == expected.Type &&
+		m.Repository == expected.Repository &&
+		m.Path == expected.Path &&
+		m.URL == expected.URL &&
+		m.SHA256 == expected.SHA256 &&
+		m.StripComponents == expected.StripComponents
+}
+
+// IsFresh reports whether the cache entry in dir was fetched from pkg less than interval ago.
+// See CacheMetadata.MatchesImport for what is compared.
+func IsFresh(dir string, pkg config.ImportedPackage, interval time.Duration) (bool, error) {
+	cached, err := ReadCacheMetadata(dir)
+	if err != nil || cached == nil || cached.FetchedAt.IsZero() {
+		return false, err
+	}
+
+	if time.Since(cached.FetchedAt) >= interval {
+		return false, nil
+	}
+
+	return cached.MatchesImport(pkg), nil
+}
+
+// ReadCacheMetadata reads the metadata of the cache entry in dir.
+// It returns nil if the entry has no metadata, e.g. because it was fetched by an older version of ajisai.
+func ReadCacheMetadata(dir string) (*CacheMetadata, error) {
+	body, err := os.ReadFile(filepath.Join(dir, CacheMetadataFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil //nolint:nilnil // Missing metadata is not an error.
+		}
+		return nil, fmt.Errorf("failed to read cache metadata in %s: %w", dir, err)
+	}
+
+	var metadata CacheMetadata
+	if unmarshalErr := json.Unmarshal(body, &metadata); unmarshalErr != nil {
+		// A corrupted file is treated like a missing one, so the entry is fetched again.
+		return nil, nil //nolint:nilnil // See above.
+	}
+
+	return &metadata, nil
+}
+
+// WriteCacheMetadata records that the cache entry in dir was fetched from pkg.
+func WriteCacheMetadata(dir string, pkg config.ImportedPackage) error {
+	return writeCacheMetadata(dir, NewCacheMetadata(pkg))
+}
+
+// WriteLinkedCacheMetadata records that the cache entry in dir links to the entry of the global cache in storeDir,
+// copying the source of storeDir.
+func WriteLinkedCacheMetadata(dir string, storeDir string) error {
+	metadata, err := ReadCacheMetadata(storeDir)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		return fmt.Errorf("%s has no cache metadata", storeDir)
+	}
+
+	metadata.Store = storeDir
+	return writeCacheMetadata(dir, *metadata)
+}
+
+func writeCacheMetadata(dir string, metadata CacheMetadata) error {
+	metadata.FetchedAt = time.Now().UTC().Truncate(time.Second)
+
+	body, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache metadata: %w", err)
+	}
+
+	if ensureErr := utils.EnsureDir(dir); ensureErr != nil {
+		return ensureErr
+	}
+
+	if writeErr := utils.AtomicWriteFile(
+		filepath.Join(dir, CacheMetadataFileName),
+		bytes.NewReader(append(body, '\n')),
+	); writeErr != nil {
+		return fmt.Errorf("failed to write cache metadata in %s: %w", dir, writeErr)
+	}
+
+	return nil
+}
+
+// needsReclone reports whether the existing cache entry in dir must be cloned again for the git import
+// described by expected, because it was cloned from another repository, mirror or subdirectory,
+// or its origin is unknown. Entries linked from the global cache are cloned again as well, since their files
+// are shared. A different revision does not need a new clone.
+func needsReclone(dir string, expected CacheMetadata) (bool, error) {
+	cached, err := ReadCacheMetadata(dir)
+	if err != nil {
+		return false, err
+	}
+	if cached == nil {
+		return true, nil
+	}
+
+	return cached.Store != "" ||
+		cached.Type != expected.Type ||
+		cached.Repository != expected.Repository ||
+		cached.Mirror != expected.Mirror ||
+		cached.Path != expected.Path, nil
+}
@@ -0,0 +1,40 @@
+package fetcher
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Mirrors rewrites the URLs of git repositories and archives to the mirrors configured in the settings,
+// like `url.<base>.insteadOf` of git.
+type Mirrors struct {
+	// prefixes are sorted from the longest, so that the most specific rule wins.
+	prefixes     []string
+	replacements map[string]string
+}
+
+// NewMirrors creates Mirrors from URL prefixes mapped to the prefix that replaces them.
+func NewMirrors(rules map[string]string) *Mirrors {
+	prefixes := slices.SortedFunc(maps.Keys(rules), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+
+	return &Mirrors{prefixes: prefixes, replacements: maps.Clone(rules)}
+}
+
+// Rewrite returns the URL to fetch instead of rawURL, or rawURL itself if no rule matches.
+func (m *Mirrors) Rewrite(rawURL string) string {
+	if m == nil {
+		return rawURL
+	}
+
+	for _, prefix := range m.prefixes {
+		if rest, found := strings.CutPrefix(rawURL, prefix); found {
+			return m.replacements[prefix] + rest
+		}
+	}
+
+	return rawURL
+}
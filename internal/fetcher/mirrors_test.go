@@ -0,0 +1,101 @@
+package fetcher_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+)
+
+func TestMirrors_Rewrite(t *testing.T) {
+	mirrors := fetcher.NewMirrors(map[string]string{
+		"https://github.com/":         "https://mirror.example.com/github/",
+		"https://github.com/example/": "https://mirror.example.com/example/",
+		"git@github.com:":             "https://mirror.example.com/github/",
+	})
+
+	tests := map[string]struct {
+		url  string
+		want string
+	}{
+		"prefix":         {"https://github.com/org/repo.git", "https://mirror.example.com/github/org/repo.git"},
+		"longest prefix": {"https://github.com/example/repo.git", "https://mirror.example.com/example/repo.git"},
+		"scp-like":       {"git@github.com:org/repo.git", "https://mirror.example.com/github/org/repo.git"},
+		"no match":       {"https://gitlab.com/org/repo.git", "https://gitlab.com/org/repo.git"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mirrors.Rewrite(tt.url))
+		})
+	}
+
+	var noMirrors *fetcher.Mirrors
+	assert.Equal(t, "https://github.com/org/repo.git", noMirrors.Rewrite("https://github.com/org/repo.git"))
+}
+
+func TestGit_Fetch_Mirror(t *testing.T) {
+	repoDir := newRemoteRepository(t)
+	commit := commitFile(t, repoDir, "rules/rule.md", "mirrored")
+
+	repoURL := "https://github.com/example/" + filepath.Base(repoDir)
+	mirrorURL := "file://" + filepath.ToSlash(repoDir)
+	mirrors := fetcher.NewMirrors(map[string]string{
+		"https://github.com/example/": "file://" + filepath.ToSlash(filepath.Dir(repoDir)) + "/",
+	})
+
+	for _, backend := range []config.GitBackend{config.GitBackendCLI, config.GitBackendGo} {
+		t.Run(string(backend), func(t *testing.T) {
+			destDir := filepath.Join(t.TempDir(), "dest")
+			git := fetcher.NewGit(backend, nil, mirrors)
+
+			require.NoError(t, git.Fetch(gitImport(config.GitImportDetails{Repository: repoURL}), destDir))
+			assert.Equal(t, "mirrored", readFile(t, filepath.Join(destDir, "rules", "rule.md")))
+
+			// The import is still identified by its own repository.
+			metadata, err := fetcher.ReadCacheMetadata(destDir)
+			require.NoError(t, err)
+			require.NotNil(t, metadata)
+			assert.Equal(t, repoURL, metadata.Repository)
+			assert.Equal(t, mirrorURL, metadata.Mirror)
+
+			refs, err := git.ListRemoteRefs(repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, commit, refs.Head)
+		})
+	}
+}
+
+func TestArchiveFetcher_Fetch_Mirror(t *testing.T) {
+	body := tarGzArchive(t, archiveEntry{name: "rules/style.md", content: "# Style"})
+	mirrorURL, requests := serveArchive(t, body)
+
+	originalURL := "https://releases.example.com/presets.tar.gz"
+	mirrors := fetcher.NewMirrors(map[string]string{
+		"https://releases.example.com/": strings.TrimSuffix(mirrorURL, "presets.tar.gz"),
+	})
+	destDir := filepath.Join(t.TempDir(), "presets")
+
+	archiveFetcher := fetcher.NewArchiveFetcherWithMirrors(mirrors)
+	require.NoError(t, archiveFetcher.Fetch(archiveImport(originalURL, checksum(body), 0), destDir))
+
+	content, err := os.ReadFile(filepath.Join(destDir, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style", string(content))
+
+	metadata, err := fetcher.ReadCacheMetadata(destDir)
+	require.NoError(t, err)
+	require.NotNil(t, metadata)
+	assert.Equal(t, originalURL, metadata.URL)
+	assert.Equal(t, mirrorURL, metadata.Mirror)
+
+	// The cached archive is not downloaded again.
+	require.NoError(t, archiveFetcher.Fetch(archiveImport(originalURL, checksum(body), 0), destDir))
+	assert.Equal(t, 1, *requests)
+}
@@ -0,0 +1,144 @@
+package fetcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+// ociLayerTarMediaType is the media type of uncompressed tarball layers, which are accepted as well.
+const ociLayerTarMediaType = "application/vnd.oci.image.layer.v1.tar"
+
+// OCIFetcher pulls packages pushed to an OCI registry, e.g. by `ajisai push`.
+type OCIFetcher struct {
+	client *oci.Client
+}
+
+var _ domain.PackageFetcher = (*OCIFetcher)(nil)
+
+// NewOCIFetcher creates a new OCIFetcher.
+func NewOCIFetcher() *OCIFetcher {
+	return NewOCIFetcherWithClient(oci.NewClient())
+}
+
+// NewOCIFetcherWithClient creates a new OCIFetcher that pulls with the given client.
+func NewOCIFetcherWithClient(client *oci.Client) *OCIFetcher {
+	return &OCIFetcher{client: client}
+}
+
+// Fetch resolves the reference to a manifest, downloads its layers, verifies their digests
+// and extracts them into destinationDir in order.
+// The layers are not downloaded again if destinationDir already holds the same manifest.
+func (f *OCIFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
+	ociDetails, ok := config.GetImportDetails[config.OCIImportDetails](source)
+	if !ok {
+		return &InvalidSourceTypeError{
+			expectedType: config.ImportTypeOCI,
+			actualType:   source.Type,
+			err:          fmt.Errorf("cannot fetch from source type: %s", source.Type),
+		}
+	}
+
+	ref, err := oci.ParseReference(ociDetails.Ref)
+	if err != nil {
+		return err
+	}
+
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return err
+	}
+
+	manifest, digest, err := f.client.FetchManifest(ref)
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest of %s: %w", ref, err)
+	}
+
+	metadata := NewCacheMetadata(source)
+	metadata.Digest = digest
+	if cached, _ := ReadCacheMetadata(destAbsDir); cached != nil && cached.sameSource(metadata) {
+		// The manifest is unchanged. Record that it was checked, so that the refresh interval starts again.
+		return writeCacheMetadata(destAbsDir, metadata)
+	}
+
+	parentDir := filepath.Dir(destAbsDir)
+	if err = utils.EnsureDir(parentDir); err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(parentDir, "."+filepath.Base(destAbsDir)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// The limits apply to all layers together, since they are extracted into the same directory.
+	extractor := &archiveExtractor{dest: staging, limits: DefaultArchiveLimits()}
+	for _, layer := range manifest.Layers {
+		if layerErr := f.extractLayer(ref, layer, parentDir, extractor); layerErr != nil {
+			return fmt.Errorf("failed to extract layer %s of %s: %w", layer.Digest, ref, layerErr)
+		}
+	}
+
+	if err = writeCacheMetadata(staging, metadata); err != nil {
+		return err
+	}
+
+	if err = utils.EmptyDir(destAbsDir); err != nil {
+		return err
+	}
+
+	return os.Rename(staging, destAbsDir)
+}
+
+// Resolve returns the digest of the manifest pulled into destinationDir.
+func (f *OCIFetcher) Resolve(destinationDir string) (string, error) {
+	destAbsDir, err := utils.ResolveAbsPath(destinationDir)
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := ReadCacheMetadata(destAbsDir)
+	if err != nil {
+		return "", err
+	}
+	if metadata == nil || metadata.Digest == "" {
+		return "", fmt.Errorf("%s has no pulled manifest", destAbsDir)
+	}
+
+	return metadata.Digest, nil
+}
+
+// extractLayer downloads the layer into a temporary file in tmpDir and extracts it with extractor.
+func (f *OCIFetcher) extractLayer(
+	ref oci.Reference,
+	layer oci.Descriptor,
+	tmpDir string,
+	extractor *archiveExtractor,
+) error {
+	if layer.MediaType != oci.LayerMediaType && layer.MediaType != ociLayerTarMediaType {
+		return fmt.Errorf("unsupported media type %q", layer.MediaType)
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, ".ajisai-layer-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if fetchErr := f.client.FetchBlob(ref, layer, tmp); fetchErr != nil {
+		return fetchErr
+	}
+
+	if closeErr := tmp.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	return extractor.extract(tmp.Name())
+}
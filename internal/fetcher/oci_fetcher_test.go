@@ -0,0 +1,101 @@
+package fetcher_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/oci/ocitest"
+)
+
+// pushPackage pushes the archive to the registry and returns the reference and manifest digest.
+func pushPackage(t *testing.T, registry *ocitest.Registry, tag string, archive []byte) (string, string) {
+	t.Helper()
+
+	rawRef := registry.Host + "/ai/presets:" + tag
+	ref, err := oci.ParseReference(rawRef)
+	require.NoError(t, err)
+
+	digest, err := oci.NewClientWithHTTP(http.DefaultClient, oci.NewDockerCredentialStore()).
+		PushPackage(ref, archive, nil)
+	require.NoError(t, err)
+
+	return rawRef, digest
+}
+
+func ociImport(ref string) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeOCI,
+		Include: []string{"default"},
+		Details: config.OCIImportDetails{Ref: ref},
+	}
+}
+
+func TestOCIFetcher_Fetch(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	registry := ocitest.NewRegistry(t)
+	ref, digest := pushPackage(t, registry, "1.0.0", tarGzArchive(t,
+		archiveEntry{name: "ajisai.yml", content: "package:\n  name: presets\n"},
+		archiveEntry{name: "rules/style.md", content: "# Style"},
+	))
+	destDir := filepath.Join(t.TempDir(), "presets")
+
+	ociFetcher := fetcher.NewOCIFetcher()
+	require.NoError(t, ociFetcher.Fetch(ociImport(ref), destDir))
+
+	content, err := os.ReadFile(filepath.Join(destDir, "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Style", string(content))
+
+	resolved, err := ociFetcher.Resolve(destDir)
+	require.NoError(t, err)
+	assert.Equal(t, digest, resolved)
+
+	// The layers of the same manifest are not downloaded again.
+	require.NoError(t, ociFetcher.Fetch(ociImport(ref), destDir))
+	blobRequests := slices.DeleteFunc(registry.Requests(), func(request string) bool {
+		return !strings.HasPrefix(request, "GET ") || !strings.Contains(request, "/blobs/")
+	})
+	assert.Len(t, blobRequests, 1)
+}
+
+func TestOCIFetcher_Fetch_NewTag(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	registry := ocitest.NewRegistry(t)
+	ref, _ := pushPackage(t, registry, "latest", tarGzArchive(t, archiveEntry{name: "rules/old.md", content: "old"}))
+	destDir := filepath.Join(t.TempDir(), "presets")
+
+	ociFetcher := fetcher.NewOCIFetcher()
+	require.NoError(t, ociFetcher.Fetch(ociImport(ref), destDir))
+
+	pushPackage(t, registry, "latest", tarGzArchive(t, archiveEntry{name: "rules/new.md", content: "new"}))
+	require.NoError(t, ociFetcher.Fetch(ociImport(ref), destDir))
+
+	assert.NoFileExists(t, filepath.Join(destDir, "rules", "old.md"))
+	assert.FileExists(t, filepath.Join(destDir, "rules", "new.md"))
+}
+
+func TestOCIFetcher_Fetch_TamperedLayer(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	registry := ocitest.NewRegistry(t)
+	archive := tarGzArchive(t, archiveEntry{name: "rules/style.md", content: "# Style"})
+	ref, _ := pushPackage(t, registry, "1.0.0", archive)
+	registry.PutBlob(oci.Digest(archive), tarGzArchive(t, archiveEntry{name: "rules/style.md", content: "# Evil"}))
+
+	err := fetcher.NewOCIFetcher().Fetch(ociImport(ref), filepath.Join(t.TempDir(), "presets"))
+
+	var mismatchErr *oci.DigestMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+}
@@ -0,0 +1,254 @@
+package globalcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	// DirEnv overrides the directory of the global cache.
+	DirEnv = "AJISAI_GLOBAL_CACHE_DIR"
+
+	// identityLength is the number of hex digits of the source hash that names the directory of an import.
+	identityLength = 16
+
+	lockSuffix = ".lock"
+)
+
+// Store is the global cache shared by every project of the user. It is content-addressed: entries are keyed by
+// the source of an import and the commit or digest it resolves to, and are never modified once written.
+//
+// Entries are laid out as `<root>/<type>/<source hash>/<revision>`. Fetches of the same source are serialized
+// with a lock file, so that concurrent runs in different projects do not write the same entry.
+type Store struct {
+	root string
+}
+
+// DefaultDir returns the directory of the global cache, which is `ajisai` in the user cache directory
+// unless AJISAI_GLOBAL_CACHE_DIR is set.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return utils.ResolveAbsPath(dir)
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory (set %s instead): %w", DirEnv, err)
+	}
+
+	return filepath.Join(userCacheDir, "ajisai"), nil
+}
+
+// New creates a Store in root.
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+// Fetch makes destinationDir a copy of the entry of source, fetching the entry with packageFetcher first
+// if the store does not have it. Files are hard-linked from the entry, or copied if linking fails.
+//
+// The entry is looked up before fetching if the revision of source is a commit SHA or digest,
+// and the checksum of archives. Other revisions are fetched and stored under what they resolve to.
+func (s *Store) Fetch(
+	packageFetcher domain.PackageFetcher,
+	source config.ImportedPackage,
+	destinationDir string,
+) error {
+	identityDir, err := s.identityDir(source)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(identityDir + lockSuffix)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entryDir, found, err := lookup(identityDir, source)
+	if err != nil {
+		return err
+	}
+	if !found {
+		entryDir, err = store(packageFetcher, source, identityDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return linkTree(entryDir, destinationDir)
+}
+
+// Restore makes destinationDir a copy of the entry of source without fetching. It reports false if the revision
+// of source is not known before fetching, or the store does not have its entry.
+func (s *Store) Restore(source config.ImportedPackage, destinationDir string) (bool, error) {
+	identityDir, err := s.identityDir(source)
+	if err != nil {
+		return false, err
+	}
+
+	unlock, err := lock(identityDir + lockSuffix)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	entryDir, found, err := lookup(identityDir, source)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, linkTree(entryDir, destinationDir)
+}
+
+// identityDir returns the directory holding the entries of the source, creating its parent.
+func (s *Store) identityDir(source config.ImportedPackage) (string, error) {
+	if source.Type == config.ImportTypeLocal {
+		return "", errors.New("local imports are not stored in the global cache")
+	}
+
+	typeDir := filepath.Join(s.root, string(source.Type))
+	if err := utils.EnsureDir(typeDir); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(typeDir, identity(source)), nil
+}
+
+// lookup returns the entry of source in identityDir, if its revision is known before fetching.
+func lookup(identityDir string, source config.ImportedPackage) (string, bool, error) {
+	revision := KnownRevision(source)
+	if revision == "" {
+		return "", false, nil
+	}
+
+	entryDir := filepath.Join(identityDir, entryName(revision))
+	exists, err := utils.IsDirExists(entryDir)
+	if err != nil {
+		return "", false, err
+	}
+
+	return entryDir, exists, nil
+}
+
+// store fetches source into identityDir and returns its entry. The caller must hold the lock of identityDir.
+func store(packageFetcher domain.PackageFetcher, source config.ImportedPackage, identityDir string) (string, error) {
+	if err := utils.EnsureDir(identityDir); err != nil {
+		return "", err
+	}
+
+	// Fetches of this source interrupted before they were stored are left over in hidden directories.
+	// No other fetch of it can be in progress while the lock is held.
+	leftovers, err := filepath.Glob(filepath.Join(identityDir, ".fetch-*"))
+	if err != nil {
+		return "", err
+	}
+	for _, leftover := range leftovers {
+		if removeErr := os.RemoveAll(leftover); removeErr != nil {
+			return "", fmt.Errorf("failed to remove interrupted fetch %s: %w", leftover, removeErr)
+		}
+	}
+
+	workDir, err := os.MkdirTemp(identityDir, ".fetch-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	fetchedDir := filepath.Join(workDir, "entry")
+	if fetchErr := packageFetcher.Fetch(source, fetchedDir); fetchErr != nil {
+		return "", fetchErr
+	}
+
+	revision := KnownRevision(source)
+	if revision == "" {
+		if revision, err = packageFetcher.Resolve(fetchedDir); err != nil {
+			return "", fmt.Errorf("failed to resolve fetched content: %w", err)
+		}
+	}
+
+	entryDir := filepath.Join(identityDir, entryName(revision))
+	exists, err := utils.IsDirExists(entryDir)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		// The revision was not known before fetching, and resolved to a stored entry.
+		return entryDir, nil
+	}
+
+	if renameErr := os.Rename(fetchedDir, entryDir); renameErr != nil {
+		return "", fmt.Errorf("failed to store %s: %w", entryDir, renameErr)
+	}
+
+	return entryDir, nil
+}
+
+// identity returns a hash of what identifies the source apart from its revision.
+func identity(source config.ImportedPackage) string {
+	metadata := fetcher.NewCacheMetadata(source)
+	metadata.Revision = ""
+	metadata.SHA256 = ""
+	if ref, err := oci.ParseReference(metadata.Ref); err == nil {
+		metadata.Ref = ref.Registry + "/" + ref.Repository
+	}
+
+	// Marshaling a struct of strings and an int does not fail.
+	body, _ := json.Marshal(metadata)
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])[:identityLength]
+}
+
+// KnownRevision returns what the entry of source is stored under, if it is known before fetching:
+// the commit of git imports that check out a full commit SHA, the digest of OCI references with one,
+// and the checksum of archives. It is empty otherwise.
+func KnownRevision(source config.ImportedPackage) string {
+	switch source.Type {
+	case config.ImportTypeGit:
+		details, _ := config.GetImportDetails[config.GitImportDetails](source)
+		if isFullCommitSHA(details.Revision) {
+			return strings.ToLower(details.Revision)
+		}
+	case config.ImportTypeArchive:
+		details, _ := config.GetImportDetails[config.ArchiveImportDetails](source)
+		return "sha256:" + strings.ToLower(details.SHA256)
+	case config.ImportTypeOCI:
+		details, _ := config.GetImportDetails[config.OCIImportDetails](source)
+		if ref, err := oci.ParseReference(details.Ref); err == nil {
+			return ref.Digest
+		}
+	case config.ImportTypeLocal:
+	}
+
+	return ""
+}
+
+// isFullCommitSHA reports whether revision is a SHA-1 or SHA-256 commit hash, as opposed to a branch, tag
+// or abbreviated hash.
+func isFullCommitSHA(revision string) bool {
+	const sha1Length, sha256Length = 40, 64
+	if len(revision) != sha1Length && len(revision) != sha256Length {
+		return false
+	}
+
+	return strings.Trim(strings.ToLower(revision), "0123456789abcdef") == ""
+}
+
+// entryName returns the directory name of a revision. Digests like `sha256:<hex>` contain a colon,
+// which Windows does not allow in file names.
+func entryName(revision string) string {
+	return strings.ReplaceAll(revision, ":", "-")
+}
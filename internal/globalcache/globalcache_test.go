@@ -0,0 +1,123 @@
+package globalcache_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/globalcache"
+)
+
+const testChecksum = "0f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a6978"
+
+// countingFetcher writes a rule and counts how often it fetches.
+type countingFetcher struct {
+	fetches  atomic.Int32
+	resolved string
+}
+
+func (f *countingFetcher) Fetch(source config.ImportedPackage, destinationDir string) error {
+	f.fetches.Add(1)
+
+	if err := os.MkdirAll(filepath.Join(destinationDir, "rules"), 0750); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(destinationDir, "rules", "rule.md"), []byte("# Rule"), 0600); err != nil {
+		return err
+	}
+
+	return fetcher.WriteCacheMetadata(destinationDir, source)
+}
+
+func (f *countingFetcher) Resolve(string) (string, error) {
+	return f.resolved, nil
+}
+
+func archiveImport() config.ImportedPackage {
+	return config.ImportedPackage{
+		Type: config.ImportTypeArchive,
+		Details: config.ArchiveImportDetails{
+			URL:    "https://releases.example.com/presets.tar.gz",
+			SHA256: testChecksum,
+		},
+	}
+}
+
+func TestStore_Fetch(t *testing.T) {
+	store := globalcache.New(filepath.Join(t.TempDir(), "global"))
+	packageFetcher := &countingFetcher{}
+	projectsDir := t.TempDir()
+
+	// Concurrent runs in different projects fetch the archive once.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			projectDir := filepath.Join(projectsDir, fmt.Sprintf("project-%d", i))
+			errs[i] = store.Fetch(packageFetcher, archiveImport(), filepath.Join(projectDir, "presets"))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), packageFetcher.fetches.Load())
+
+	first, err := os.Stat(filepath.Join(projectsDir, "project-0", "presets", "rules", "rule.md"))
+	require.NoError(t, err)
+	second, err := os.Stat(filepath.Join(projectsDir, "project-1", "presets", "rules", "rule.md"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(first, second))
+
+	// The metadata of a project records the entry, and is not shared.
+	metadata, err := fetcher.ReadCacheMetadata(filepath.Join(projectsDir, "project-0", "presets"))
+	require.NoError(t, err)
+	require.NotNil(t, metadata)
+	assert.Equal(t, "https://releases.example.com/presets.tar.gz", metadata.URL)
+	assert.NotEmpty(t, metadata.Store)
+
+	restored, err := store.Restore(archiveImport(), filepath.Join(projectsDir, "restored", "presets"))
+	require.NoError(t, err)
+	assert.True(t, restored)
+	assert.Equal(t, int32(1), packageFetcher.fetches.Load())
+}
+
+func TestStore_Fetch_UnknownRevision(t *testing.T) {
+	store := globalcache.New(filepath.Join(t.TempDir(), "global"))
+	commit := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	packageFetcher := &countingFetcher{resolved: commit}
+
+	source := config.ImportedPackage{
+		Type:    config.ImportTypeGit,
+		Details: config.GitImportDetails{Repository: "https://github.com/example/presets.git", Revision: "main"},
+	}
+	assert.Empty(t, globalcache.KnownRevision(source))
+
+	// The branch is not known before fetching, so it cannot be restored.
+	restored, err := store.Restore(source, filepath.Join(t.TempDir(), "presets"))
+	require.NoError(t, err)
+	assert.False(t, restored)
+
+	require.NoError(t, store.Fetch(packageFetcher, source, filepath.Join(t.TempDir(), "presets")))
+	require.NoError(t, store.Fetch(packageFetcher, source, filepath.Join(t.TempDir(), "presets")))
+	assert.Equal(t, int32(2), packageFetcher.fetches.Load())
+
+	// The fetched branch was stored under the commit it resolved to.
+	pinned := source
+	pinned.Details = config.GitImportDetails{Repository: "https://github.com/example/presets.git", Revision: commit}
+	assert.Equal(t, commit, globalcache.KnownRevision(pinned))
+
+	require.NoError(t, store.Fetch(packageFetcher, pinned, filepath.Join(t.TempDir(), "presets")))
+	assert.Equal(t, int32(2), packageFetcher.fetches.Load())
+}
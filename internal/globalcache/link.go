@@ -0,0 +1,88 @@
+package globalcache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	cp "github.com/otiai10/copy"
+
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+// linkTree replaces destinationDir with a copy of entryDir whose files are hard links to those in entryDir,
+// or copies of them where linking fails (e.g. across file systems). The cache metadata is written anew
+// to record entryDir. It does nothing if destinationDir is already linked to entryDir.
+func linkTree(entryDir string, destinationDir string) error {
+	if isLinked(entryDir, destinationDir) {
+		return nil
+	}
+
+	parentDir := filepath.Dir(destinationDir)
+	if err := utils.EnsureDir(parentDir); err != nil {
+		return err
+	}
+
+	// The tree is linked into a hidden sibling first, so that destinationDir is never left half populated.
+	tmpDir, err := os.MkdirTemp(parentDir, "."+filepath.Base(destinationDir)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	walkErr := filepath.WalkDir(entryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, relErr := filepath.Rel(entryDir, path)
+		if relErr != nil {
+			return relErr
+		}
+		target := filepath.Join(tmpDir, rel)
+
+		switch {
+		case rel == fetcher.CacheMetadataFileName:
+			return nil
+		case d.IsDir():
+			return os.MkdirAll(target, 0750)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, readErr := os.Readlink(path)
+			if readErr != nil {
+				return readErr
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			if linkErr := os.Link(path, target); linkErr == nil {
+				return nil
+			}
+			return cp.Copy(path, target)
+		}
+
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("failed to link %s into %s: %w", entryDir, destinationDir, walkErr)
+	}
+	if metadataErr := fetcher.WriteLinkedCacheMetadata(tmpDir, entryDir); metadataErr != nil {
+		return metadataErr
+	}
+
+	if removeErr := os.RemoveAll(destinationDir); removeErr != nil {
+		return fmt.Errorf("failed to remove outdated cache %s: %w", destinationDir, removeErr)
+	}
+	if renameErr := os.Rename(tmpDir, destinationDir); renameErr != nil {
+		return fmt.Errorf("failed to move linked cache into %s: %w", destinationDir, renameErr)
+	}
+
+	return nil
+}
+
+// isLinked reports whether destinationDir was linked from entryDir. Fetchers replace the cache metadata
+// whenever they write into destinationDir.
+func isLinked(entryDir string, destinationDir string) bool {
+	metadata, err := fetcher.ReadCacheMetadata(destinationDir)
+	return err == nil && metadata != nil && metadata.Store == entryDir
+}
@@ -0,0 +1,25 @@
+package globalcache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lock acquires an exclusive lock on the file at path, which is created if needed, waiting while another process
+// holds it. The returned function releases the lock.
+func lock(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	if lockErr := lockFile(file); lockErr != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, lockErr)
+	}
+
+	return func() error {
+		return errors.Join(unlockFile(file), file.Close())
+	}, nil
+}
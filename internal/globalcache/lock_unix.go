@@ -0,0 +1,16 @@
+//go:build !windows
+
+package globalcache
+
+import (
+	"os"
+	"syscall"
+)
+
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX) //nolint:gosec // File descriptors fit in int.
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN) //nolint:gosec // File descriptors fit in int.
+}
@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	rulesDir   = "rules"
+	promptsDir = "prompts"
+)
+
+type (
+	// Importer converts hand-written agent files into a preset package in ajisai format.
+	Importer struct {
+		outputDir string
+		force     bool
+	}
+
+	// Result holds the absolute paths of the files written by Importer.
+	Result struct {
+		Rules    []string
+		Prompts  []string
+		Manifest string
+	}
+
+	// FileConflictError is returned when the output files already exist and overwriting is not allowed.
+	FileConflictError struct {
+		Paths []string
+	}
+
+	// ManifestInUseError is returned when the manifest to overwrite also configures a workspace or settings,
+	// which would be lost even with force.
+	ManifestInUseError struct {
+		Path     string
+		Sections []string
+	}
+)
+
+func (e *FileConflictError) Error() string {
+	return fmt.Sprintf("files already exist (use --force to overwrite): %s", strings.Join(e.Paths, ", "))
+}
+
+func (e *FileConflictError) Unwrap() error {
+	return nil
+}
+
+func (e *ManifestInUseError) Error() string {
+	return fmt.Sprintf(
+		"%s has %s, which would be lost by overwriting it (choose another directory with --output)",
+		e.Path,
+		strings.Join(e.Sections, " and "),
+	)
+}
+
+func (e *ManifestInUseError) Unwrap() error {
+	return nil
+}
+
+// New creates an Importer that writes the package into outputDir.
+// If force is true, existing files are overwritten.
+func New(outputDir string, force bool) *Importer {
+	return &Importer{outputDir: outputDir, force: force}
+}
+
+// Import reads the files of the given agent integration and writes them as a package
+// exporting a single `default` preset.
+func (i *Importer) Import(source domain.AgentIntegration, namespace string) (*Result, error) {
+	outputAbsDir, err := utils.ResolveAbsPath(i.outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	preset, err := source.ReadPreset(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent files: %w", err)
+	}
+
+	files := make(map[string][]byte, len(preset.Rules)+len(preset.Prompts))
+	result := &Result{
+		Rules:    make([]string, 0, len(preset.Rules)),
+		Prompts:  make([]string, 0, len(preset.Prompts)),
+		Manifest: resolveManifestPath(outputAbsDir),
+	}
+
+	for _, rule := range preset.Rules {
+		body, marshalErr := rule.MarshalToMarkdown()
+		if marshalErr != nil {
+			return nil, fmt.Errorf("failed to convert rule %s: %w", rule.URI.Path, marshalErr)
+		}
+
+		path := filepath.Join(
+			outputAbsDir,
+			rulesDir,
+			filepath.FromSlash(rule.URI.Path)+domain.RuleInternalExtension,
+		)
+		files[path] = body
+		result.Rules = append(result.Rules, path)
+	}
+
+	for _, prompt := range preset.Prompts {
+		body, marshalErr := prompt.MarshalToMarkdown()
+		if marshalErr != nil {
+			return nil, fmt.Errorf("failed to convert prompt %s: %w", prompt.URI.Path, marshalErr)
+		}
+
+		path := filepath.Join(
+			outputAbsDir,
+			promptsDir,
+			filepath.FromSlash(prompt.URI.Path)+domain.PromptInternalExtension,
+		)
+		files[path] = body
+		result.Prompts = append(result.Prompts, path)
+	}
+
+	if conflictErr := i.checkConflicts(outputAbsDir, files); conflictErr != nil {
+		return nil, conflictErr
+	}
+	if manifestErr := checkManifest(result.Manifest); manifestErr != nil {
+		return nil, manifestErr
+	}
+
+	for path, body := range files {
+		if dirErr := utils.EnsureDir(filepath.Dir(path)); dirErr != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, dirErr)
+		}
+
+		if writeErr := utils.AtomicWriteFile(path, bytes.NewReader(body)); writeErr != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, writeErr)
+		}
+	}
+
+	if manifestErr := writeManifest(result.Manifest); manifestErr != nil {
+		return nil, manifestErr
+	}
+
+	return result, nil
+}
+
+// checkConflicts returns a FileConflictError if any output file already exists and force is disabled.
+func (i *Importer) checkConflicts(outputAbsDir string, files map[string][]byte) error {
+	if i.force {
+		return nil
+	}
+
+	candidates := slices.Collect(maps.Keys(files))
+	candidates = append(candidates, manifestCandidates(outputAbsDir)...)
+	slices.Sort(candidates)
+
+	conflicts := make([]string, 0)
+	for _, path := range candidates {
+		if _, statErr := os.Stat(path); statErr == nil {
+			conflicts = append(conflicts, path)
+		} else if !errors.Is(statErr, os.ErrNotExist) {
+			return statErr
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &FileConflictError{Paths: conflicts}
+	}
+
+	return nil
+}
+
+// checkManifest returns a ManifestInUseError if the manifest at path has a workspace or settings section.
+// A manifest that does not exist yet is fine.
+func checkManifest(path string) error {
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read package manifest %s: %w", path, err)
+	}
+
+	var sections map[string]any
+	if unmarshalErr := yaml.Unmarshal(body, &sections); unmarshalErr != nil {
+		return fmt.Errorf("failed to read package manifest %s: %w", path, unmarshalErr)
+	}
+
+	inUse := make([]string, 0)
+	for _, section := range []string{"workspace", "settings"} {
+		if _, exists := sections[section]; exists {
+			inUse = append(inUse, section)
+		}
+	}
+	if len(inUse) > 0 {
+		return &ManifestInUseError{Path: path, Sections: inUse}
+	}
+
+	return nil
+}
+
+func manifestCandidates(outputAbsDir string) []string {
+	return []string{
+		filepath.Join(outputAbsDir, "ajisai.yml"),
+		filepath.Join(outputAbsDir, "ajisai.yaml"),
+	}
+}
+
+// resolveManifestPath returns the existing manifest path in outputAbsDir, or `ajisai.yml` if none exists.
+func resolveManifestPath(outputAbsDir string) string {
+	candidates := manifestCandidates(outputAbsDir)
+	for _, path := range candidates {
+		if _, statErr := os.Stat(path); statErr == nil {
+			return path
+		}
+	}
+
+	return candidates[0]
+}
+
+func writeManifest(path string) error {
+	manager, err := config.NewManager(path)
+	if err != nil {
+		return fmt.Errorf("failed to prepare package manifest: %w", err)
+	}
+
+	manifest := &config.Config{
+		Package: &config.Package{
+			Exports: map[string]config.ExportedPresetDefinition{
+				config.DefaultPresetName: {
+					Prompts: []string{promptsDir + "/**/*.md"},
+					Rules:   []string{rulesDir + "/**/*.md"},
+				},
+			},
+		},
+	}
+
+	if saveErr := manager.Save(manifest); saveErr != nil {
+		return fmt.Errorf("failed to write package manifest %s: %w", path, saveErr)
+	}
+
+	return nil
+}
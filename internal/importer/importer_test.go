@@ -0,0 +1,141 @@
+package importer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+	"github.com/sushichan044/ajisai/internal/importer"
+	"github.com/sushichan044/ajisai/internal/integration"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	writeFiles(t, map[string]string{
+		filepath.Join(tempDir, ".cursor", "rules", "go.mdc"):                "---\nalwaysApply: false\ndescription:\nglobs: **/*.go\n---\n# Go\nUse gofmt.",
+		filepath.Join(tempDir, ".cursor", "rules", "ajisai", "managed.mdc"): "---\nalwaysApply: true\n---\nManaged.",
+		filepath.Join(tempDir, ".cursor", "prompts", "review.md"):           "# Review\nReview the code.",
+	})
+
+	source, err := integration.New(tempDir, integration.NewCursorAdapter())
+	require.NoError(t, err)
+
+	result, err := importer.New(".ai", false).Import(source, "ajisai")
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(tempDir, ".ai")
+	assert.Equal(t, []string{filepath.Join(outputDir, "rules", "go.md")}, result.Rules)
+	assert.Equal(t, []string{filepath.Join(outputDir, "prompts", "review.md")}, result.Prompts)
+	assert.Equal(t, filepath.Join(outputDir, "ajisai.yml"), result.Manifest)
+
+	ruleBody, err := os.ReadFile(filepath.Join(outputDir, "rules", "go.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "---\nattach: glob\nglobs:\n- \"**/*.go\"\n---\n# Go\nUse gofmt.\n", string(ruleBody))
+
+	promptBody, err := os.ReadFile(filepath.Join(outputDir, "prompts", "review.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Review\nReview the code.\n", string(promptBody))
+
+	manager, err := config.NewManager(result.Manifest)
+	require.NoError(t, err)
+	manifest, err := manager.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]config.ExportedPresetDefinition{
+		config.DefaultPresetName: {
+			Prompts: []string{"prompts/**/*.md"},
+			Rules:   []string{"rules/**/*.md"},
+		},
+	}, manifest.Package.Exports)
+}
+
+func TestImporter_Import_Conflict(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	outputDir := filepath.Join(tempDir, ".ai")
+	writeFiles(t, map[string]string{
+		filepath.Join(tempDir, ".github", "instructions", "style.instructions.md"): "---\napplyTo: \"**\"\n---\nNew.",
+		filepath.Join(outputDir, "rules", "style.md"):                              "Existing.",
+	})
+
+	source, err := integration.New(tempDir, integration.NewGitHubCopilotAdapter())
+	require.NoError(t, err)
+
+	t.Run("refuses to overwrite without force", func(t *testing.T) {
+		_, importErr := importer.New(outputDir, false).Import(source, "ajisai")
+
+		var conflictErr *importer.FileConflictError
+		require.ErrorAs(t, importErr, &conflictErr)
+		assert.Equal(t, []string{filepath.Join(outputDir, "rules", "style.md")}, conflictErr.Paths)
+
+		body, readErr := os.ReadFile(filepath.Join(outputDir, "rules", "style.md"))
+		require.NoError(t, readErr)
+		assert.Equal(t, "Existing.", string(body))
+	})
+
+	t.Run("overwrites with force", func(t *testing.T) {
+		_, importErr := importer.New(outputDir, true).Import(source, "ajisai")
+		require.NoError(t, importErr)
+
+		body, readErr := os.ReadFile(filepath.Join(outputDir, "rules", "style.md"))
+		require.NoError(t, readErr)
+		assert.Equal(t, "---\nattach: always\n---\nNew.\n", string(body))
+	})
+}
+
+func TestImporter_Import_ManifestInUse(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	manifest := "settings:\n  namespace: ajisai\nworkspace:\n  integrations:\n    cursor:\n      enabled: true\n"
+	writeFiles(t, map[string]string{
+		filepath.Join(tempDir, ".github", "instructions", "style.instructions.md"): "---\napplyTo: \"**\"\n---\nNew.",
+		filepath.Join(tempDir, "ajisai.yml"):                                       manifest,
+	})
+
+	source, err := integration.New(tempDir, integration.NewGitHubCopilotAdapter())
+	require.NoError(t, err)
+
+	_, importErr := importer.New(tempDir, true).Import(source, "ajisai")
+
+	var inUseErr *importer.ManifestInUseError
+	require.ErrorAs(t, importErr, &inUseErr)
+	assert.Equal(t, filepath.Join(tempDir, "ajisai.yml"), inUseErr.Path)
+	assert.Equal(t, []string{"workspace", "settings"}, inUseErr.Sections)
+
+	body, readErr := os.ReadFile(filepath.Join(tempDir, "ajisai.yml"))
+	require.NoError(t, readErr)
+	assert.Equal(t, manifest, string(body))
+	assert.NoDirExists(t, filepath.Join(tempDir, "rules"))
+}
+
+type failingIntegration struct {
+	domain.AgentIntegration
+}
+
+func (f *failingIntegration) ReadPreset(_ string) (*domain.AgentPreset, error) {
+	return nil, os.ErrPermission
+}
+
+func TestImporter_Import_ReadFailure(t *testing.T) {
+	_, err := importer.New(t.TempDir(), false).Import(&failingIntegration{}, "ajisai")
+
+	require.ErrorIs(t, err, os.ErrPermission)
+	assert.ErrorContains(t, err, "failed to read agent files")
+}
@@ -56,3 +56,31 @@ func (adapter *cursorAdapter) SerializePrompt(prompt *domain.PromptItem) (string
 
 	return adapter.bridge.SerializeAgentPrompt(cursorPrompt)
 }
+
+func (adapter *cursorAdapter) DeserializeRule(slug string, body string) (*domain.RuleItem, error) {
+	cursorRule, err := adapter.bridge.DeserializeAgentRule(slug, body)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := adapter.bridge.FromAgentRule(cursorRule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (adapter *cursorAdapter) DeserializePrompt(slug string, body string) (*domain.PromptItem, error) {
+	cursorPrompt, err := adapter.bridge.DeserializeAgentPrompt(slug, body)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := adapter.bridge.FromAgentPrompt(cursorPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prompt, nil
+}
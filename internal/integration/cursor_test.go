@@ -60,3 +60,32 @@ func TestCursorAdapter_SerializePrompt(t *testing.T) {
 	assert.NotEmpty(t, serialized, "Serialized prompt should not be empty")
 	assert.Contains(t, serialized, "# Test Prompt", "Serialized prompt should include original content")
 }
+
+func TestCursorAdapter_DeserializeRule(t *testing.T) {
+	// Setup
+	adapter := integration.NewCursorAdapter()
+	body := "---\nalwaysApply: false\ndescription:\nglobs: **/*.go,**/*.mod\n---\n# Go Rule\nUse gofmt."
+
+	// Execute
+	rule, err := adapter.DeserializeRule("go/style", body)
+
+	// Verify
+	require.NoError(t, err, "DeserializeRule should not return error")
+	assert.Equal(t, "go/style", rule.URI.Path)
+	assert.Equal(t, domain.AttachTypeGlob, rule.Metadata.Attach)
+	assert.Equal(t, []string{"**/*.go", "**/*.mod"}, rule.Metadata.Globs)
+	assert.Contains(t, rule.Content, "Use gofmt.")
+}
+
+func TestCursorAdapter_DeserializePrompt(t *testing.T) {
+	// Setup
+	adapter := integration.NewCursorAdapter()
+
+	// Execute
+	prompt, err := adapter.DeserializePrompt("review", "# Review\nReview the code.")
+
+	// Verify
+	require.NoError(t, err, "DeserializePrompt should not return error")
+	assert.Equal(t, "review", prompt.URI.Path)
+	assert.Equal(t, "Review", prompt.Metadata.Description)
+}
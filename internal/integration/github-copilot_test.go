@@ -60,3 +60,32 @@ func TestGitHubCopilotAdapter_SerializePrompt(t *testing.T) {
 	assert.NotEmpty(t, serialized, "Serialized prompt should not be empty")
 	assert.Contains(t, serialized, "# Test Prompt", "Serialized prompt should include original content")
 }
+
+func TestGitHubCopilotAdapter_DeserializeRule(t *testing.T) {
+	// Setup
+	adapter := integration.NewGitHubCopilotAdapter()
+	body := "---\napplyTo: \"**\"\n---\n# Always Rule\nAlways follow this."
+
+	// Execute
+	rule, err := adapter.DeserializeRule("always", body)
+
+	// Verify
+	require.NoError(t, err, "DeserializeRule should not return error")
+	assert.Equal(t, "always", rule.URI.Path)
+	assert.Equal(t, domain.AttachTypeAlways, rule.Metadata.Attach)
+	assert.Contains(t, rule.Content, "Always follow this.")
+}
+
+func TestGitHubCopilotAdapter_DeserializePrompt(t *testing.T) {
+	// Setup
+	adapter := integration.NewGitHubCopilotAdapter()
+	body := "---\ndescription: Review code\nmode: agent\n---\nReview the code."
+
+	// Execute
+	prompt, err := adapter.DeserializePrompt("review", body)
+
+	// Verify
+	require.NoError(t, err, "DeserializePrompt should not return error")
+	assert.Equal(t, "review", prompt.URI.Path)
+	assert.Equal(t, "Review code", prompt.Metadata.Description)
+}
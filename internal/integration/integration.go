@@ -3,11 +3,14 @@ package integration
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/sushichan044/ajisai/internal/config"
 	"github.com/sushichan044/ajisai/internal/domain"
 	"github.com/sushichan044/ajisai/utils"
 )
@@ -37,6 +40,10 @@ type agentSpecificationAdapter interface {
 	SerializeRule(rule *domain.RuleItem) (string, error)
 
 	SerializePrompt(prompt *domain.PromptItem) (string, error)
+
+	DeserializeRule(slug string, body string) (*domain.RuleItem, error)
+
+	DeserializePrompt(slug string, body string) (*domain.PromptItem, error)
 }
 
 type integrationImpl struct {
@@ -46,14 +53,15 @@ type integrationImpl struct {
 	resolvedPromptsRootDir string
 }
 
-func New(adapter agentSpecificationAdapter) (domain.AgentIntegration, error) {
-	cwd, err := os.Getwd()
+// New creates an integration that reads and writes the agent files under projectRoot.
+func New(projectRoot string, adapter agentSpecificationAdapter) (domain.AgentIntegration, error) {
+	absProjectRoot, err := utils.ResolveAbsPath(projectRoot)
 	if err != nil {
 		return nil, err
 	}
 
-	resolvedRulesRootDir := filepath.Join(cwd, filepath.FromSlash(adapter.RulesDir()))
-	resolvedPromptsRootDir := filepath.Join(cwd, filepath.FromSlash(adapter.PromptsDir()))
+	resolvedRulesRootDir := filepath.Join(absProjectRoot, filepath.FromSlash(adapter.RulesDir()))
+	resolvedPromptsRootDir := filepath.Join(absProjectRoot, filepath.FromSlash(adapter.PromptsDir()))
 
 	return &integrationImpl{
 		adapter:                adapter,
@@ -172,6 +180,101 @@ func (repo *integrationImpl) ensureGitignoreFiles(namespace string) error {
 	return eg.Wait()
 }
 
+func (repo *integrationImpl) ReadPreset(namespace string) (*domain.AgentPreset, error) {
+	rules, ruleErr := readAgentFiles(
+		repo.resolvedRulesRootDir,
+		namespace,
+		repo.adapter.RuleExtension(),
+		repo.adapter.DeserializeRule,
+	)
+	if ruleErr != nil {
+		return nil, fmt.Errorf("could not read rules from %s: %w", repo.resolvedRulesRootDir, ruleErr)
+	}
+
+	prompts, promptErr := readAgentFiles(
+		repo.resolvedPromptsRootDir,
+		namespace,
+		repo.adapter.PromptExtension(),
+		repo.adapter.DeserializePrompt,
+	)
+	if promptErr != nil {
+		return nil, fmt.Errorf("could not read prompts from %s: %w", repo.resolvedPromptsRootDir, promptErr)
+	}
+
+	return &domain.AgentPreset{
+		Name:    config.DefaultPresetName,
+		Rules:   rules,
+		Prompts: prompts,
+	}, nil
+}
+
+// readAgentFiles walks rootDir and deserializes every file with the given extension.
+// The slug of each item is its path relative to rootDir without the extension.
+func readAgentFiles[T any](
+	rootDir, namespace, extension string,
+	deserialize func(slug string, body string) (T, error),
+) ([]T, error) {
+	items := make([]T, 0)
+
+	exists, err := utils.IsDirExists(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return items, nil
+	}
+
+	namespaceDir := filepath.Join(rootDir, namespace)
+
+	walkErr := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if namespace != "" && path == namespaceDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(d.Name(), extension) {
+			return nil
+		}
+
+		relPath, relErr := filepath.Rel(rootDir, path)
+		if relErr != nil {
+			return relErr
+		}
+		slug := strings.TrimSuffix(filepath.ToSlash(relPath), extension)
+
+		body, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return fmt.Errorf("could not read %s: %w", path, readErr)
+		}
+
+		item, deserializeErr := deserialize(slug, string(body))
+		if deserializeErr != nil {
+			return fmt.Errorf("could not convert %s: %w", path, deserializeErr)
+		}
+
+		items = append(items, item)
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	return items, nil
+}
+
+func (repo *integrationImpl) OutputDirs(namespace string) []string {
+	return []string{
+		filepath.Join(repo.resolvedRulesRootDir, namespace),
+		filepath.Join(repo.resolvedPromptsRootDir, namespace),
+	}
+}
+
 func (repo *integrationImpl) Clean(namespace string) error {
 	ruleDir := filepath.Join(repo.resolvedRulesRootDir, namespace)
 	promptDir := filepath.Join(repo.resolvedPromptsRootDir, namespace)
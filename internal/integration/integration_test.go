@@ -54,12 +54,27 @@ func (m *mockFileAdapter) SerializePrompt(prompt *domain.PromptItem) (string, er
 	return "---\ndescription: " + prompt.Metadata.Description + "\n---\n" + prompt.Content, nil
 }
 
+func (m *mockFileAdapter) DeserializeRule(slug string, body string) (*domain.RuleItem, error) {
+	return domain.NewRuleItem(
+		domain.NewPlaceholderURI(slug, domain.RulesPresetType),
+		body,
+		domain.RuleMetadata{Attach: domain.AttachTypeManual},
+	), nil
+}
+
+func (m *mockFileAdapter) DeserializePrompt(slug string, body string) (*domain.PromptItem, error) {
+	return domain.NewPromptItem(
+		domain.NewPlaceholderURI(slug, domain.PromptsPresetType),
+		body,
+		domain.PromptMetadata{},
+	), nil
+}
+
 func TestWritePreset(t *testing.T) {
 	tempDir := t.TempDir()
-	t.Chdir(tempDir)
 
 	adapter := newMockFileAdapter()
-	repo, err := integration.New(adapter)
+	repo, err := integration.New(tempDir, adapter)
 	require.NoError(t, err)
 
 	preset := domain.AgentPreset{
@@ -132,10 +147,9 @@ func TestWritePreset(t *testing.T) {
 
 func TestClean(t *testing.T) {
 	tempDir := t.TempDir()
-	t.Chdir(tempDir)
 
 	adapter := newMockFileAdapter()
-	repo, err := integration.New(adapter)
+	repo, err := integration.New(tempDir, adapter)
 	require.NoError(t, err)
 
 	testNamespace := "test-namespace"
@@ -175,10 +189,9 @@ func TestClean(t *testing.T) {
 
 func TestEnsureGitignoreFiles(t *testing.T) {
 	tempDir := t.TempDir()
-	t.Chdir(tempDir)
 
 	adapter := newMockFileAdapter()
-	repo, err := integration.New(adapter)
+	repo, err := integration.New(tempDir, adapter)
 	require.NoError(t, err)
 
 	// Create empty package to trigger gitignore creation
@@ -219,6 +232,55 @@ func TestEnsureGitignoreFiles(t *testing.T) {
 	require.NoError(t, err, "Prompts namespace directory should exist")
 }
 
+func TestReadPreset(t *testing.T) {
+	tempDir := t.TempDir()
+
+	adapter := newMockFileAdapter()
+	repo, err := integration.New(tempDir, adapter)
+	require.NoError(t, err)
+
+	rulesDir := filepath.Join(tempDir, adapter.RulesDir())
+	promptsDir := filepath.Join(tempDir, adapter.PromptsDir())
+
+	files := map[string]string{
+		filepath.Join(rulesDir, "go-style"+adapter.RuleExtension()):                      "go style",
+		filepath.Join(rulesDir, "frontend", "react"+adapter.RuleExtension()):             "react",
+		filepath.Join(rulesDir, "notes.txt"):                                             "not a rule",
+		filepath.Join(rulesDir, "test-namespace", "managed"+adapter.RuleExtension()):     "managed by ajisai",
+		filepath.Join(promptsDir, "refactor"+adapter.PromptExtension()):                  "refactor",
+		filepath.Join(promptsDir, "test-namespace", "managed"+adapter.PromptExtension()): "managed by ajisai",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	preset, err := repo.ReadPreset("test-namespace")
+	require.NoError(t, err)
+
+	ruleSlugs := make([]string, 0, len(preset.Rules))
+	for _, rule := range preset.Rules {
+		ruleSlugs = append(ruleSlugs, rule.URI.Path)
+	}
+	assert.ElementsMatch(t, []string{"go-style", "frontend/react"}, ruleSlugs)
+
+	require.Len(t, preset.Prompts, 1)
+	assert.Equal(t, "refactor", preset.Prompts[0].URI.Path)
+	assert.Equal(t, "refactor", preset.Prompts[0].Content)
+}
+
+func TestReadPreset_NoAgentDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+
+	repo, err := integration.New(tempDir, newMockFileAdapter())
+	require.NoError(t, err)
+
+	preset, err := repo.ReadPreset("test-namespace")
+	require.NoError(t, err)
+	assert.Empty(t, preset.Rules)
+	assert.Empty(t, preset.Prompts)
+}
+
 // Note: Directory creation failure tests would require complex mocking of the filesystem
 // which would significantly complicate the test setup. The error paths for utils.EnsureDir
 // failures are covered by the utils package tests and the error handling logic is
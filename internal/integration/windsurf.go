@@ -56,3 +56,31 @@ func (adapter *windsurfAdapter) SerializePrompt(prompt *domain.PromptItem) (stri
 
 	return adapter.bridge.SerializeAgentPrompt(agentPrompt)
 }
+
+func (adapter *windsurfAdapter) DeserializeRule(slug string, body string) (*domain.RuleItem, error) {
+	agentRule, err := adapter.bridge.DeserializeAgentRule(slug, body)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := adapter.bridge.FromAgentRule(agentRule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (adapter *windsurfAdapter) DeserializePrompt(slug string, body string) (*domain.PromptItem, error) {
+	agentPrompt, err := adapter.bridge.DeserializeAgentPrompt(slug, body)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := adapter.bridge.FromAgentPrompt(agentPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prompt, nil
+}
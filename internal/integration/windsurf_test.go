@@ -60,3 +60,29 @@ func TestWindsurfAdapter_SerializePrompt(t *testing.T) {
 	assert.NotEmpty(t, serialized, "Serialized prompt should not be empty")
 	assert.Contains(t, serialized, "# Test Prompt", "Serialized prompt should include original content")
 }
+
+func TestWindsurfAdapter_DeserializeRule(t *testing.T) {
+	// Setup
+	adapter := integration.NewWindsurfAdapter()
+	body := "---\ntrigger: model_decision\ndescription: Use when writing SQL\n---\nPrefer CTEs."
+
+	// Execute
+	rule, err := adapter.DeserializeRule("sql", body)
+
+	// Verify
+	require.NoError(t, err, "DeserializeRule should not return error")
+	assert.Equal(t, "sql", rule.URI.Path)
+	assert.Equal(t, domain.AttachTypeAgentRequested, rule.Metadata.Attach)
+	assert.Equal(t, "Use when writing SQL", rule.Metadata.Description)
+}
+
+func TestWindsurfAdapter_DeserializeRule_UnknownTrigger(t *testing.T) {
+	// Setup
+	adapter := integration.NewWindsurfAdapter()
+
+	// Execute
+	_, err := adapter.DeserializeRule("broken", "---\ntrigger: sometimes\n---\nBody")
+
+	// Verify
+	require.Error(t, err, "DeserializeRule should fail for unknown trigger")
+}
@@ -0,0 +1,343 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/domain"
+)
+
+const frontMatterDelimiter = "---"
+
+type (
+	// FrontMatterIssue is a problem in the front matter of a rule or prompt file.
+	FrontMatterIssue struct {
+		File string
+		// 1-based line in the file.
+		Line    int
+		Message string
+	}
+
+	// FrontMatterError is returned when rule or prompt files have invalid front matter.
+	FrontMatterError struct {
+		Issues []FrontMatterIssue
+	}
+
+	// frontMatter is the parsed front matter block of a Markdown file.
+	frontMatter struct {
+		// Line of the opening delimiter. Zero if the file has no front matter.
+		startLine int
+		entries   map[string]*ast.MappingValueNode
+	}
+)
+
+func (i FrontMatterIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+func (e *FrontMatterError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("invalid front matter:")
+	for _, issue := range e.Issues {
+		b.WriteString("\n  ")
+		b.WriteString(issue.String())
+	}
+
+	return b.String()
+}
+
+func (e *FrontMatterError) Unwrap() error {
+	return nil
+}
+
+// LintPackage checks the front matter of every rule and prompt file exported by the package in dir.
+// It returns a FrontMatterError listing every problem found.
+func LintPackage(dir string) error {
+	pkgManifest, err := resolveManifestInDir(dir, "")
+	if err != nil {
+		return fmt.Errorf("failed to load package manifest in %s: %w", dir, err)
+	}
+
+	return lintPresets(dir, pkgManifest, slices.Sorted(maps.Keys(pkgManifest.Exports)))
+}
+
+func (l *agentPresetLoader) lintIncludedPresets(pkgManifest *config.Package, includes []string) error {
+	rootDir, err := l.cfg.GetImportedPackageRoot(pkgManifest.Name)
+	if err != nil {
+		return err
+	}
+
+	return lintPresets(rootDir, pkgManifest, includes)
+}
+
+// lintPresets checks every file exported by the given presets once.
+// Presets that are not exported are skipped.
+func lintPresets(rootDir string, pkgManifest *config.Package, presetNames []string) error {
+	issues := make([]FrontMatterIssue, 0)
+	visited := make(map[string]bool)
+
+	lintFiles := func(globs []string, extension string, lint func(path string, body []byte) []FrontMatterIssue) error {
+		for _, glob := range globs {
+			walkErr := walkExportedFiles(rootDir, glob, extension, func(_, fullPath string) error {
+				if visited[fullPath] {
+					return nil
+				}
+				visited[fullPath] = true
+
+				body, readErr := os.ReadFile(fullPath)
+				if readErr != nil {
+					return fmt.Errorf("failed to read %s: %w", fullPath, readErr)
+				}
+
+				issues = append(issues, lint(fullPath, body)...)
+				return nil
+			})
+			if walkErr != nil {
+				return fmt.Errorf("glob failed for %s: %w", glob, walkErr)
+			}
+		}
+
+		return nil
+	}
+
+	for _, presetName := range presetNames {
+		exports, isExported := pkgManifest.Exports[presetName]
+		if !isExported {
+			continue
+		}
+
+		if err := lintFiles(exports.Rules, domain.RuleInternalExtension, lintRuleFile); err != nil {
+			return err
+		}
+		if err := lintFiles(exports.Prompts, domain.PromptInternalExtension, lintPromptFile); err != nil {
+			return err
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(issues, func(a, b FrontMatterIssue) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+
+	return &FrontMatterError{Issues: issues}
+}
+
+// lintRuleFile checks that the front matter only has known keys, `attach` is a valid AttachType
+// and the fields required by the attach type are present.
+func lintRuleFile(path string, body []byte) []FrontMatterIssue {
+	fm, issues := parseFrontMatter(path, body)
+	if fm == nil {
+		return issues
+	}
+
+	issues = append(issues, fm.unknownKeys(path, "attach", "description", "globs")...)
+	issues = append(issues, fm.stringField(path, "description")...)
+
+	attach, hasAttach := fm.entries["attach"]
+	if !hasAttach {
+		// Point at the opening delimiter, or the first line if there is no front matter.
+		return append(issues, FrontMatterIssue{File: path, Line: max(fm.startLine, 1), Message: "`attach` is required"})
+	}
+
+	attachType := domain.AttachType(scalarValue(attach.Value))
+	switch attachType {
+	case domain.AttachTypeGlob:
+		if globs, hasGlobs := fm.entries["globs"]; !hasGlobs {
+			issues = append(issues, fm.issueAt(path, attach, "`globs` is required when `attach` is `glob`"))
+		} else if !isStringSequence(globs.Value) {
+			issues = append(issues, fm.issueAt(path, globs, "`globs` must be a list of strings"))
+		}
+	case domain.AttachTypeAgentRequested:
+		if scalarValue(fm.valueOf("description")) == "" {
+			issues = append(
+				issues,
+				fm.issueAt(path, attach, "`description` is required when `attach` is `agent-requested`"),
+			)
+		}
+	case domain.AttachTypeAlways, domain.AttachTypeManual:
+		// No additional fields are required.
+	default:
+		issues = append(issues, fm.issueAt(path, attach, fmt.Sprintf(
+			"invalid `attach` value %q (supported: %s, %s, %s, %s)",
+			attachType,
+			domain.AttachTypeAlways,
+			domain.AttachTypeGlob,
+			domain.AttachTypeAgentRequested,
+			domain.AttachTypeManual,
+		)))
+	}
+
+	return issues
+}
+
+// lintPromptFile checks that the front matter of a prompt only has known keys.
+// Front matter is optional for prompts.
+func lintPromptFile(path string, body []byte) []FrontMatterIssue {
+	fm, issues := parseFrontMatter(path, body)
+	if fm == nil || fm.startLine == 0 {
+		return issues
+	}
+
+	issues = append(issues, fm.unknownKeys(path, "description")...)
+	issues = append(issues, fm.stringField(path, "description")...)
+
+	return issues
+}
+
+// parseFrontMatter parses the YAML front matter at the top of body.
+// It returns a nil frontMatter if the front matter cannot be parsed.
+func parseFrontMatter(path string, body []byte) (*frontMatter, []FrontMatterIssue) {
+	fm := &frontMatter{entries: make(map[string]*ast.MappingValueNode)}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	var yamlBody strings.Builder
+	closed := false
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if line == 1 {
+			if text != frontMatterDelimiter {
+				// No front matter.
+				return fm, nil
+			}
+			fm.startLine = line
+			continue
+		}
+
+		if text == frontMatterDelimiter {
+			closed = true
+			break
+		}
+		yamlBody.WriteString(text)
+		yamlBody.WriteString("\n")
+	}
+
+	if fm.startLine == 0 {
+		// Empty file.
+		return fm, nil
+	}
+
+	if !closed {
+		return nil, []FrontMatterIssue{
+			{File: path, Line: fm.startLine, Message: "front matter is not closed with `---`"},
+		}
+	}
+
+	file, err := parser.ParseBytes([]byte(yamlBody.String()), 0)
+	if err != nil {
+		return nil, []FrontMatterIssue{
+			{File: path, Line: fm.startLine, Message: "front matter is not valid YAML: " + err.Error()},
+		}
+	}
+
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return fm, nil
+	}
+
+	mapNode, isMap := file.Docs[0].Body.(ast.MapNode)
+	if !isMap {
+		return nil, []FrontMatterIssue{{File: path, Line: fm.startLine, Message: "front matter must be a mapping"}}
+	}
+
+	entries := mapNode.MapRange()
+	for entries.Next() {
+		fm.entries[entries.Key().GetToken().Value] = entries.KeyValue()
+	}
+
+	return fm, nil
+}
+
+func (fm *frontMatter) unknownKeys(path string, known ...string) []FrontMatterIssue {
+	issues := make([]FrontMatterIssue, 0)
+
+	for _, key := range slices.Sorted(maps.Keys(fm.entries)) {
+		if !slices.Contains(known, key) {
+			issues = append(issues, fm.issueAt(path, fm.entries[key], fmt.Sprintf(
+				"unknown key %q (supported: %s)",
+				key,
+				strings.Join(known, ", "),
+			)))
+		}
+	}
+
+	return issues
+}
+
+func (fm *frontMatter) stringField(path string, key string) []FrontMatterIssue {
+	entry, exists := fm.entries[key]
+	if !exists {
+		return nil
+	}
+
+	switch entry.Value.Type() { //nolint:exhaustive // Only string-like types are accepted.
+	case ast.StringType, ast.LiteralType, ast.NullType:
+		return nil
+	}
+
+	return []FrontMatterIssue{fm.issueAt(path, entry, fmt.Sprintf("`%s` must be a string", key))}
+}
+
+func (fm *frontMatter) valueOf(key string) ast.Node {
+	entry, exists := fm.entries[key]
+	if !exists {
+		return nil
+	}
+
+	return entry.Value
+}
+
+// issueAt creates an issue at the line of entry's key in the file.
+func (fm *frontMatter) issueAt(path string, entry *ast.MappingValueNode, message string) FrontMatterIssue {
+	// Lines in the front matter are counted from the line after the opening delimiter.
+	return FrontMatterIssue{
+		File:    path,
+		Line:    fm.startLine + entry.Key.GetToken().Position.Line,
+		Message: message,
+	}
+}
+
+func scalarValue(node ast.Node) string {
+	if node == nil || node.Type() == ast.NullType {
+		return ""
+	}
+
+	if literal, isLiteral := node.(*ast.LiteralNode); isLiteral {
+		return literal.Value.Value
+	}
+
+	if _, isScalar := node.(ast.ScalarNode); !isScalar {
+		return ""
+	}
+
+	return node.GetToken().Value
+}
+
+func isStringSequence(node ast.Node) bool {
+	sequence, isSequence := node.(*ast.SequenceNode)
+	if !isSequence {
+		return false
+	}
+
+	for _, value := range sequence.Values {
+		if value.Type() != ast.StringType {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package loader_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/loader"
+)
+
+func writePackageFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0750))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0600))
+	}
+}
+
+func TestLintPackage(t *testing.T) {
+	t.Run("valid package", func(t *testing.T) {
+		dir := t.TempDir()
+		writePackageFiles(t, dir, map[string]string{
+			"rules/always.md":    "---\nattach: always\n---\n# Always\n",
+			"rules/glob.md":      "---\nattach: glob\nglobs:\n  - \"**/*.go\"\n---\n# Go\n",
+			"rules/requested.md": "---\nattach: agent-requested\ndescription: |\n  Use when testing.\n---\n# Test\n",
+			"prompts/plain.md":   "# Plain prompt without front matter\n",
+			"prompts/desc.md":    "---\ndescription: Review\n---\n# Review\n",
+		})
+
+		assert.NoError(t, loader.LintPackage(dir))
+	})
+
+	t.Run("reports every problem with lines", func(t *testing.T) {
+		dir := t.TempDir()
+		writePackageFiles(t, dir, map[string]string{
+			"rules/typo.md":      "---\nattatch: always\n---\n# Typo\n",
+			"rules/invalid.md":   "---\ndescription: x\nattach: sometimes\n---\n",
+			"rules/glob.md":      "---\nattach: glob\n---\n",
+			"rules/globs.md":     "---\nattach: glob\nglobs: \"**/*.go\"\n---\n",
+			"rules/requested.md": "---\n\nattach: agent-requested\n---\n",
+			"rules/unclosed.md":  "---\nattach: always\n",
+			"prompts/extra.md":   "---\ndescription: ok\ntitle: extra\n---\n",
+		})
+
+		var lintErr *loader.FrontMatterError
+		require.ErrorAs(t, loader.LintPackage(dir), &lintErr)
+
+		rule := func(name string) string { return filepath.Join(dir, "rules", name) }
+		assert.Equal(t, []loader.FrontMatterIssue{
+			{
+				File:    filepath.Join(dir, "prompts", "extra.md"),
+				Line:    3,
+				Message: `unknown key "title" (supported: description)`,
+			},
+			{File: rule("glob.md"), Line: 2, Message: "`globs` is required when `attach` is `glob`"},
+			{File: rule("globs.md"), Line: 3, Message: "`globs` must be a list of strings"},
+			{
+				File:    rule("invalid.md"),
+				Line:    3,
+				Message: `invalid ` + "`attach`" + ` value "sometimes" (supported: always, glob, agent-requested, manual)`,
+			},
+			{
+				File:    rule("requested.md"),
+				Line:    3,
+				Message: "`description` is required when `attach` is `agent-requested`",
+			},
+			{File: rule("typo.md"), Line: 1, Message: "`attach` is required"},
+			{File: rule("typo.md"), Line: 2, Message: `unknown key "attatch" (supported: attach, description, globs)`},
+			{File: rule("unclosed.md"), Line: 1, Message: "front matter is not closed with `---`"},
+		}, lintErr.Issues)
+	})
+
+	t.Run("only checks exported files", func(t *testing.T) {
+		dir := t.TempDir()
+		writePackageFiles(t, dir, map[string]string{
+			"ajisai.yml":          "package:\n  exports:\n    go:\n      rules:\n        - go/**/*.md\n",
+			"go/rules/style.md":   "---\nattach: always\n---\n",
+			"other/rules/typo.md": "---\nattach: sometimes\n---\n",
+		})
+
+		assert.NoError(t, loader.LintPackage(dir))
+	})
+}
+
+func TestLoadAgentPresetPackage_Strict(t *testing.T) {
+	cacheDir := t.TempDir()
+	writePackageFiles(t, filepath.Join(cacheDir, "pkg"), map[string]string{
+		"rules/typo.md": "---\nattach: alwasy\n---\n",
+	})
+
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: cacheDir},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"pkg": {Type: config.ImportTypeLocal, Include: []string{config.DefaultPresetName}},
+			},
+		},
+	}
+
+	t.Run("lenient loader accepts invalid front matter", func(t *testing.T) {
+		pkg, err := loader.NewAgentPresetPackageLoader(cfg).LoadAgentPresetPackage("pkg")
+		require.NoError(t, err)
+		require.Len(t, pkg.Presets, 1)
+		assert.Len(t, pkg.Presets[0].Rules, 1)
+	})
+
+	t.Run("strict loader rejects invalid front matter", func(t *testing.T) {
+		_, err := loader.NewStrictAgentPresetPackageLoader(cfg).LoadAgentPresetPackage("pkg")
+
+		var lintErr *loader.FrontMatterError
+		require.ErrorAs(t, err, &lintErr)
+		require.Len(t, lintErr.Issues, 1)
+		assert.Equal(t, filepath.Join(cacheDir, "pkg", "rules", "typo.md")+":2: "+
+			`invalid `+"`attach`"+` value "alwasy" (supported: always, glob, agent-requested, manual)`,
+			lintErr.Issues[0].String())
+	})
+}
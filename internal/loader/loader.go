@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -17,13 +19,20 @@ import (
 )
 
 type agentPresetLoader struct {
-	cfg *config.Config
+	cfg    *config.Config
+	strict bool
 }
 
 func NewAgentPresetPackageLoader(config *config.Config) domain.AgentPresetPackageLoader {
 	return &agentPresetLoader{cfg: config}
 }
 
+// NewStrictAgentPresetPackageLoader creates a loader that fails with a FrontMatterError
+// if any included rule or prompt file has invalid front matter.
+func NewStrictAgentPresetPackageLoader(config *config.Config) domain.AgentPresetPackageLoader {
+	return &agentPresetLoader{cfg: config, strict: true}
+}
+
 func (l *agentPresetLoader) LoadAgentPresetPackage(packageName string) (*domain.AgentPresetPackage, error) {
 	importedPkgCfg, isImported := l.cfg.Workspace.Imports[packageName]
 	if !isImported {
@@ -35,12 +44,18 @@ func (l *agentPresetLoader) LoadAgentPresetPackage(packageName string) (*domain.
 		return nil, fmt.Errorf("failed to load package manifest for %s: %w", packageName, manifestErr)
 	}
 
+	if l.strict {
+		if lintErr := l.lintIncludedPresets(pkgManifest, importedPkgCfg.Include); lintErr != nil {
+			return nil, lintErr
+		}
+	}
+
 	eg := errgroup.Group{}
 	importedPresets := make([]*domain.AgentPreset, 0, len(importedPkgCfg.Include))
 
 	for _, includedPresetName := range importedPkgCfg.Include {
 		if _, isExported := pkgManifest.Exports[includedPresetName]; !isExported {
-			// TODO: log warning
+			// Not fatal here; `ajisai doctor` reports presets that are included but not exported.
 			continue
 		}
 
@@ -66,23 +81,45 @@ func (l *agentPresetLoader) LoadAgentPresetPackage(packageName string) (*domain.
 }
 
 func (l *agentPresetLoader) ResolvePackageManifest(packageName string) (*config.Package, error) {
-	cacheDir, err := l.cfg.GetImportedPackageCacheRoot(packageName)
+	rootDir, err := l.cfg.GetImportedPackageRoot(packageName)
 	if err != nil {
 		return nil, fmt.Errorf("resolve package manifest for %s: %w", packageName, err)
 	}
 
-	manager, err := config.NewDefaultManagerInDir(cacheDir)
+	pkgManifest, err := resolveManifestInDir(rootDir, packageName)
 	if err != nil {
 		return nil, fmt.Errorf("resolve package manifest for %s: %w", packageName, err)
 	}
 
+	return pkgManifest, nil
+}
+
+// resolveManifestInDir reads the package manifest in dir and names the package packageName.
+// If dir has no manifest, the package exports the special `default` preset.
+func resolveManifestInDir(dir string, packageName string) (*config.Package, error) {
+	pkgManifest, err := loadManifestInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgManifest.Name = packageName
+	return pkgManifest, nil
+}
+
+// loadManifestInDir reads the package manifest in dir with the name it declares.
+// If dir has no manifest, the package exports the special `default` preset.
+func loadManifestInDir(dir string) (*config.Package, error) {
+	manager, err := config.NewDefaultManagerInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
 	rawManifest, err := manager.Load()
 	if err != nil {
 		var manifestNotFound *config.NoFileToReadError
 		if errors.As(err, &manifestNotFound) {
 			// manifest file not found, fallback to special `default` preset
 			return &config.Package{
-				Name: packageName,
 				Exports: map[string]config.ExportedPresetDefinition{
 					config.DefaultPresetName: {
 						Prompts: []string{"prompts/**/*.md"},
@@ -92,18 +129,92 @@ func (l *agentPresetLoader) ResolvePackageManifest(packageName string) (*config.
 			}, nil
 		}
 
-		return nil, fmt.Errorf("resolve package manifest for %s: %w", packageName, err)
+		return nil, err
 	}
 
 	return &config.Package{
-		Name:    packageName,
+		Name:    rawManifest.Package.Name,
 		Exports: rawManifest.Package.Exports,
 	}, nil
 }
 
+// ExportedFiles returns the manifest of the package in dir and every rule and prompt file its exports match.
+// Files are returned once each, as sorted slash-separated paths relative to dir.
+func ExportedFiles(dir string) (*config.Package, []string, error) {
+	return IncludedFiles(dir, nil)
+}
+
+// IncludedFiles returns the manifest of the package in dir and the rule and prompt files matched by the given
+// presets, or by every export if presets is nil. Presets the package does not export are skipped.
+// Files are returned once each, as sorted slash-separated paths relative to dir.
+func IncludedFiles(dir string, presets []string) (*config.Package, []string, error) {
+	pkgManifest, err := loadManifestInDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load package manifest in %s: %w", dir, err)
+	}
+
+	if presets == nil {
+		presets = slices.Sorted(maps.Keys(pkgManifest.Exports))
+	}
+
+	files := make([]string, 0)
+	collect := func(globs []string, extension string) error {
+		for _, glob := range globs {
+			walkErr := walkExportedFiles(dir, glob, extension, func(_, fullPath string) error {
+				relPath, relErr := filepath.Rel(dir, fullPath)
+				if relErr != nil {
+					return relErr
+				}
+				files = append(files, filepath.ToSlash(relPath))
+				return nil
+			})
+			if walkErr != nil {
+				return fmt.Errorf("glob failed for %s: %w", glob, walkErr)
+			}
+		}
+
+		return nil
+	}
+
+	for _, name := range presets {
+		exports, isExported := pkgManifest.Exports[name]
+		if !isExported {
+			continue
+		}
+		if rulesErr := collect(exports.Rules, domain.RuleInternalExtension); rulesErr != nil {
+			return nil, nil, rulesErr
+		}
+		if promptsErr := collect(exports.Prompts, domain.PromptInternalExtension); promptsErr != nil {
+			return nil, nil, promptsErr
+		}
+	}
+
+	slices.Sort(files)
+	return pkgManifest, slices.Compact(files), nil
+}
+
+// ManifestFile returns the path of the package manifest in dir, and false if dir has none.
+func ManifestFile(dir string) (string, bool, error) {
+	manager, err := config.NewDefaultManagerInDir(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	manifestPath, err := manager.Path()
+	if err != nil {
+		var manifestNotFound *config.NoFileToReadError
+		if errors.As(err, &manifestNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	return manifestPath, true, nil
+}
+
 // buildPreset scans the source directory for rules and prompts and returns a Preset.
 func (l *agentPresetLoader) buildPreset(pkgManifest *config.Package, presetName string) (*domain.AgentPreset, error) {
-	rootDir, err := l.cfg.GetImportedPackageCacheRoot(pkgManifest.Name)
+	rootDir, err := l.cfg.GetImportedPackageRoot(pkgManifest.Name)
 	if err != nil {
 		return nil, fmt.Errorf("build preset %s: %w", presetName, err)
 	}
@@ -156,20 +267,9 @@ func (l *agentPresetLoader) loadPromptItems(
 	rootDir, packageName, presetName, promptGlob string,
 ) ([]*domain.PromptItem, error) {
 	var loadedPrompts []*domain.PromptItem
-	slashed := filepath.ToSlash(promptGlob)
-	base, glob := doublestar.SplitPattern(slashed)
-	fsys := os.DirFS(filepath.Join(rootDir, base))
 
-	err := doublestar.GlobWalk(fsys, glob, func(path string, d fs.DirEntry) error {
-		if d.IsDir() || !strings.HasSuffix(path, domain.PromptInternalExtension) {
-			return nil
-		}
-
-		// Construct the full path relative to the actual file system for ReadFile
-		// and GetSlugFromBaseDir, as `path` is relative to `fsys`'s root.
-		fullPath := filepath.Join(rootDir, base, path)
-
-		uriPath, pathErr := domain.GetPathFromBaseDir(filepath.Join(rootDir, base), fullPath)
+	err := walkExportedFiles(rootDir, promptGlob, domain.PromptInternalExtension, func(baseDir, fullPath string) error {
+		uriPath, pathErr := domain.GetPathFromBaseDir(baseDir, fullPath)
 		if pathErr != nil {
 			return fmt.Errorf("failed to get path for prompt %s: %w", fullPath, pathErr)
 		}
@@ -211,20 +311,9 @@ func (l *agentPresetLoader) loadRuleItems(
 	rootDir, packageName, presetName, ruleGlob string,
 ) ([]*domain.RuleItem, error) {
 	var loadedRules []*domain.RuleItem
-	slashed := filepath.ToSlash(ruleGlob)
-	base, glob := doublestar.SplitPattern(slashed)
-	fsys := os.DirFS(filepath.Join(rootDir, base))
-
-	err := doublestar.GlobWalk(fsys, glob, func(path string, d fs.DirEntry) error {
-		if d.IsDir() || !strings.HasSuffix(path, domain.RuleInternalExtension) {
-			return nil
-		}
-
-		// Construct the full path relative to the actual file system for ReadFile
-		// and GetSlugFromBaseDir, as `path` is relative to `fsys`'s root.
-		fullPath := filepath.Join(rootDir, base, path)
 
-		uriPath, pathErr := domain.GetPathFromBaseDir(filepath.Join(rootDir, base), fullPath)
+	err := walkExportedFiles(rootDir, ruleGlob, domain.RuleInternalExtension, func(baseDir, fullPath string) error {
+		uriPath, pathErr := domain.GetPathFromBaseDir(baseDir, fullPath)
 		if pathErr != nil {
 			return fmt.Errorf("failed to get path for rule %s: %w", fullPath, pathErr)
 		}
@@ -250,3 +339,21 @@ func (l *agentPresetLoader) loadRuleItems(
 	}
 	return loadedRules, nil
 }
+
+// walkExportedFiles calls fn for every file with the given extension matched by pattern under rootDir.
+// baseDir is the static prefix of pattern joined to rootDir, which item paths are relative to.
+func walkExportedFiles(rootDir, pattern, extension string, fn func(baseDir, fullPath string) error) error {
+	slashed := filepath.ToSlash(pattern)
+	base, glob := doublestar.SplitPattern(slashed)
+	baseDir := filepath.Join(rootDir, base)
+	fsys := os.DirFS(baseDir)
+
+	return doublestar.GlobWalk(fsys, glob, func(path string, d fs.DirEntry) error {
+		if d.IsDir() || !strings.HasSuffix(path, extension) {
+			return nil
+		}
+
+		// Construct the full path relative to the actual file system, as `path` is relative to `fsys`'s root.
+		return fn(baseDir, filepath.Join(baseDir, path))
+	})
+}
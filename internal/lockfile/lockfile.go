@@ -0,0 +1,208 @@
+package lockfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	// FileName is the name of the lockfile written next to the config file.
+	FileName = "ajisai.lock"
+
+	currentVersion = 1
+)
+
+type (
+	// Lockfile records what each import resolved to, so that later applies reproduce the same content.
+	Lockfile struct {
+		Version int              `yaml:"version"`
+		Imports map[string]Entry `yaml:"imports"`
+	}
+
+	// Entry is the resolved state of a single import.
+	Entry struct {
+		Type config.ImportType `yaml:"type"`
+
+		// Repository and Revision are copied from the config of git imports.
+		// The entry is only reused while they are unchanged.
+		Repository string `yaml:"repository,omitempty"`
+		Revision   string `yaml:"revision,omitempty"`
+
+		// Tag a version range revision resolved to. Empty if the revision is not a range.
+		Version string `yaml:"version,omitempty"`
+
+		// Commit SHA the git import was checked out at.
+		Commit string `yaml:"commit,omitempty"`
+
+		// Path is copied from the config of local imports, and of git imports with a subdirectory.
+		Path string `yaml:"path,omitempty"`
+
+		// URL and SHA256 are copied from the config of archive imports.
+		URL    string `yaml:"url,omitempty"`
+		SHA256 string `yaml:"sha256,omitempty"`
+
+		// Ref is copied from the config of OCI imports.
+		Ref string `yaml:"ref,omitempty"`
+
+		// Digest of the fetched content of a local or archive import (see utils.DigestDir),
+		// or digest of the manifest an OCI import was pulled at.
+		Digest string `yaml:"digest,omitempty"`
+
+		// Presets included from the package.
+		Include []string `yaml:"include"`
+	}
+
+	// UnsupportedVersionError is returned when the lockfile was written by a newer version of ajisai.
+	UnsupportedVersionError struct {
+		Path    string
+		Version int
+	}
+)
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("lockfile %s has unsupported version %d (supported: %d)", e.Path, e.Version, currentVersion)
+}
+
+func (e *UnsupportedVersionError) Unwrap() error {
+	return nil
+}
+
+// New creates an empty Lockfile.
+func New() *Lockfile {
+	return &Lockfile{Version: currentVersion, Imports: map[string]Entry{}}
+}
+
+// PathForConfig returns the lockfile path for the given config file path.
+func PathForConfig(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// Load reads the lockfile at path. If the file does not exist, an empty Lockfile is returned.
+func Load(path string) (*Lockfile, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return New(), nil
+		}
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+
+	lock := New()
+	if unmarshalErr := yaml.Unmarshal(body, lock); unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, unmarshalErr)
+	}
+
+	if lock.Version != currentVersion {
+		return nil, &UnsupportedVersionError{Path: path, Version: lock.Version}
+	}
+
+	if lock.Imports == nil {
+		lock.Imports = map[string]Entry{}
+	}
+
+	return lock, nil
+}
+
+// Save writes the lockfile to path.
+func (l *Lockfile) Save(path string) error {
+	body, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	header := []byte("# This file is generated by ajisai. Do not edit it manually.\n")
+	if writeErr := utils.AtomicWriteFile(path, bytes.NewReader(append(header, body...))); writeErr != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", path, writeErr)
+	}
+
+	return nil
+}
+
+// PinnedCommit returns the commit recorded for a git import, if the entry still matches its config.
+func (l *Lockfile) PinnedCommit(name string, pkg config.ImportedPackage) (string, bool) {
+	entry, exists := l.Imports[name]
+	if !exists || entry.Commit == "" {
+		return "", false
+	}
+
+	details, isGit := config.GetImportDetails[config.GitImportDetails](pkg)
+	if !isGit || entry.Type != pkg.Type {
+		return "", false
+	}
+
+	if entry.Repository != details.Repository || entry.Revision != details.Revision {
+		return "", false
+	}
+
+	return entry.Commit, true
+}
+
+// PinnedDigest returns the manifest digest recorded for an OCI import, if the entry still matches its config.
+func (l *Lockfile) PinnedDigest(name string, pkg config.ImportedPackage) (string, bool) {
+	entry, exists := l.Imports[name]
+	if !exists || entry.Digest == "" {
+		return "", false
+	}
+
+	details, isOCI := config.GetImportDetails[config.OCIImportDetails](pkg)
+	if !isOCI || entry.Type != pkg.Type || entry.Ref != details.Ref {
+		return "", false
+	}
+
+	return entry.Digest, true
+}
+
+// NewEntry creates an entry for the import resolved to the given commit SHA or content digest.
+func NewEntry(pkg config.ImportedPackage, resolved string) Entry {
+	entry := Entry{
+		Type:    pkg.Type,
+		Include: slices.Clone(pkg.Include),
+	}
+
+	switch pkg.Type {
+	case config.ImportTypeGit:
+		details, _ := config.GetImportDetails[config.GitImportDetails](pkg)
+		entry.Repository = details.Repository
+		entry.Revision = details.Revision
+		entry.Path = details.Path
+		entry.Commit = resolved
+	case config.ImportTypeLocal:
+		details, _ := config.GetImportDetails[config.LocalImportDetails](pkg)
+		entry.Path = details.Path
+		entry.Digest = resolved
+	case config.ImportTypeArchive:
+		details, _ := config.GetImportDetails[config.ArchiveImportDetails](pkg)
+		entry.URL = details.URL
+		entry.SHA256 = details.SHA256
+		entry.Digest = resolved
+	case config.ImportTypeOCI:
+		details, _ := config.GetImportDetails[config.OCIImportDetails](pkg)
+		entry.Ref = details.Ref
+		entry.Digest = resolved
+	}
+
+	return entry
+}
+
+// Matches reports whether the entry was resolved from pkg as it is configured now, including the presets
+// it includes. What the import resolved to is not compared.
+func (e Entry) Matches(pkg config.ImportedPackage) bool {
+	expected := NewEntry(pkg, "")
+	return e.Type == expected.Type &&
+		e.Repository == expected.Repository &&
+		e.Revision == expected.Revision &&
+		e.Path == expected.Path &&
+		e.URL == expected.URL &&
+		e.SHA256 == expected.SHA256 &&
+		e.Ref == expected.Ref &&
+		slices.Equal(e.Include, expected.Include)
+}
@@ -0,0 +1,153 @@
+package lockfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+)
+
+func gitImport(repository, revision string) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeGit,
+		Include: []string{config.DefaultPresetName},
+		Details: config.GitImportDetails{Repository: repository, Revision: revision},
+	}
+}
+
+func TestPathForConfig(t *testing.T) {
+	got := lockfile.PathForConfig(filepath.Join("project", "ajisai.yml"))
+
+	assert.Equal(t, filepath.Join("project", "ajisai.lock"), got)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	lock, err := lockfile.Load(filepath.Join(t.TempDir(), lockfile.FileName))
+
+	require.NoError(t, err)
+	assert.Empty(t, lock.Imports)
+}
+
+func TestLoad_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockfile.FileName)
+	require.NoError(t, os.WriteFile(path, []byte("version: 99\nimports: {}\n"), 0600))
+
+	_, err := lockfile.Load(path)
+
+	var versionErr *lockfile.UnsupportedVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, 99, versionErr.Version)
+}
+
+func TestLockfile_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockfile.FileName)
+
+	lock := lockfile.New()
+	lock.Imports["remote"] = lockfile.NewEntry(gitImport("https://github.com/example/repo.git", "main"), "abc123")
+	lock.Imports["local"] = lockfile.NewEntry(config.ImportedPackage{
+		Type:    config.ImportTypeLocal,
+		Include: []string{config.DefaultPresetName},
+		Details: config.LocalImportDetails{Path: "./presets"},
+	}, "sha256:def456")
+
+	require.NoError(t, lock.Save(path))
+
+	loaded, err := lockfile.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, lock, loaded)
+	assert.Equal(t, lockfile.Entry{
+		Type:       config.ImportTypeGit,
+		Repository: "https://github.com/example/repo.git",
+		Revision:   "main",
+		Commit:     "abc123",
+		Include:    []string{config.DefaultPresetName},
+	}, loaded.Imports["remote"])
+	assert.Equal(t, "sha256:def456", loaded.Imports["local"].Digest)
+}
+
+func TestLockfile_PinnedCommit(t *testing.T) {
+	lock := lockfile.New()
+	lock.Imports["remote"] = lockfile.NewEntry(gitImport("https://github.com/example/repo.git", "main"), "abc123")
+
+	tests := []struct {
+		name       string
+		importName string
+		pkg        config.ImportedPackage
+		wantCommit string
+		wantPinned bool
+	}{
+		{
+			name:       "matching entry",
+			importName: "remote",
+			pkg:        gitImport("https://github.com/example/repo.git", "main"),
+			wantCommit: "abc123",
+			wantPinned: true,
+		},
+		{
+			name:       "revision changed",
+			importName: "remote",
+			pkg:        gitImport("https://github.com/example/repo.git", "v2"),
+		},
+		{
+			name:       "repository changed",
+			importName: "remote",
+			pkg:        gitImport("https://github.com/example/other.git", "main"),
+		},
+		{
+			name:       "not locked",
+			importName: "other",
+			pkg:        gitImport("https://github.com/example/repo.git", "main"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, pinned := lock.PinnedCommit(tt.importName, tt.pkg)
+
+			assert.Equal(t, tt.wantPinned, pinned)
+			assert.Equal(t, tt.wantCommit, commit)
+		})
+	}
+}
+
+func TestLockfile_PinnedDigest(t *testing.T) {
+	ociImport := func(ref string) config.ImportedPackage {
+		return config.ImportedPackage{
+			Type:    config.ImportTypeOCI,
+			Include: []string{"default"},
+			Details: config.OCIImportDetails{Ref: ref},
+		}
+	}
+
+	lock := lockfile.New()
+	lock.Imports["presets"] = lockfile.NewEntry(ociImport("registry.example.com/ai/presets:1.0.0"), "sha256:abc")
+
+	digest, pinned := lock.PinnedDigest("presets", ociImport("registry.example.com/ai/presets:1.0.0"))
+	assert.True(t, pinned)
+	assert.Equal(t, "sha256:abc", digest)
+
+	_, pinned = lock.PinnedDigest("presets", ociImport("registry.example.com/ai/presets:2.0.0"))
+	assert.False(t, pinned)
+
+	_, pinned = lock.PinnedDigest("presets", gitImport("https://github.com/example/repo.git", "main"))
+	assert.False(t, pinned)
+}
+
+func TestEntry_Matches(t *testing.T) {
+	pkg := gitImport("https://github.com/example/presets.git", "main")
+	entry := lockfile.NewEntry(pkg, "4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+	entry.Version = "v1.0.0"
+
+	assert.True(t, entry.Matches(pkg))
+	assert.False(t, entry.Matches(gitImport("https://github.com/example/presets.git", "v2")))
+	assert.False(t, entry.Matches(gitImport("https://github.com/example/other.git", "main")))
+
+	moreIncluded := pkg
+	moreIncluded.Include = []string{config.DefaultPresetName, "go"}
+	assert.False(t, entry.Matches(moreIncluded))
+}
@@ -0,0 +1,409 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// ManifestMediaType is the media type of the manifests ajisai pushes and accepts.
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+	// ArtifactType identifies a manifest as an ajisai package.
+	ArtifactType = "application/vnd.ajisai.package.v1"
+
+	// LayerMediaType is the media type of layers holding a gzip-compressed tarball of the package files.
+	LayerMediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// EmptyConfigMediaType is the media type of the empty config blob of artifacts.
+	EmptyConfigMediaType = "application/vnd.oci.empty.v1+json"
+
+	maxManifestSize = 4 << 20
+)
+
+type (
+	// Client talks to OCI registries.
+	// Registries that require a token are supported through the bearer token challenge of the distribution API.
+	Client struct {
+		httpClient  *http.Client
+		credentials CredentialStore
+
+		mu     sync.Mutex
+		tokens map[string]string
+	}
+
+	// Descriptor describes a blob referenced by a manifest.
+	Descriptor struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Size        int64             `json:"size"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	// Manifest is an OCI image manifest.
+	Manifest struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		MediaType     string            `json:"mediaType"`
+		ArtifactType  string            `json:"artifactType,omitempty"`
+		Config        Descriptor        `json:"config"`
+		Layers        []Descriptor      `json:"layers"`
+		Annotations   map[string]string `json:"annotations,omitempty"`
+	}
+
+	// DigestMismatchError is returned when downloaded content does not match its digest.
+	DigestMismatchError struct {
+		Expected string
+		Actual   string
+	}
+
+	// RegistryError is returned when the registry responds with an unexpected status.
+	RegistryError struct {
+		Method     string
+		URL        string
+		StatusCode int
+		Body       string
+	}
+)
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("digest mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+func (e *DigestMismatchError) Unwrap() error {
+	return nil
+}
+
+func (e *RegistryError) Error() string {
+	message := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		message += ": " + e.Body
+	}
+
+	return message
+}
+
+func (e *RegistryError) Unwrap() error {
+	return nil
+}
+
+// NewClient creates a Client that reads credentials from the Docker config file.
+func NewClient() *Client {
+	return NewClientWithHTTP(http.DefaultClient, NewDockerCredentialStore())
+}
+
+// NewClientWithHTTP creates a Client that sends requests with the given HTTP client and credentials.
+func NewClientWithHTTP(httpClient *http.Client, credentials CredentialStore) *Client {
+	return &Client{httpClient: httpClient, credentials: credentials, tokens: map[string]string{}}
+}
+
+// Digest returns the `sha256:<hex>` digest of content.
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// FetchManifest downloads the manifest ref points to and returns it with its digest.
+// If ref has a digest, the manifest is verified against it.
+func (c *Client) FetchManifest(ref Reference) (*Manifest, string, error) {
+	resp, err := c.do(ref, http.MethodGet, ref.baseURL()+"/manifests/"+ref.identifier(), nil, func(req *http.Request) {
+		req.Header.Set("Accept", ManifestMediaType)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest of %s: %w", ref, err)
+	}
+	if len(body) > maxManifestSize {
+		return nil, "", fmt.Errorf("manifest of %s is too large (over %d bytes)", ref, maxManifestSize)
+	}
+
+	digest := Digest(body)
+	if ref.Digest != "" && digest != ref.Digest {
+		return nil, "", &DigestMismatchError{Expected: ref.Digest, Actual: digest}
+	}
+
+	var manifest Manifest
+	if unmarshalErr := json.Unmarshal(body, &manifest); unmarshalErr != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest of %s: %w", ref, unmarshalErr)
+	}
+
+	if manifest.MediaType != "" && manifest.MediaType != ManifestMediaType {
+		return nil, "", fmt.Errorf("unsupported manifest media type %q of %s", manifest.MediaType, ref)
+	}
+
+	return &manifest, digest, nil
+}
+
+// FetchBlob downloads the blob to w and verifies its size and digest.
+// Content written to w before a verification error must be discarded by the caller.
+func (c *Client) FetchBlob(ref Reference, desc Descriptor, w io.Writer) error {
+	resp, err := c.do(ref, http.MethodGet, ref.baseURL()+"/blobs/"+desc.Digest, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return fmt.Errorf("failed to download blob %s: %w", desc.Digest, err)
+	}
+
+	actual := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if written != desc.Size || actual != desc.Digest {
+		return &DigestMismatchError{Expected: desc.Digest, Actual: actual}
+	}
+
+	return nil
+}
+
+// PushBlob uploads content unless the registry already has it, and returns its descriptor.
+func (c *Client) PushBlob(ref Reference, mediaType string, content []byte) (Descriptor, error) {
+	desc := Descriptor{MediaType: mediaType, Digest: Digest(content), Size: int64(len(content))}
+
+	if head, err := c.do(ref, http.MethodHead, ref.baseURL()+"/blobs/"+desc.Digest, nil, nil); err == nil {
+		head.Body.Close()
+		return desc, nil
+	}
+
+	start, err := c.do(ref, http.MethodPost, ref.baseURL()+"/blobs/uploads/", nil, nil)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	start.Body.Close()
+
+	location, err := start.Location()
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("registry did not return an upload location: %w", err)
+	}
+
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	upload, err := c.do(ref, http.MethodPut, location.String(), content, func(req *http.Request) {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	})
+	if err != nil {
+		return Descriptor{}, err
+	}
+	upload.Body.Close()
+
+	return desc, nil
+}
+
+// PushManifest uploads the manifest to the tag of ref and returns its digest.
+func (c *Client) PushManifest(ref Reference, manifest *Manifest) (string, error) {
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	resp, err := c.do(ref, http.MethodPut, ref.baseURL()+"/manifests/"+ref.identifier(), body, func(req *http.Request) {
+		req.Header.Set("Content-Type", ManifestMediaType)
+	})
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return Digest(body), nil
+}
+
+// PushPackage pushes a package archive built by `ajisai pack` as an artifact to the tag of ref,
+// and returns the digest of its manifest.
+func (c *Client) PushPackage(ref Reference, archive []byte, annotations map[string]string) (string, error) {
+	emptyConfig, err := c.PushBlob(ref, EmptyConfigMediaType, []byte("{}"))
+	if err != nil {
+		return "", fmt.Errorf("failed to push config: %w", err)
+	}
+
+	layer, err := c.PushBlob(ref, LayerMediaType, archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to push package archive: %w", err)
+	}
+
+	return c.PushManifest(ref, &Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        emptyConfig,
+		Layers:        []Descriptor{layer},
+		Annotations:   annotations,
+	})
+}
+
+// do sends a request and returns the response if it succeeded.
+// A 401 response with a bearer challenge is answered with a token, and the request is sent again.
+func (c *Client) do(
+	ref Reference,
+	method string,
+	rawURL string,
+	body []byte,
+	prepare func(*http.Request),
+) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(context.Background(), method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(body))
+
+		if prepare != nil {
+			prepare(req)
+		}
+		c.authorize(ref, req)
+
+		return c.httpClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, rawURL, err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if authErr := c.authenticate(ref, method, challenge); authErr != nil {
+			return nil, authErr
+		}
+
+		if resp, err = send(); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, rawURL, err)
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &RegistryError{Method: method, URL: rawURL, StatusCode: resp.StatusCode, Body: string(message)}
+	}
+
+	return resp, nil
+}
+
+// authorize sets the token or the credentials of the registry on the request.
+func (c *Client) authorize(ref Reference, req *http.Request) {
+	c.mu.Lock()
+	token, hasToken := c.tokens[ref.Registry+"/"+ref.Repository]
+	c.mu.Unlock()
+
+	if hasToken {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+
+	if username, password, found := c.credentials.Credentials(ref.Registry); found {
+		req.SetBasicAuth(username, password)
+	}
+}
+
+// authenticate answers a bearer challenge (`Bearer realm="...",service="...",scope="..."`) with a token
+// from the realm. Basic challenges are answered by the credentials authorize already sends.
+// Without a scope in the challenge, a GET only asks for pull access, so that pulling works with read-only
+// credentials.
+func (c *Client) authenticate(ref Reference, method string, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	if scheme != "bearer" || params["realm"] == "" {
+		return fmt.Errorf("registry %[1]s requires authentication; log in with `docker login %[1]s`", ref.Registry)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		actions := "pull,push"
+		if method == http.MethodGet {
+			actions = "pull"
+		}
+		scope = fmt.Sprintf("repository:%s:%s", ref.Repository, actions)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if username, password, found := c.credentials.Credentials(ref.Registry); found {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get token for %s: %w", ref.Registry, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &RegistryError{Method: http.MethodGet, URL: realm.String(), StatusCode: resp.StatusCode}
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if decodeErr := json.NewDecoder(resp.Body).Decode(&token); decodeErr != nil {
+		return fmt.Errorf("failed to parse token response of %s: %w", ref.Registry, decodeErr)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return errors.New("token response of " + ref.Registry + " has no token")
+	}
+
+	c.mu.Lock()
+	c.tokens[ref.Registry+"/"+ref.Repository] = token.Token
+	c.mu.Unlock()
+
+	return nil
+}
+
+// parseChallenge parses a `WWW-Authenticate` header into its lower-cased scheme and parameters.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return strings.ToLower(scheme), params
+}
@@ -0,0 +1,132 @@
+package oci_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/oci"
+	"github.com/sushichan044/ajisai/internal/oci/ocitest"
+)
+
+type noCredentials struct{}
+
+func (noCredentials) Credentials(string) (string, string, bool) {
+	return "", "", false
+}
+
+func newClient() *oci.Client {
+	return oci.NewClientWithHTTP(http.DefaultClient, noCredentials{})
+}
+
+func TestClient_PushAndPull(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:1.0.0")
+	require.NoError(t, err)
+
+	client := newClient()
+	archive := []byte("package archive")
+
+	pushedDigest, err := client.PushPackage(ref, archive, nil)
+	require.NoError(t, err)
+
+	manifest, digest, err := client.FetchManifest(ref)
+	require.NoError(t, err)
+	assert.Equal(t, pushedDigest, digest)
+	assert.Equal(t, oci.ArtifactType, manifest.ArtifactType)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, oci.LayerMediaType, manifest.Layers[0].MediaType)
+
+	var layer bytes.Buffer
+	require.NoError(t, client.FetchBlob(ref, manifest.Layers[0], &layer))
+	assert.Equal(t, archive, layer.Bytes())
+
+	// The manifest can be pulled by digest as well.
+	_, _, err = client.FetchManifest(ref.WithDigest(digest))
+	require.NoError(t, err)
+}
+
+func TestClient_PushBlob_SkipsExistingBlob(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:1.0.0")
+	require.NoError(t, err)
+
+	client := newClient()
+	_, err = client.PushBlob(ref, oci.LayerMediaType, []byte("layer"))
+	require.NoError(t, err)
+	_, err = client.PushBlob(ref, oci.LayerMediaType, []byte("layer"))
+	require.NoError(t, err)
+
+	digest := oci.Digest([]byte("layer"))
+	assert.Equal(t, []string{
+		"HEAD /v2/ai/presets/blobs/" + digest,
+		"POST /v2/ai/presets/blobs/uploads/",
+		"PUT /upload/ai/presets",
+		"HEAD /v2/ai/presets/blobs/" + digest,
+	}, registry.Requests())
+}
+
+func TestClient_BearerToken(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	registry.Token = "secret-token"
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:1.0.0")
+	require.NoError(t, err)
+
+	_, err = newClient().PushPackage(ref, []byte("package archive"), nil)
+	require.NoError(t, err)
+
+	_, _, err = newClient().FetchManifest(ref)
+	require.NoError(t, err)
+
+	// Pulling only asks for pull access.
+	assert.Equal(t, []string{"repository:ai/presets:pull,push", "repository:ai/presets:pull"}, registry.TokenScopes())
+}
+
+func TestClient_FetchBlob_DigestMismatch(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:1.0.0")
+	require.NoError(t, err)
+
+	desc := oci.Descriptor{MediaType: oci.LayerMediaType, Digest: oci.Digest([]byte("original")), Size: 8}
+	registry.PutBlob(desc.Digest, []byte("tampered"))
+
+	err = newClient().FetchBlob(ref, desc, &bytes.Buffer{})
+
+	var mismatchErr *oci.DigestMismatchError
+	require.ErrorAs(t, err, &mismatchErr)
+	assert.Equal(t, oci.Digest([]byte("tampered")), mismatchErr.Actual)
+}
+
+func TestClient_FetchManifest_NotFound(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:missing")
+	require.NoError(t, err)
+
+	_, _, err = newClient().FetchManifest(ref)
+
+	var registryErr *oci.RegistryError
+	require.ErrorAs(t, err, &registryErr)
+	assert.Equal(t, http.StatusNotFound, registryErr.StatusCode)
+}
+
+func TestClient_FetchManifest_TooLarge(t *testing.T) {
+	registry := ocitest.NewRegistry(t)
+	ref, err := oci.ParseReference(registry.Host + "/ai/presets:1.0.0")
+	require.NoError(t, err)
+
+	client := newClient()
+	_, err = client.PushManifest(ref, &oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.ManifestMediaType,
+		Annotations:   map[string]string{"padding": strings.Repeat("a", 4<<20)},
+	})
+	require.NoError(t, err)
+
+	_, _, err = client.FetchManifest(ref)
+
+	require.ErrorContains(t, err, "manifest of "+ref.String()+" is too large")
+}
@@ -0,0 +1,86 @@
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// CredentialStore returns the username and password for a registry host.
+	CredentialStore interface {
+		Credentials(registry string) (string, string, bool)
+	}
+
+	// DockerCredentialStore reads the credentials saved by `docker login` in the Docker config file
+	// (`$DOCKER_CONFIG/config.json` or `~/.docker/config.json`).
+	// Credential helpers (`credsStore`, `credHelpers`) are not supported.
+	DockerCredentialStore struct {
+		path string
+	}
+
+	dockerConfig struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+)
+
+// NewDockerCredentialStore creates a DockerCredentialStore that reads the default Docker config file.
+func NewDockerCredentialStore() *DockerCredentialStore {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &DockerCredentialStore{}
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	return &DockerCredentialStore{path: filepath.Join(dir, "config.json")}
+}
+
+// Credentials returns the credentials saved for the registry, if any.
+// A missing or unreadable config file means there are no credentials.
+func (s *DockerCredentialStore) Credentials(registry string) (string, string, bool) {
+	if s.path == "" {
+		return "", "", false
+	}
+
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", "", false
+	}
+
+	var cfg dockerConfig
+	if unmarshalErr := json.Unmarshal(body, &cfg); unmarshalErr != nil {
+		return "", "", false
+	}
+
+	for host, auth := range cfg.Auths {
+		// Keys may be saved as URLs, e.g. `https://registry.example.com/v1/`.
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		host, _, _ = strings.Cut(host, "/")
+		if host != registry {
+			continue
+		}
+
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, auth.Username != ""
+		}
+
+		decoded, decodeErr := base64.StdEncoding.DecodeString(auth.Auth)
+		if decodeErr != nil {
+			return "", "", false
+		}
+
+		username, password, ok := strings.Cut(string(decoded), ":")
+		return username, password, ok
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,130 @@
+// Package ocitest provides an in-memory OCI registry for tests.
+package ocitest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sushichan044/ajisai/internal/oci"
+)
+
+// Registry is an in-memory registry that implements the parts of the OCI distribution API used by ajisai.
+type Registry struct {
+	// Host of the registry, e.g. `127.0.0.1:12345`.
+	Host string
+
+	// Token required as a bearer token, if not empty. It is handed out by the `/token` endpoint.
+	Token string
+
+	mu          sync.Mutex
+	blobs       map[string][]byte
+	manifests   map[string][]byte
+	requests    []string
+	tokenScopes []string
+}
+
+// NewRegistry starts a registry that is stopped when the test ends.
+func NewRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	registry := &Registry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(registry.serve))
+	t.Cleanup(server.Close)
+
+	registry.Host = strings.TrimPrefix(server.URL, "http://")
+	return registry
+}
+
+// Requests returns the method and path of every request received, e.g. `GET /v2/ai/presets/manifests/1.0.0`.
+func (r *Registry) Requests() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.requests...)
+}
+
+// TokenScopes returns the scope of every token requested from the `/token` endpoint.
+func (r *Registry) TokenScopes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.tokenScopes...)
+}
+
+// PutBlob stores a blob, e.g. to serve content that does not match its digest.
+func (r *Registry) PutBlob(digest string, content []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.blobs[digest] = content
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+
+	if req.URL.Path == "/token" {
+		r.tokenScopes = append(r.tokenScopes, req.URL.Query().Get("scope"))
+		fmt.Fprintf(w, `{"token":%q}`, r.Token)
+		return
+	}
+
+	if r.Token != "" && req.Header.Get("Authorization") != "Bearer "+r.Token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="ocitest"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.HasSuffix(path, "/blobs/uploads/"):
+		w.Header().Set("Location", "/upload/"+strings.TrimSuffix(path, "/blobs/uploads/"))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasPrefix(req.URL.Path, "/upload/"):
+		body, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = body
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(path, "/blobs/"):
+		_, digest, _ := strings.Cut(path, "/blobs/")
+		r.serveContent(w, req, r.blobs[digest])
+	case strings.Contains(path, "/manifests/"):
+		repository, identifier, _ := strings.Cut(path, "/manifests/")
+		if req.Method == http.MethodPut {
+			body, _ := io.ReadAll(req.Body)
+			r.manifests[repository+":"+identifier] = body
+			r.manifests[repository+"@"+oci.Digest(body)] = body
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		separator := ":"
+		if oci.IsDigest(identifier) {
+			separator = "@"
+		}
+		w.Header().Set("Content-Type", oci.ManifestMediaType)
+		r.serveContent(w, req, r.manifests[repository+separator+identifier])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *Registry) serveContent(w http.ResponseWriter, req *http.Request, content []byte) {
+	if content == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if req.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,155 @@
+// Package oci pulls and pushes ajisai packages as artifacts in OCI registries,
+// using the subset of the OCI distribution API that ajisai needs.
+package oci
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultTag = "latest"
+
+	// repositoryComponent is a single path component of a repository name, as defined by the distribution spec.
+	repositoryComponent = `[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*`
+)
+
+type (
+	// Reference points to an artifact in a registry, e.g. `registry.example.com/ai/presets:1.2.0`
+	// or `registry.example.com/ai/presets@sha256:...`.
+	Reference struct {
+		// Registry host, with an optional port.
+		Registry   string
+		Repository string
+
+		// Tag is empty if the reference has a digest.
+		Tag    string
+		Digest string
+	}
+
+	// InvalidReferenceError is returned when a reference cannot be parsed.
+	InvalidReferenceError struct {
+		Reference string
+		Reason    string
+	}
+)
+
+func (e *InvalidReferenceError) Error() string {
+	return fmt.Sprintf("invalid OCI reference %q: %s", e.Reference, e.Reason)
+}
+
+func (e *InvalidReferenceError) Unwrap() error {
+	return nil
+}
+
+var (
+	repositoryPattern = regexp.MustCompile("^" + repositoryComponent + "(?:/" + repositoryComponent + ")*$")
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// ParseReference parses `host/repository[:tag]` or `host/repository@sha256:<hex>`.
+// The registry host is required. The tag defaults to `latest`.
+func ParseReference(s string) (Reference, error) {
+	host, rest, hasRepository := strings.Cut(s, "/")
+	if !hasRepository || !isRegistryHost(host) {
+		return Reference{}, &InvalidReferenceError{Reference: s, Reason: "must start with a registry host"}
+	}
+
+	ref := Reference{Registry: host}
+
+	if repository, digest, hasDigest := strings.Cut(rest, "@"); hasDigest {
+		if !IsDigest(digest) {
+			return Reference{}, &InvalidReferenceError{Reference: s, Reason: "digest must be sha256:<hex>"}
+		}
+		ref.Repository = repository
+		ref.Digest = digest
+	} else {
+		ref.Repository = rest
+		ref.Tag = defaultTag
+
+		if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+			ref.Repository = rest[:i]
+			ref.Tag = rest[i+1:]
+		}
+
+		if !tagPattern.MatchString(ref.Tag) {
+			return Reference{}, &InvalidReferenceError{Reference: s, Reason: fmt.Sprintf("invalid tag %q", ref.Tag)}
+		}
+	}
+
+	if !repositoryPattern.MatchString(ref.Repository) {
+		return Reference{}, &InvalidReferenceError{
+			Reference: s,
+			Reason:    fmt.Sprintf("invalid repository %q", ref.Repository),
+		}
+	}
+
+	return ref, nil
+}
+
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Digest)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
+
+// WithDigest returns a copy of the reference that points to the manifest with the given digest.
+func (r Reference) WithDigest(digest string) Reference {
+	r.Tag = ""
+	r.Digest = digest
+	return r
+}
+
+// identifier returns the tag or digest used in manifest URLs.
+func (r Reference) identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// baseURL returns the URL of the registry API. Loopback registries are accessed over plain HTTP,
+// like Docker does, so that a local registry can be used without TLS.
+func (r Reference) baseURL() string {
+	scheme := "https"
+	if isLoopback(r.Registry) {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, r.Registry, r.Repository)
+}
+
+// IsDigest reports whether s is a `sha256:<hex>` digest.
+func IsDigest(s string) bool {
+	encoded, isSHA256 := strings.CutPrefix(s, "sha256:")
+	if !isSHA256 || len(encoded) != 64 {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func isRegistryHost(host string) bool {
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
+
+func isLoopback(registry string) bool {
+	host := registry
+	if splitHost, _, err := net.SplitHostPort(registry); err == nil {
+		host = splitHost
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
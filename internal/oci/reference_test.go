@@ -0,0 +1,69 @@
+package oci_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/oci"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		input    string
+		expected oci.Reference
+	}{
+		{
+			input:    "registry.example.com/ai/presets:1.2.0",
+			expected: oci.Reference{Registry: "registry.example.com", Repository: "ai/presets", Tag: "1.2.0"},
+		},
+		{
+			input:    "localhost:5000/presets",
+			expected: oci.Reference{Registry: "localhost:5000", Repository: "presets", Tag: "latest"},
+		},
+		{
+			input:    "registry.example.com/ai/presets@" + digest,
+			expected: oci.Reference{Registry: "registry.example.com", Repository: "ai/presets", Digest: digest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := oci.ParseReference(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+}
+
+func TestParseReference_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"ai/presets:1.2.0":                            "must start with a registry host",
+		"registry.example.com/AI/presets":             `invalid repository "AI/presets"`,
+		"registry.example.com/ai/presets:":            `invalid tag ""`,
+		"registry.example.com/ai/presets@sha256:abcd": "digest must be sha256:<hex>",
+	}
+
+	for input, reason := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := oci.ParseReference(input)
+
+			var refErr *oci.InvalidReferenceError
+			require.ErrorAs(t, err, &refErr)
+			assert.Equal(t, reason, refErr.Reason)
+		})
+	}
+}
+
+func TestReference_String(t *testing.T) {
+	ref, err := oci.ParseReference("registry.example.com/ai/presets:1.2.0")
+	require.NoError(t, err)
+
+	digest := "sha256:" + strings.Repeat("b", 64)
+	assert.Equal(t, "registry.example.com/ai/presets:1.2.0", ref.String())
+	assert.Equal(t, "registry.example.com/ai/presets@"+digest, ref.WithDigest(digest).String())
+}
@@ -0,0 +1,151 @@
+// Package packer builds distributable archives of preset packages.
+package packer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/loader"
+)
+
+const (
+	// ManifestFileName is the name of the normalized package manifest in the archive.
+	ManifestFileName = "ajisai.yml"
+
+	// ChecksumFileName is the name of the file listing the SHA-256 checksum of every other file in the archive.
+	// It uses the format of `sha256sum`, so the extracted files can be checked with `sha256sum -c`.
+	ChecksumFileName = "SHA256SUMS"
+)
+
+// Result describes a packed archive.
+type Result struct {
+	// Files in the archive, in the order they were written.
+	Files []string
+
+	// Hex-encoded SHA-256 checksum of the archive, as used by `sha256` of archive imports.
+	SHA256 string
+}
+
+// Pack writes a gzip-compressed tarball of the package in dir to w.
+//
+// The tarball holds the rule and prompt files matched by the package exports, a normalized manifest
+// and a checksum file. It is deterministic: packing the same files twice produces the same bytes.
+func Pack(dir string, w io.Writer) (*Result, error) {
+	pkgManifest, files, err := loader.ExportedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := normalizedManifest(pkgManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(w, hash))
+	tw := tar.NewWriter(gz)
+
+	var checksums strings.Builder
+	written := make([]string, 0, len(files)+2)
+
+	writeFile := func(name string, body []byte) error {
+		if writeErr := writeTarFile(tw, name, body); writeErr != nil {
+			return fmt.Errorf("failed to write %s to archive: %w", name, writeErr)
+		}
+
+		sum := sha256.Sum256(body)
+		fmt.Fprintf(&checksums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+		written = append(written, name)
+		return nil
+	}
+
+	if err = writeFile(ManifestFileName, manifest); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		body, readErr := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, readErr)
+		}
+
+		if err = writeFile(file, body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = writeTarFile(tw, ChecksumFileName, []byte(checksums.String())); err != nil {
+		return nil, fmt.Errorf("failed to write %s to archive: %w", ChecksumFileName, err)
+	}
+	written = append(written, ChecksumFileName)
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return &Result{Files: written, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// normalizedManifest returns a manifest that only holds the package definition, with sorted and deduplicated globs.
+func normalizedManifest(pkgManifest *config.Package) ([]byte, error) {
+	normalized := &config.Package{
+		Name:    pkgManifest.Name,
+		Exports: make(map[string]config.ExportedPresetDefinition, len(pkgManifest.Exports)),
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(pkgManifest.Exports)) {
+		exports := pkgManifest.Exports[name]
+		normalized.Exports[name] = config.ExportedPresetDefinition{
+			Prompts: slices.Compact(slices.Sorted(slices.Values(exports.Prompts))),
+			Rules:   slices.Compact(slices.Sorted(slices.Values(exports.Rules))),
+		}
+	}
+
+	serializable, err := config.NewSerializer().Serialize(&config.Config{Package: normalized})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert manifest to serializable format: %w", err)
+	}
+
+	body, err := yaml.Marshal(serializable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest to YAML: %w", err)
+	}
+
+	return body, nil
+}
+
+// writeTarFile writes a regular file with fixed metadata, so that the archive does not depend on
+// file modes, owners or modification times.
+func writeTarFile(tw *tar.Writer, name string, body []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(body)),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(body)
+	return err
+}
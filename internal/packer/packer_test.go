@@ -0,0 +1,127 @@
+package packer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/packer"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+// readArchive returns the files in the gzip-compressed tarball.
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, nextErr := tr.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+		require.NoError(t, nextErr)
+
+		body, readErr := io.ReadAll(tr)
+		require.NoError(t, readErr)
+		files[header.Name] = string(body)
+	}
+
+	return files
+}
+
+func TestPack(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ajisai.yml"), `package:
+  name: example
+  exports:
+    essential:
+      rules:
+      - rules/**/*.md
+      - rules/style.md
+      prompts:
+      - prompts/*.md
+workspace:
+  imports:
+    other:
+      type: local
+      path: ../other
+      include:
+      - default
+`)
+	writeFile(t, filepath.Join(dir, "rules", "style.md"), "# Style")
+	writeFile(t, filepath.Join(dir, "rules", "nested", "go.md"), "# Go")
+	writeFile(t, filepath.Join(dir, "rules", "notes.txt"), "not exported")
+	writeFile(t, filepath.Join(dir, "prompts", "review.md"), "# Review")
+	writeFile(t, filepath.Join(dir, "README.md"), "not exported")
+
+	var archive bytes.Buffer
+	result, err := packer.Pack(dir, &archive)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"ajisai.yml",
+		"prompts/review.md",
+		"rules/nested/go.md",
+		"rules/style.md",
+		"SHA256SUMS",
+	}, result.Files)
+
+	sum := sha256.Sum256(archive.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.SHA256)
+
+	files := readArchive(t, archive.Bytes())
+	assert.Len(t, files, 5)
+	assert.Equal(t, "# Style", files["rules/style.md"])
+	assert.Equal(t, `package:
+  exports:
+    essential:
+      prompts:
+      - prompts/*.md
+      rules:
+      - rules/**/*.md
+      - rules/style.md
+  name: example
+`, files["ajisai.yml"])
+
+	styleSum := sha256.Sum256([]byte("# Style"))
+	assert.Contains(t, files["SHA256SUMS"], hex.EncodeToString(styleSum[:])+"  rules/style.md\n")
+}
+
+func TestPack_Deterministic(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "rules", "style.md"), "# Style")
+
+	var first bytes.Buffer
+	_, err := packer.Pack(dir, &first)
+	require.NoError(t, err)
+
+	// Modification times and modes do not change the archive.
+	require.NoError(t, os.Chmod(filepath.Join(dir, "rules", "style.md"), 0644))
+	writeFile(t, filepath.Join(dir, "rules", "style.md"), "# Style")
+
+	var second bytes.Buffer
+	_, err = packer.Pack(dir, &second)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Bytes(), second.Bytes())
+}
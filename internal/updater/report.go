@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sushichan044/ajisai/internal/fetcher"
+)
+
+const shortSHALength = 7
+
+// WriteOutdated writes the statuses as a table.
+func WriteOutdated(w io.Writer, statuses []ImportStatus) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "IMPORT\tREVISION\tCURRENT\tLATEST\tSTATUS\tNEWER TAGS")
+	for _, status := range statuses {
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			status.Name,
+			orDash(status.Revision),
+			orDash(shortSHA(status.Current)),
+			orDash(shortSHA(status.Latest)),
+			status.Status,
+			orDash(strings.Join(status.NewerTags, ", ")),
+		)
+	}
+
+	return table.Flush()
+}
+
+// WriteUpdate writes the files changed by moving the import from one commit to another.
+// An empty from means the import had not been fetched before.
+func WriteUpdate(w io.Writer, name string, from string, to string, changes []fetcher.FileChange) error {
+	if from == "" {
+		_, err := fmt.Fprintf(w, "%s: fetched at %s\n", name, shortSHA(to))
+		return err
+	}
+
+	if from == to {
+		_, err := fmt.Fprintf(w, "%s: already up to date (%s)\n", name, shortSHA(to))
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%s: %s -> %s\n", name, shortSHA(from), shortSHA(to)); err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "  no rule or prompt files changed")
+		return err
+	}
+
+	for _, change := range changes {
+		line := fmt.Sprintf("  %s %s", change.Status, change.Path)
+		if change.OldPath != "" {
+			line = fmt.Sprintf("  %s %s -> %s", change.Status, change.OldPath, change.Path)
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func shortSHA(commit string) string {
+	if len(commit) > shortSHALength {
+		return commit[:shortSHALength]
+	}
+
+	return commit
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
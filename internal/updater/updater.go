@@ -0,0 +1,291 @@
+package updater
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/loader"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/versionrange"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+const (
+	// StatusUpToDate indicates the import is at the commit its revision points to on the remote.
+	StatusUpToDate Status = iota
+
+	// StatusOutdated indicates the revision points to a newer commit on the remote,
+	// or newer tags exist for a tag revision.
+	StatusOutdated
+
+	// StatusNotFetched indicates the import has not been fetched into the cache yet.
+	StatusNotFetched
+
+	// StatusPinned indicates the revision is a commit SHA, which never moves.
+	StatusPinned
+
+	// StatusUnknownRevision indicates the revision is neither a branch nor a tag on the remote,
+	// or no tag satisfies the version range.
+	StatusUnknownRevision
+)
+
+type (
+	// Status is the outcome of comparing an import with its remote.
+	Status int
+
+	// ImportStatus is the state of a git import compared with its remote.
+	ImportStatus struct {
+		Name string
+
+		// Revision from the config. Empty if the import follows the remote default branch.
+		Revision string
+
+		// Commit the import is currently checked out at. Empty if not fetched.
+		Current string
+
+		// Commit the revision points to on the remote. Empty if it could not be resolved.
+		Latest string
+
+		// Tags newer than Revision, sorted oldest first.
+		// Only set when Revision is a semver tag, or a version range for tags outside the range.
+		NewerTags []string
+
+		Status Status
+	}
+
+	// Updater compares git imports with their remotes and reports what changed between commits.
+	Updater struct {
+		git fetcher.Git
+	}
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusUpToDate:
+		return "up to date"
+	case StatusOutdated:
+		return "outdated"
+	case StatusNotFetched:
+		return "not fetched"
+	case StatusPinned:
+		return "pinned to commit"
+	case StatusUnknownRevision:
+		return "revision not found on remote"
+	}
+
+	return "unknown"
+}
+
+// New creates an Updater that uses the `git` command if it is found on PATH,
+// and the built-in git implementation otherwise.
+func New() *Updater {
+	return &Updater{git: fetcher.NewGit(config.GitBackendAuto, nil, nil)}
+}
+
+// NewWithConfig creates an Updater that uses the git backend, credentials and mirrors from the config.
+func NewWithConfig(cfg *config.Config) *Updater {
+	return &Updater{git: fetcher.NewGit(
+		cfg.Settings.GitBackend,
+		fetcher.NewGitCredentials(cfg),
+		fetcher.NewMirrors(cfg.Settings.Mirrors),
+	)}
+}
+
+// NewWithRunner creates an Updater with a custom command runner (for testing).
+func NewWithRunner(runner utils.CommandRunner) *Updater {
+	return &Updater{git: fetcher.NewGitFetcherWithRunner(runner)}
+}
+
+// CurrentCommits returns the commit each git import is checked out at, keyed by import name.
+// The commit pinned in lock is preferred. Otherwise the HEAD of the cached clone is used.
+// Imports that have not been fetched yet are omitted.
+func (u *Updater) CurrentCommits(cfg *config.Config, lock *lockfile.Lockfile) (map[string]string, error) {
+	commits := make(map[string]string)
+
+	for name, pkg := range cfg.Workspace.Imports {
+		if pkg.Type != config.ImportTypeGit {
+			continue
+		}
+
+		if commit, isPinned := lock.PinnedCommit(name, pkg); isPinned {
+			commits[name] = commit
+			continue
+		}
+
+		cacheRoot, err := cfg.GetImportedPackageCacheRoot(name)
+		if err != nil {
+			return nil, err
+		}
+
+		cached, err := utils.IsDirExists(cacheRoot)
+		if err != nil {
+			return nil, err
+		}
+		if !cached {
+			continue
+		}
+
+		commit, err := u.git.Resolve(cacheRoot)
+		if err != nil {
+			return nil, err
+		}
+		commits[name] = commit
+	}
+
+	return commits, nil
+}
+
+// Outdated compares every git import with its remote, sorted by import name.
+func (u *Updater) Outdated(cfg *config.Config, lock *lockfile.Lockfile) ([]ImportStatus, error) {
+	current, err := u.CurrentCommits(cfg, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ImportStatus, 0)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Workspace.Imports)) {
+		details, isGit := config.GetImportDetails[config.GitImportDetails](cfg.Workspace.Imports[name])
+		if !isGit {
+			continue
+		}
+
+		refs, refsErr := u.git.ListRemoteRefs(details.Repository)
+		if refsErr != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", name, refsErr)
+		}
+
+		statuses = append(statuses, compare(name, details.Revision, current[name], refs))
+	}
+
+	return statuses, nil
+}
+
+// ChangedFiles returns the rule and prompt files included from the import that differ between two commits.
+// The import must already be fetched at a commit that contains both.
+func (u *Updater) ChangedFiles(cfg *config.Config, name string, from string, to string) ([]fetcher.FileChange, error) {
+	pkg, imported := cfg.Workspace.Imports[name]
+	if !imported {
+		return nil, fmt.Errorf("package %s is not imported", name)
+	}
+
+	packageRoot, err := cfg.GetImportedPackageRoot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := u.git.ChangedFiles(packageRoot, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := loader.NewAgentPresetPackageLoader(cfg).ResolvePackageManifest(name)
+	if err != nil {
+		return nil, err
+	}
+
+	globs := make([]string, 0)
+	for _, include := range pkg.Include {
+		if exports, isExported := manifest.Exports[include]; isExported {
+			globs = append(globs, exports.Rules...)
+			globs = append(globs, exports.Prompts...)
+		}
+	}
+
+	included := make([]fetcher.FileChange, 0, len(changes))
+	for _, change := range changes {
+		if matchesAny(globs, change.Path) || (change.OldPath != "" && matchesAny(globs, change.OldPath)) {
+			included = append(included, change)
+		}
+	}
+
+	return included, nil
+}
+
+// compare resolves the configured revision against the remote refs.
+func compare(name, revision, current string, refs *fetcher.RemoteRefs) ImportStatus {
+	status := ImportStatus{Name: name, Revision: revision, Current: current}
+
+	switch {
+	case revision == "":
+		status.Latest = refs.Head
+	case refs.Branches[revision] != "":
+		status.Latest = refs.Branches[revision]
+	case refs.Tags[revision] != "":
+		status.Latest = refs.Tags[revision]
+		status.NewerTags = newerTags(revision, slices.Collect(maps.Keys(refs.Tags)))
+	case versionrange.IsConstraint(revision):
+		constraint, err := versionrange.Parse(revision)
+		if err != nil {
+			status.Status = StatusUnknownRevision
+			return status
+		}
+
+		tag, found := constraint.Highest(slices.Collect(maps.Keys(refs.Tags)))
+		if !found {
+			status.Status = StatusUnknownRevision
+			return status
+		}
+
+		// Tags outside the range are reported as newer tags; the range must be edited to reach them.
+		status.Latest = refs.Tags[tag]
+		status.NewerTags = newerTags(tag, slices.Collect(maps.Keys(refs.Tags)))
+	case isCommitSHA(revision):
+		status.Status = StatusPinned
+		return status
+	default:
+		status.Status = StatusUnknownRevision
+		return status
+	}
+
+	switch {
+	case current == "":
+		status.Status = StatusNotFetched
+	case current != status.Latest || len(status.NewerTags) > 0:
+		status.Status = StatusOutdated
+	default:
+		status.Status = StatusUpToDate
+	}
+
+	return status
+}
+
+// newerTags returns the semver tags greater than tag, sorted oldest first.
+// Tags are compared with or without the leading `v`.
+func newerTags(tag string, tags []string) []string {
+	newer := make([]string, 0)
+	if !versionrange.IsVersion(tag) {
+		return newer
+	}
+
+	for _, candidate := range tags {
+		if versionrange.Compare(candidate, tag) > 0 {
+			newer = append(newer, candidate)
+		}
+	}
+
+	slices.SortFunc(newer, versionrange.Compare)
+
+	return newer
+}
+
+func isCommitSHA(revision string) bool {
+	const minShortSHALength = 7
+	if len(revision) < minShortSHALength {
+		return false
+	}
+
+	return strings.Trim(strings.ToLower(revision), "0123456789abcdef") == ""
+}
+
+func matchesAny(globs []string, path string) bool {
+	return slices.ContainsFunc(globs, func(glob string) bool {
+		matched, err := doublestar.Match(glob, path)
+		return err == nil && matched
+	})
+}
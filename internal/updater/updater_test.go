@@ -0,0 +1,268 @@
+package updater_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/updater"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v failed: %s", args, out)
+
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(body), 0600))
+}
+
+// remoteRepo is a bare repository with a working clone used to push new commits.
+type remoteRepo struct {
+	bareDir string
+	workDir string
+}
+
+func newRemoteRepo(t *testing.T, root string) *remoteRepo {
+	t.Helper()
+
+	repo := &remoteRepo{bareDir: filepath.Join(root, "remote.git"), workDir: filepath.Join(root, "work")}
+	runGit(t, root, "init", "--bare", "--initial-branch=main", repo.bareDir)
+	runGit(t, root, "clone", repo.bareDir, repo.workDir)
+	runGit(t, repo.workDir, "checkout", "-b", "main")
+
+	return repo
+}
+
+func (r *remoteRepo) commit(t *testing.T, files map[string]string, tag string) string {
+	t.Helper()
+
+	for path, body := range files {
+		writeFile(t, filepath.Join(r.workDir, path), body)
+	}
+	runGit(t, r.workDir, "add", "-A")
+	runGit(t, r.workDir, "commit", "-m", "update")
+	if tag != "" {
+		runGit(t, r.workDir, "tag", "-a", tag, "-m", tag)
+	}
+	runGit(t, r.workDir, "push", "--follow-tags", "origin", "main")
+
+	return runGit(t, r.workDir, "rev-parse", "HEAD")
+}
+
+func gitImport(repository, revision string) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeGit,
+		Include: []string{config.DefaultPresetName},
+		Details: config.GitImportDetails{Repository: repository, Revision: revision},
+	}
+}
+
+func TestUpdater_Outdated(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repo := newRemoteRepo(t, tempDir)
+	firstCommit := repo.commit(t, map[string]string{"rules/go.md": "first"}, "v1.0.0")
+
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: filepath.Join(tempDir, "cache")},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{
+				"branch":    gitImport(repo.bareDir, ""),
+				"tagged":    gitImport(repo.bareDir, "v1.0.0"),
+				"pinned":    gitImport(repo.bareDir, firstCommit),
+				"ranged":    gitImport(repo.bareDir, "^1.0"),
+				"unknown":   gitImport(repo.bareDir, "no-such-branch"),
+				"unfetched": gitImport(repo.bareDir, "main"),
+				"local": {
+					Type:    config.ImportTypeLocal,
+					Include: []string{config.DefaultPresetName},
+					Details: config.LocalImportDetails{Path: repo.workDir},
+				},
+			},
+		},
+	}
+
+	git := fetcher.NewGitFetcher()
+	for _, name := range []string{"branch", "tagged", "pinned", "ranged"} {
+		cacheRoot, err := cfg.GetImportedPackageCacheRoot(name)
+		require.NoError(t, err)
+		require.NoError(t, git.Fetch(cfg.Workspace.Imports[name], cacheRoot))
+	}
+
+	upd := updater.New()
+
+	statuses, err := upd.Outdated(cfg, lockfile.New())
+	require.NoError(t, err)
+	for _, status := range statuses {
+		if status.Name != "unfetched" && status.Name != "unknown" {
+			assert.Equal(t, firstCommit, status.Current, status.Name)
+		}
+	}
+	assert.Equal(t, updater.StatusUpToDate, statuses[0].Status, statuses[0].Name)
+	assert.Equal(t, updater.StatusUpToDate, statuses[2].Status, statuses[2].Name)
+	assert.Equal(t, updater.StatusUpToDate, statuses[3].Status, statuses[3].Name)
+
+	secondCommit := repo.commit(t, map[string]string{
+		"rules/go.md":    "second",
+		"prompts/new.md": "new",
+		"README.md":      "not exported",
+	}, "v1.1.0")
+
+	statuses, err = upd.Outdated(cfg, lockfile.New())
+	require.NoError(t, err)
+	assert.Equal(t, []updater.ImportStatus{
+		{
+			Name:    "branch",
+			Current: firstCommit,
+			Latest:  secondCommit,
+			Status:  updater.StatusOutdated,
+		},
+		{
+			Name:     "pinned",
+			Revision: firstCommit,
+			Current:  firstCommit,
+			Status:   updater.StatusPinned,
+		},
+		{
+			Name:      "ranged",
+			Revision:  "^1.0",
+			Current:   firstCommit,
+			Latest:    secondCommit,
+			NewerTags: []string{},
+			Status:    updater.StatusOutdated,
+		},
+		{
+			Name:      "tagged",
+			Revision:  "v1.0.0",
+			Current:   firstCommit,
+			Latest:    firstCommit,
+			NewerTags: []string{"v1.1.0"},
+			Status:    updater.StatusOutdated,
+		},
+		{
+			Name:     "unfetched",
+			Revision: "main",
+			Latest:   secondCommit,
+			Status:   updater.StatusNotFetched,
+		},
+		{
+			Name:     "unknown",
+			Revision: "no-such-branch",
+			Status:   updater.StatusUnknownRevision,
+		},
+	}, statuses)
+
+	// The commit pinned in the lockfile takes precedence over the cached clone.
+	lock := lockfile.New()
+	lock.Imports["branch"] = lockfile.NewEntry(cfg.Workspace.Imports["branch"], secondCommit)
+	current, err := upd.CurrentCommits(cfg, lock)
+	require.NoError(t, err)
+	assert.Equal(t, secondCommit, current["branch"])
+}
+
+func TestUpdater_ChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tempDir := t.TempDir()
+	repo := newRemoteRepo(t, tempDir)
+	firstCommit := repo.commit(t, map[string]string{"rules/go.md": "first", "rules/old.md": "old"}, "")
+
+	cfg := &config.Config{
+		Settings: &config.Settings{CacheDir: filepath.Join(tempDir, "cache")},
+		Workspace: &config.Workspace{
+			Imports: map[string]config.ImportedPackage{"remote": gitImport(repo.bareDir, "")},
+		},
+	}
+	cacheRoot, err := cfg.GetImportedPackageCacheRoot("remote")
+	require.NoError(t, err)
+
+	git := fetcher.NewGitFetcher()
+	require.NoError(t, git.Fetch(cfg.Workspace.Imports["remote"], cacheRoot))
+
+	require.NoError(t, os.Remove(filepath.Join(repo.workDir, "rules", "old.md")))
+	secondCommit := repo.commit(t, map[string]string{
+		"rules/go.md":    "second",
+		"prompts/new.md": "new",
+		"README.md":      "not exported",
+	}, "")
+	require.NoError(t, git.Fetch(cfg.Workspace.Imports["remote"], cacheRoot))
+
+	changes, err := updater.New().ChangedFiles(cfg, "remote", firstCommit, secondCommit)
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fetcher.FileChange{
+		{Status: "A", Path: "prompts/new.md"},
+		{Status: "M", Path: "rules/go.md"},
+		{Status: "D", Path: "rules/old.md"},
+	}, changes)
+}
+
+func TestWriteOutdated(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, updater.WriteOutdated(&buf, []updater.ImportStatus{
+		{
+			Name:    "branch",
+			Current: "1111111111111111111111111111111111111111",
+			Latest:  "2222222222222222222222222222222222222222",
+			Status:  updater.StatusOutdated,
+		},
+		{
+			Name:      "tagged",
+			Revision:  "v1.0.0",
+			Current:   "3333333333333333333333333333333333333333",
+			Latest:    "3333333333333333333333333333333333333333",
+			NewerTags: []string{"v1.1.0", "v2.0.0"},
+			Status:    updater.StatusOutdated,
+		},
+	}))
+
+	assert.Equal(t, `IMPORT  REVISION  CURRENT  LATEST   STATUS    NEWER TAGS
+branch  -         1111111  2222222  outdated  -
+tagged  v1.0.0    3333333  3333333  outdated  v1.1.0, v2.0.0
+`, buf.String())
+}
+
+func TestWriteUpdate(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, updater.WriteUpdate(&buf, "remote", "1111111aaa", "2222222bbb", []fetcher.FileChange{
+		{Status: "M", Path: "rules/go.md"},
+		{Status: "R", Path: "rules/b.md", OldPath: "rules/a.md"},
+	}))
+	require.NoError(t, updater.WriteUpdate(&buf, "other", "3333333ccc", "3333333ccc", nil))
+
+	assert.Equal(t, `remote: 1111111 -> 2222222
+  M rules/go.md
+  R rules/a.md -> rules/b.md
+other: already up to date (3333333)
+`, buf.String())
+}
@@ -0,0 +1,205 @@
+// Package vendoring keeps copies of imported packages in the repository, so that they can be applied
+// without fetching.
+package vendoring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	cp "github.com/otiai10/copy"
+
+	"github.com/sushichan044/ajisai/internal/loader"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/utils"
+)
+
+// MetadataFileName is the name of the file in each vendored copy that records what it was made from.
+const MetadataFileName = ".ajisai-vendor.yml"
+
+type (
+	// Metadata records what a vendored copy was made from.
+	Metadata struct {
+		// Import is what the import resolved to when it was vendored, as recorded in the lockfile.
+		Import lockfile.Entry `yaml:"import"`
+
+		// Digest of the vendored files (see utils.DigestDir), which detects files edited by hand.
+		Digest string `yaml:"digest"`
+	}
+
+	// StaleError is returned when a vendored copy was made for another definition of its import.
+	StaleError struct {
+		PackageName string
+		Dir         string
+	}
+)
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf(
+		"vendored copy of package %s in %s does not match the config; run `ajisai vendor` to update it",
+		e.PackageName,
+		e.Dir,
+	)
+}
+
+func (e *StaleError) Unwrap() error {
+	return nil
+}
+
+// Write replaces the vendored copy in vendorDir with the manifest of the package in packageRoot and the rule and
+// prompt files of the presets entry includes. entry is recorded as what the copy was made from.
+func Write(packageRoot string, vendorDir string, entry lockfile.Entry) error {
+	_, files, err := loader.IncludedFiles(packageRoot, entry.Include)
+	if err != nil {
+		return err
+	}
+
+	manifestPath, hasManifest, err := loader.ManifestFile(packageRoot)
+	if err != nil {
+		return err
+	}
+	if hasManifest {
+		files = append(files, filepath.Base(manifestPath))
+	}
+
+	parentDir := filepath.Dir(vendorDir)
+	if ensureErr := utils.EnsureDir(parentDir); ensureErr != nil {
+		return ensureErr
+	}
+
+	// The copy is written into a hidden sibling first, so that vendorDir is never left half populated.
+	tmpDir, err := os.MkdirTemp(parentDir, "."+filepath.Base(vendorDir)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, file := range files {
+		src := filepath.Join(packageRoot, filepath.FromSlash(file))
+		if copyErr := cp.Copy(src, filepath.Join(tmpDir, filepath.FromSlash(file))); copyErr != nil {
+			return fmt.Errorf("failed to copy %s: %w", src, copyErr)
+		}
+	}
+
+	digest, err := utils.DigestDir(tmpDir, MetadataFileName)
+	if err != nil {
+		return err
+	}
+	if writeErr := writeMetadata(tmpDir, Metadata{Import: entry, Digest: digest}); writeErr != nil {
+		return writeErr
+	}
+
+	if removeErr := os.RemoveAll(vendorDir); removeErr != nil {
+		return fmt.Errorf("failed to remove outdated vendored copy %s: %w", vendorDir, removeErr)
+	}
+	if renameErr := os.Rename(tmpDir, vendorDir); renameErr != nil {
+		return fmt.Errorf("failed to move vendored copy into %s: %w", vendorDir, renameErr)
+	}
+
+	return nil
+}
+
+// Read reads the metadata of the vendored copy in vendorDir.
+// It returns nil if vendorDir does not exist or has no metadata, i.e. the import is not vendored.
+func Read(vendorDir string) (*Metadata, error) {
+	body, err := os.ReadFile(filepath.Join(vendorDir, MetadataFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil //nolint:nilnil // Not vendored is not an error.
+		}
+		return nil, fmt.Errorf("failed to read vendor metadata in %s: %w", vendorDir, err)
+	}
+
+	var metadata Metadata
+	if unmarshalErr := yaml.Unmarshal(body, &metadata); unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to parse vendor metadata in %s: %w", vendorDir, unmarshalErr)
+	}
+
+	return &metadata, nil
+}
+
+// IsModified reports whether files in vendorDir were added, removed or edited since it was vendored.
+func (m *Metadata) IsModified(vendorDir string) (bool, error) {
+	digest, err := utils.DigestDir(vendorDir, MetadataFileName)
+	if err != nil {
+		return false, err
+	}
+
+	return digest != m.Digest, nil
+}
+
+// Extract replaces destinationDir with the files of the vendored copy in vendorDir.
+func Extract(vendorDir string, destinationDir string) error {
+	if err := os.RemoveAll(destinationDir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", destinationDir, err)
+	}
+
+	err := cp.Copy(vendorDir, destinationDir, cp.Options{
+		Skip: func(_ os.FileInfo, src, _ string) (bool, error) {
+			return filepath.Base(src) == MetadataFileName, nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy vendored copy %s into %s: %w", vendorDir, destinationDir, err)
+	}
+
+	return nil
+}
+
+// Prune removes the vendored copies in vendorRoot that are not named in keep, and returns their names.
+// Directories without vendor metadata are left alone, since ajisai did not write them.
+func Prune(vendorRoot string, keep []string) ([]string, error) {
+	entries, err := os.ReadDir(vendorRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read vendor directory %s: %w", vendorRoot, err)
+	}
+
+	removed := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || slices.Contains(keep, name) {
+			continue
+		}
+
+		dir := filepath.Join(vendorRoot, name)
+		metadata, readErr := Read(dir)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if metadata == nil {
+			continue
+		}
+
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			return nil, fmt.Errorf("failed to remove vendored copy %s: %w", dir, removeErr)
+		}
+		removed = append(removed, name)
+	}
+
+	return removed, nil
+}
+
+func writeMetadata(dir string, metadata Metadata) error {
+	body, err := yaml.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vendor metadata: %w", err)
+	}
+
+	header := []byte("# This file is generated by `ajisai vendor`. Do not edit it manually.\n")
+	if writeErr := utils.AtomicWriteFile(
+		filepath.Join(dir, MetadataFileName),
+		bytes.NewReader(append(header, body...)),
+	); writeErr != nil {
+		return fmt.Errorf("failed to write vendor metadata in %s: %w", dir, writeErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package vendoring_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+	"github.com/sushichan044/ajisai/internal/vendoring"
+)
+
+const manifest = `package:
+  name: presets
+  exports:
+    go:
+      rules:
+        - go/rules/**/*.md
+    web:
+      rules:
+        - web/rules/**/*.md
+`
+
+func writeFile(t *testing.T, path string, body string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(body), 0600))
+}
+
+func archiveImport(include ...string) config.ImportedPackage {
+	return config.ImportedPackage{
+		Type:    config.ImportTypeArchive,
+		Include: include,
+		Details: config.ArchiveImportDetails{
+			URL:    "https://releases.example.com/presets.tar.gz",
+			SHA256: "0f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a69780f1e2d3c4b5a6978",
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	packageRoot := t.TempDir()
+	writeFile(t, filepath.Join(packageRoot, "ajisai.yml"), manifest)
+	writeFile(t, filepath.Join(packageRoot, "go", "rules", "style.md"), "# Go")
+	writeFile(t, filepath.Join(packageRoot, "web", "rules", "style.md"), "# Web")
+	writeFile(t, filepath.Join(packageRoot, "README.md"), "# Presets")
+
+	vendorDir := filepath.Join(t.TempDir(), "vendor", "presets")
+	entry := lockfile.NewEntry(archiveImport("go"), "sha256:abc")
+	require.NoError(t, vendoring.Write(packageRoot, vendorDir, entry))
+
+	assert.FileExists(t, filepath.Join(vendorDir, "ajisai.yml"))
+	assert.FileExists(t, filepath.Join(vendorDir, "go", "rules", "style.md"))
+	assert.NoFileExists(t, filepath.Join(vendorDir, "web", "rules", "style.md"))
+	assert.NoFileExists(t, filepath.Join(vendorDir, "README.md"))
+
+	metadata, err := vendoring.Read(vendorDir)
+	require.NoError(t, err)
+	require.NotNil(t, metadata)
+	assert.Equal(t, entry, metadata.Import)
+	assert.True(t, metadata.Import.Matches(archiveImport("go")))
+
+	modified, err := metadata.IsModified(vendorDir)
+	require.NoError(t, err)
+	assert.False(t, modified)
+
+	writeFile(t, filepath.Join(vendorDir, "go", "rules", "style.md"), "# Edited")
+	modified, err = metadata.IsModified(vendorDir)
+	require.NoError(t, err)
+	assert.True(t, modified)
+
+	// Vendoring again replaces the edited copy.
+	require.NoError(t, vendoring.Write(packageRoot, vendorDir, entry))
+	body, err := os.ReadFile(filepath.Join(vendorDir, "go", "rules", "style.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Go", string(body))
+
+	destinationDir := filepath.Join(t.TempDir(), "cache", "presets")
+	writeFile(t, filepath.Join(destinationDir, "outdated.md"), "# Outdated")
+	require.NoError(t, vendoring.Extract(vendorDir, destinationDir))
+
+	assert.FileExists(t, filepath.Join(destinationDir, "go", "rules", "style.md"))
+	assert.NoFileExists(t, filepath.Join(destinationDir, vendoring.MetadataFileName))
+	assert.NoFileExists(t, filepath.Join(destinationDir, "outdated.md"))
+}
+
+func TestRead_NotVendored(t *testing.T) {
+	metadata, err := vendoring.Read(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Nil(t, metadata)
+}
+
+func TestPrune(t *testing.T) {
+	packageRoot := t.TempDir()
+	writeFile(t, filepath.Join(packageRoot, "rules", "rule.md"), "# Rule")
+
+	vendorRoot := t.TempDir()
+	entry := lockfile.NewEntry(archiveImport(config.DefaultPresetName), "sha256:abc")
+	require.NoError(t, vendoring.Write(packageRoot, filepath.Join(vendorRoot, "kept"), entry))
+	require.NoError(t, vendoring.Write(packageRoot, filepath.Join(vendorRoot, "removed"), entry))
+	writeFile(t, filepath.Join(vendorRoot, "handwritten", "notes.md"), "# Notes")
+
+	removed, err := vendoring.Prune(vendorRoot, []string{"kept"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"removed"}, removed)
+
+	assert.DirExists(t, filepath.Join(vendorRoot, "kept"))
+	assert.DirExists(t, filepath.Join(vendorRoot, "handwritten"))
+	assert.NoDirExists(t, filepath.Join(vendorRoot, "removed"))
+}
@@ -0,0 +1,294 @@
+// Package versionrange parses semver range constraints such as `^1.4` or `>=1.2.0 <2.0.0`
+// and matches them against git tags.
+package versionrange
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+const (
+	opEqual operator = iota
+	opGreater
+	opGreaterOrEqual
+	opLess
+	opLessOrEqual
+)
+
+type (
+	operator int
+
+	// comparator compares a version against a canonical semver (e.g. `v1.4.0`).
+	comparator struct {
+		op      operator
+		version string
+	}
+
+	// Constraint is a set of alternatives separated by `||`.
+	// A version matches if it satisfies every comparator of any alternative.
+	Constraint struct {
+		raw          string
+		alternatives [][]comparator
+
+		// Pre-release versions only match if the constraint itself mentions one.
+		allowPrerelease bool
+	}
+
+	// InvalidConstraintError is returned when a constraint cannot be parsed.
+	InvalidConstraintError struct {
+		Constraint string
+		Reason     string
+	}
+)
+
+func (e *InvalidConstraintError) Error() string {
+	return fmt.Sprintf("invalid version constraint %q: %s", e.Constraint, e.Reason)
+}
+
+func (e *InvalidConstraintError) Unwrap() error {
+	return nil
+}
+
+// IsConstraint reports whether revision is meant as a version range rather than a literal branch, tag or SHA.
+// A revision is a range if it starts with an operator (`^`, `~`, `>`, `<`, `=`) or combines several comparators.
+func IsConstraint(revision string) bool {
+	revision = strings.TrimSpace(revision)
+	if revision == "" {
+		return false
+	}
+
+	return strings.ContainsAny(revision[:1], "^~<>=") ||
+		strings.Contains(revision, "||") ||
+		strings.ContainsAny(revision, " \t")
+}
+
+// Parse parses a range constraint.
+//
+// Supported forms:
+//   - `^1.4`, `^1.4.2`: compatible with the version (same major, or same minor below 1.0.0)
+//   - `~1.4`, `~1.4.2`: same major and minor
+//   - `>=1.2.0`, `>1.2`, `<2`, `<=1.9`, `=1.4.2`: comparisons, omitted parts are wildcards
+//   - `1.4`, `1.4.2`: the version itself, omitted parts are wildcards
+//   - `>=1.2.0 <2.0.0`: every comparator must match
+//   - `^1.4 || ^2.0`: any alternative must match
+//
+// Versions may be written with or without the leading `v`.
+func Parse(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+
+	for alternative := range strings.SplitSeq(s, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, &InvalidConstraintError{Constraint: s, Reason: "empty range"}
+		}
+
+		comparators := make([]comparator, 0, len(fields))
+		for _, field := range fields {
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return nil, &InvalidConstraintError{Constraint: s, Reason: err.Error()}
+			}
+			comparators = append(comparators, parsed...)
+		}
+
+		c.alternatives = append(c.alternatives, comparators)
+	}
+
+	c.allowPrerelease = slices.ContainsFunc(c.alternatives, func(comparators []comparator) bool {
+		return slices.ContainsFunc(comparators, func(cmp comparator) bool {
+			return semver.Prerelease(cmp.version) != ""
+		})
+	})
+
+	return c, nil
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether the tag is a semver version that satisfies the constraint.
+func (c *Constraint) Check(tag string) bool {
+	version := canonical(tag)
+	if version == "" {
+		return false
+	}
+
+	if semver.Prerelease(version) != "" && !c.allowPrerelease {
+		return false
+	}
+
+	return slices.ContainsFunc(c.alternatives, func(comparators []comparator) bool {
+		for _, cmp := range comparators {
+			if !cmp.check(version) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// Highest returns the highest tag that satisfies the constraint.
+func (c *Constraint) Highest(tags []string) (string, bool) {
+	highest := ""
+
+	for _, tag := range tags {
+		if !c.Check(tag) {
+			continue
+		}
+
+		if highest == "" || Compare(tag, highest) > 0 {
+			highest = tag
+		}
+	}
+
+	return highest, highest != ""
+}
+
+// IsVersion reports whether the tag is a valid semver, with or without the leading `v`.
+func IsVersion(tag string) bool {
+	return canonical(tag) != ""
+}
+
+// Compare compares two semver tags, with or without the leading `v`.
+// Tags that are not valid semver are considered lower than any valid version.
+func Compare(a, b string) int {
+	return semver.Compare(canonical(a), canonical(b))
+}
+
+// canonical returns the tag as a full semver with the leading `v` (e.g. `v1.4.0`),
+// or an empty string if the tag is not a valid semver.
+func canonical(tag string) string {
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+
+	if !semver.IsValid(tag) {
+		return ""
+	}
+
+	return semver.Canonical(tag)
+}
+
+func (cmp comparator) check(version string) bool {
+	result := semver.Compare(version, cmp.version)
+
+	switch cmp.op {
+	case opEqual:
+		return result == 0
+	case opGreater:
+		return result > 0
+	case opGreaterOrEqual:
+		return result >= 0
+	case opLess:
+		return result < 0
+	case opLessOrEqual:
+		return result <= 0
+	}
+
+	return false
+}
+
+// parseComparator expands a single comparator such as `^1.4` into the lower and upper bounds it stands for.
+func parseComparator(field string) ([]comparator, error) {
+	prefix := field[:len(field)-len(strings.TrimLeft(field, "^~<>="))]
+	parts, prerelease, err := parsePartialVersion(strings.TrimPrefix(field[len(prefix):], "v"))
+	if err != nil {
+		return nil, err
+	}
+
+	lower := formatVersion(parts, prerelease)
+
+	switch prefix {
+	case "^":
+		return []comparator{{opGreaterOrEqual, lower}, {opLess, bumpCaret(parts)}}, nil
+	case "~":
+		return []comparator{{opGreaterOrEqual, lower}, {opLess, bump(parts, min(len(parts), 2))}}, nil
+	case ">=":
+		return []comparator{{opGreaterOrEqual, lower}}, nil
+	case "<":
+		return []comparator{{opLess, lower}}, nil
+	case ">":
+		if len(parts) < 3 {
+			// `>1.4` excludes every 1.4.x.
+			return []comparator{{opGreaterOrEqual, bump(parts, len(parts))}}, nil
+		}
+		return []comparator{{opGreater, lower}}, nil
+	case "<=":
+		if len(parts) < 3 {
+			// `<=1.4` includes every 1.4.x.
+			return []comparator{{opLess, bump(parts, len(parts))}}, nil
+		}
+		return []comparator{{opLessOrEqual, lower}}, nil
+	case "", "=":
+		if len(parts) < 3 {
+			return []comparator{{opGreaterOrEqual, lower}, {opLess, bump(parts, len(parts))}}, nil
+		}
+		return []comparator{{opEqual, lower}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %q", prefix)
+}
+
+// parsePartialVersion parses `1`, `1.4` or `1.4.2[-prerelease]` into its numeric parts.
+func parsePartialVersion(s string) ([]int, string, error) {
+	core, prerelease, _ := strings.Cut(s, "-")
+
+	fields := strings.Split(core, ".")
+	if core == "" || len(fields) > 3 {
+		return nil, "", fmt.Errorf("%q is not a version", s)
+	}
+
+	parts := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, "", fmt.Errorf("%q is not a version", s)
+		}
+		parts = append(parts, n)
+	}
+
+	if prerelease != "" && len(parts) < 3 {
+		return nil, "", fmt.Errorf("%q has a pre-release but no patch version", s)
+	}
+
+	return parts, prerelease, nil
+}
+
+// bumpCaret returns the exclusive upper bound of `^version`: the next version that changes
+// the left-most non-zero part among the parts that were written.
+func bumpCaret(parts []int) string {
+	for i, part := range parts {
+		if part != 0 {
+			return bump(parts, i+1)
+		}
+	}
+
+	return bump(parts, len(parts))
+}
+
+// bump increments the part at precision-1 and drops the parts after it (e.g. bump([1 4 2], 2) is `v1.5.0`).
+func bump(parts []int, precision int) string {
+	bumped := slices.Clone(parts[:precision])
+	bumped[precision-1]++
+
+	return formatVersion(bumped, "")
+}
+
+// formatVersion formats the parts as a canonical semver, filling omitted parts with zero.
+func formatVersion(parts []int, prerelease string) string {
+	full := [3]int{}
+	copy(full[:], parts)
+
+	version := fmt.Sprintf("v%d.%d.%d", full[0], full[1], full[2])
+	if prerelease != "" {
+		version += "-" + prerelease
+	}
+
+	return version
+}
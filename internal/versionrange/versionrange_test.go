@@ -0,0 +1,127 @@
+package versionrange_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/versionrange"
+)
+
+func TestIsConstraint(t *testing.T) {
+	for revision, want := range map[string]bool{
+		"^1.4":          true,
+		"~1.4.0":        true,
+		">=1.2.0 <2":    true,
+		"=1.4.2":        true,
+		"1.x || 2.x":    true,
+		"":              false,
+		"main":          false,
+		"v1.4.2":        false,
+		"1.4":           false,
+		"feature/1.4":   false,
+		"0123456789abc": false,
+	} {
+		assert.Equal(t, want, versionrange.IsConstraint(revision), revision)
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{
+			constraint: "^1.4",
+			matches:    []string{"v1.4.0", "v1.4.2", "1.9.0"},
+			rejects:    []string{"v1.3.9", "v2.0.0", "v1.5.0-rc.1", "main"},
+		},
+		{
+			constraint: "^0.4.1",
+			matches:    []string{"v0.4.1", "v0.4.9"},
+			rejects:    []string{"v0.4.0", "v0.5.0", "v1.0.0"},
+		},
+		{
+			constraint: "^0.0.3",
+			matches:    []string{"v0.0.3"},
+			rejects:    []string{"v0.0.4"},
+		},
+		{
+			constraint: "~1.4.0",
+			matches:    []string{"v1.4.0", "v1.4.7"},
+			rejects:    []string{"v1.5.0", "v1.3.0"},
+		},
+		{
+			constraint: "~1",
+			matches:    []string{"v1.0.0", "v1.9.9"},
+			rejects:    []string{"v2.0.0"},
+		},
+		{
+			constraint: ">=1.2.0 <2",
+			matches:    []string{"v1.2.0", "v1.99.0"},
+			rejects:    []string{"v1.1.9", "v2.0.0"},
+		},
+		{
+			constraint: ">1.4",
+			matches:    []string{"v1.5.0"},
+			rejects:    []string{"v1.4.9"},
+		},
+		{
+			constraint: "<=1.4",
+			matches:    []string{"v1.4.9"},
+			rejects:    []string{"v1.5.0"},
+		},
+		{
+			constraint: "=v1.4.2",
+			matches:    []string{"v1.4.2", "1.4.2"},
+			rejects:    []string{"v1.4.3"},
+		},
+		{
+			constraint: "^1.4 || ^3",
+			matches:    []string{"v1.4.0", "v3.1.0"},
+			rejects:    []string{"v2.0.0"},
+		},
+		{
+			constraint: "^2.0.0-rc.1",
+			matches:    []string{"v2.0.0-rc.1", "v2.0.0-rc.2", "v2.0.0"},
+			rejects:    []string{"v2.0.0-beta.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := versionrange.Parse(tt.constraint)
+			require.NoError(t, err)
+
+			for _, tag := range tt.matches {
+				assert.True(t, c.Check(tag), "%s should match %s", tt.constraint, tag)
+			}
+			for _, tag := range tt.rejects {
+				assert.False(t, c.Check(tag), "%s should not match %s", tt.constraint, tag)
+			}
+		})
+	}
+}
+
+func TestConstraint_Highest(t *testing.T) {
+	c, err := versionrange.Parse("^1.4")
+	require.NoError(t, err)
+
+	highest, found := c.Highest([]string{"v1.3.0", "v1.4.2", "v1.10.0", "v1.4.10", "v2.0.0", "latest"})
+	assert.True(t, found)
+	assert.Equal(t, "v1.10.0", highest)
+
+	_, found = c.Highest([]string{"v2.0.0"})
+	assert.False(t, found)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, constraint := range []string{"^", "^abc", ">=1.2.3.4", "^1.4 ||", "=>1.0", "~1.4-rc.1"} {
+		_, err := versionrange.Parse(constraint)
+
+		var constraintErr *versionrange.InvalidConstraintError
+		require.ErrorAs(t, err, &constraintErr, constraint)
+	}
+}
@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeRemoved  ChangeKind = "removed"
+)
+
+type (
+	// ChangeKind is how an output file changed.
+	ChangeKind string
+
+	// Change is an output file that was added, modified or removed by applying.
+	Change struct {
+		// Absolute path of the file.
+		Path string
+		Kind ChangeKind
+	}
+)
+
+// snapshot returns the content digest of each regular file under dirs, keyed by path.
+// Directories that do not exist are skipped.
+func snapshot(dirs []string) (map[string]string, error) {
+	digests := make(map[string]string)
+
+	for _, dir := range dirs {
+		walkErr := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			digest, digestErr := digestFile(path)
+			if digestErr != nil {
+				return digestErr
+			}
+			digests[path] = digest
+
+			return nil
+		})
+		if walkErr != nil && !errors.Is(walkErr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read outputs in %s: %w", dir, walkErr)
+		}
+	}
+
+	return digests, nil
+}
+
+func digestFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, copyErr := io.Copy(hash, file); copyErr != nil {
+		return "", copyErr
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// diff returns the files that differ between the snapshots, sorted by path.
+func diff(before map[string]string, after map[string]string) []Change {
+	changes := make([]Change, 0)
+
+	for _, path := range slices.Sorted(maps.Keys(after)) {
+		digest, existed := before[path]
+		switch {
+		case !existed:
+			changes = append(changes, Change{Path: path, Kind: ChangeAdded})
+		case digest != after[path]:
+			changes = append(changes, Change{Path: path, Kind: ChangeModified})
+		}
+	}
+
+	for path := range before {
+		if _, exists := after[path]; !exists {
+			changes = append(changes, Change{Path: path, Kind: ChangeRemoved})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return changes
+}
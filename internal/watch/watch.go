@@ -0,0 +1,472 @@
+// Package watch applies imports again when the config file or the source directory of a local import changes.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+
+	"github.com/sushichan044/ajisai/internal/config"
+	"github.com/sushichan044/ajisai/internal/engine"
+	"github.com/sushichan044/ajisai/internal/fetcher"
+	"github.com/sushichan044/ajisai/internal/lockfile"
+)
+
+// DefaultDebounce is how long Watcher waits after the last change before applying, by default.
+const DefaultDebounce = 200 * time.Millisecond
+
+type (
+	// Options changes how Watcher applies imports.
+	Options struct {
+		// Reject rule and prompt files with invalid front matter instead of loading them leniently.
+		Strict bool
+
+		// How long to wait after the last change before applying, so that a burst of changes (e.g. a save that
+		// replaces a file) is applied once. Defaults to DefaultDebounce.
+		Debounce time.Duration
+	}
+
+	// Watcher applies the imports of a config file, and applies them again when they change:
+	// a local import when a file in its source directory changes, and every import when the config file changes.
+	Watcher struct {
+		configPath string
+		opts       Options
+		w          io.Writer
+
+		fs      *fsnotify.Watcher
+		watched map[string]bool
+
+		cfg     *config.Config
+		eng     *engine.Engine
+		sources []source
+
+		// Paths written by applying, whose changes are not applied again even if they are in a source directory.
+		ignored []string
+	}
+
+	// source is the source directory of local imports.
+	source struct {
+		dir      string
+		packages []string
+		ignore   gitignore.Matcher
+	}
+
+	// batch collects the changes seen within the debounce interval.
+	batch struct {
+		config   bool
+		packages map[string]bool
+	}
+)
+
+// New creates a Watcher of the config file at configPath, which reports what it applies to w.
+func New(configPath string, w io.Writer, opts Options) *Watcher {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+
+	return &Watcher{configPath: configPath, opts: opts, w: w, watched: make(map[string]bool)}
+}
+
+// Run applies every import, then watches for changes until ctx is done.
+// It returns an error if the config cannot be loaded at first. Later errors are reported and watching goes on,
+// so that they can be fixed without a restart.
+func (wt *Watcher) Run(ctx context.Context) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching files: %w", err)
+	}
+	defer fsWatcher.Close()
+	wt.fs = fsWatcher
+
+	if reloadErr := wt.reload(); reloadErr != nil {
+		return reloadErr
+	}
+	fmt.Fprintln(wt.w, "Watching for changes. Press Ctrl+C to stop.")
+
+	debounce := time.NewTimer(wt.opts.Debounce)
+	debounce.Stop()
+	pending := newBatch()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			if wt.collect(pending, event) {
+				debounce.Reset(wt.opts.Debounce)
+			}
+		case watchErr, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(wt.w, "failed to watch files: %s\n", watchErr)
+		case <-debounce.C:
+			wt.apply(pending)
+			pending = newBatch()
+		}
+	}
+}
+
+func newBatch() *batch {
+	return &batch{packages: make(map[string]bool)}
+}
+
+// collect adds the packages affected by the event to pending, and reports whether there is anything to apply.
+func (wt *Watcher) collect(pending *batch, event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	path := filepath.Clean(event.Name)
+	if path == wt.configPath {
+		pending.config = true
+		return true
+	}
+	if wt.isIgnored(path) {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			wt.watchTree(path)
+		}
+	}
+
+	affected := false
+	for _, src := range wt.sources {
+		if src.contains(path) {
+			for _, name := range src.packages {
+				pending.packages[name] = true
+			}
+			affected = true
+		}
+	}
+
+	return affected
+}
+
+// apply applies the imports affected by the pending changes.
+func (wt *Watcher) apply(pending *batch) {
+	if pending.config {
+		if err := wt.reload(); err != nil {
+			fmt.Fprintf(wt.w, "%s: %s\n", filepath.Base(wt.configPath), err)
+		}
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(pending.packages)) {
+		changes, err := wt.applyPackage(name)
+		if err != nil {
+			fmt.Fprintf(wt.w, "%s: %s\n", name, err)
+			continue
+		}
+		wt.report(name+": applied", changes)
+	}
+}
+
+// reload loads the config file, applies every import and watches the source directories of its local imports.
+// The previous config stays in use if the new one cannot be loaded. Errors while applying are reported to the
+// writer instead of being returned, since they may be fixed by editing the sources.
+func (wt *Watcher) reload() error {
+	manager, err := config.NewManager(wt.configPath)
+	if err != nil {
+		return err
+	}
+	cfg, err := manager.Load()
+	if err != nil {
+		return err
+	}
+
+	eng, err := engine.NewEngineWithOptions(cfg, engine.Options{
+		Strict:       wt.opts.Strict,
+		LockfilePath: lockfile.PathForConfig(wt.configPath),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create engine: %w", err)
+	}
+
+	// Outputs of the previous config are compared as well, since its namespace or integrations may be different.
+	outputDirs := eng.OutputDirs()
+	if wt.eng != nil {
+		outputDirs = append(outputDirs, wt.eng.OutputDirs()...)
+	}
+
+	before, err := snapshot(outputDirs)
+	if err != nil {
+		return err
+	}
+	if wt.eng != nil {
+		if cleanErr := wt.eng.CleanOutputs(); cleanErr != nil {
+			return fmt.Errorf("failed to clean: %w", cleanErr)
+		}
+	}
+
+	// Sources are watched after applying, so that the directories created by applying are not seen as changes.
+	wt.cfg, wt.eng = cfg, eng
+	applyErr := applyAll(eng, cfg)
+	if watchErr := wt.watchSources(); watchErr != nil {
+		return watchErr
+	}
+
+	heading := filepath.Base(wt.configPath)
+	if applyErr != nil {
+		fmt.Fprintf(wt.w, "%s: %s\n", heading, applyErr)
+		return nil
+	}
+
+	after, err := snapshot(outputDirs)
+	if err != nil {
+		return err
+	}
+	wt.report(heading+": applied every import", diff(before, after))
+
+	return nil
+}
+
+// applyAll regenerates the outputs of every import and writes the lockfile.
+func applyAll(eng *engine.Engine, cfg *config.Config) error {
+	if err := eng.CleanOutputs(); err != nil {
+		return fmt.Errorf("failed to clean: %w", err)
+	}
+
+	for _, packageName := range slices.Sorted(maps.Keys(cfg.Workspace.Imports)) {
+		if err := eng.ApplyPackage(packageName); err != nil {
+			return fmt.Errorf("failed to apply package %s: %w", packageName, err)
+		}
+	}
+
+	if err := eng.WriteLockfile(); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// applyPackage applies the package again and returns the output files that changed.
+func (wt *Watcher) applyPackage(packageName string) ([]Change, error) {
+	outputDirs := wt.eng.OutputDirs()
+	before, err := snapshot(outputDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	if cleanErr := wt.eng.CleanPackageOutputs(packageName); cleanErr != nil {
+		return nil, cleanErr
+	}
+	if applyErr := wt.eng.ApplyPackage(packageName); applyErr != nil {
+		return nil, applyErr
+	}
+	if lockErr := wt.eng.WriteLockfile(); lockErr != nil {
+		return nil, fmt.Errorf("failed to write lockfile: %w", lockErr)
+	}
+
+	after, err := snapshot(outputDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff(before, after), nil
+}
+
+// report writes the heading and the changed files, relative to the project root.
+func (wt *Watcher) report(heading string, changes []Change) {
+	if len(changes) == 0 {
+		fmt.Fprintf(wt.w, "%s, no output files changed\n", heading)
+		return
+	}
+
+	fmt.Fprintln(wt.w, heading)
+	projectRoot, _ := wt.cfg.ProjectRoot()
+	for _, change := range changes {
+		path := change.Path
+		if relPath, err := filepath.Rel(projectRoot, path); err == nil && !strings.HasPrefix(relPath, "..") {
+			path = relPath
+		}
+		fmt.Fprintf(wt.w, "  %s %s\n", change.Kind, path)
+	}
+}
+
+// watchSources watches the config file and the source directories of the local imports, and stops watching the
+// directories that are no longer sources. Directories that stay watched keep their watch, since removing a watch
+// discards the events queued for it, e.g. those of an edit made while applying.
+func (wt *Watcher) watchSources() error {
+	ignored, err := wt.ignoredPaths()
+	if err != nil {
+		return err
+	}
+	wt.ignored = ignored
+
+	sourcesByDir := make(map[string]*source)
+	for _, name := range slices.Sorted(maps.Keys(wt.cfg.Workspace.Imports)) {
+		details, isLocal := config.GetImportDetails[config.LocalImportDetails](wt.cfg.Workspace.Imports[name])
+		if !isLocal {
+			continue
+		}
+
+		dir, resolveErr := wt.cfg.ResolvePath(details.Path)
+		if resolveErr != nil {
+			return resolveErr
+		}
+		if src, exists := sourcesByDir[dir]; exists {
+			src.packages = append(src.packages, name)
+			continue
+		}
+
+		matcher, matcherErr := fetcher.NewIgnoreMatcher(dir)
+		if matcherErr != nil {
+			// The source directory may not exist yet. The error is reported when the import is applied.
+			matcher = gitignore.NewMatcher(nil)
+		}
+		sourcesByDir[dir] = &source{dir: dir, packages: []string{name}, ignore: matcher}
+	}
+
+	wt.sources = make([]source, 0, len(sourcesByDir))
+	for _, dir := range slices.Sorted(maps.Keys(sourcesByDir)) {
+		wt.sources = append(wt.sources, *sourcesByDir[dir])
+	}
+
+	configDir := filepath.Dir(wt.configPath)
+	wanted := map[string]bool{configDir: true}
+	for _, src := range wt.sources {
+		for _, dir := range wt.treeDirs(src.dir) {
+			wanted[dir] = true
+		}
+	}
+
+	for dir := range wt.watched {
+		if !wanted[dir] {
+			// The directory may have been removed, which removes its watch as well.
+			_ = wt.fs.Remove(dir)
+			delete(wt.watched, dir)
+		}
+	}
+
+	if err := wt.watch(configDir); err != nil {
+		return err
+	}
+	for _, dir := range slices.Sorted(maps.Keys(wanted)) {
+		if err := wt.watch(dir); err != nil {
+			fmt.Fprintln(wt.w, err)
+		}
+	}
+
+	return nil
+}
+
+// ignoredPaths returns the paths that applying writes into.
+func (wt *Watcher) ignoredPaths() ([]string, error) {
+	cacheDir, err := wt.cfg.ResolvePath(wt.cfg.Settings.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	vendorDir, err := wt.cfg.ResolvePath(wt.cfg.Settings.VendorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wt.eng.OutputDirs(), cacheDir, vendorDir, lockfile.PathForConfig(wt.configPath)), nil
+}
+
+// watchTree watches dir and its subdirectories, except those written by applying or ignored by the source.
+func (wt *Watcher) watchTree(dir string) {
+	for _, subDir := range wt.treeDirs(dir) {
+		if err := wt.watch(subDir); err != nil {
+			fmt.Fprintln(wt.w, err)
+		}
+	}
+}
+
+// treeDirs returns dir and its subdirectories, except those written by applying or ignored by the source.
+func (wt *Watcher) treeDirs(dir string) []string {
+	var dirs []string
+	walkErr := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (d.Name() == ".git" || wt.isIgnored(path)) {
+			return filepath.SkipDir
+		}
+
+		dirs = append(dirs, path)
+		return nil
+	})
+	if walkErr != nil && !errors.Is(walkErr, fs.ErrNotExist) {
+		fmt.Fprintf(wt.w, "failed to watch %s: %s\n", dir, walkErr)
+	}
+
+	return dirs
+}
+
+func (wt *Watcher) watch(dir string) error {
+	if wt.watched[dir] {
+		return nil
+	}
+
+	if err := wt.fs.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	wt.watched[dir] = true
+
+	return nil
+}
+
+// isIgnored reports whether path is written by applying, or ignored by every source directory it is in.
+func (wt *Watcher) isIgnored(path string) bool {
+	for _, ignored := range wt.ignored {
+		if isWithin(ignored, path) {
+			return true
+		}
+	}
+
+	inSource := false
+	for _, src := range wt.sources {
+		if !isWithin(src.dir, path) {
+			continue
+		}
+		if src.contains(path) {
+			return false
+		}
+		inSource = true
+	}
+
+	return inSource
+}
+
+// contains reports whether path is in the source directory and not ignored by it.
+func (s source) contains(path string) bool {
+	if !isWithin(s.dir, path) {
+		return false
+	}
+
+	relPath, err := filepath.Rel(s.dir, path)
+	if err != nil || relPath == "." {
+		return err == nil
+	}
+
+	info, statErr := os.Stat(path)
+	isDir := statErr == nil && info.IsDir()
+	return !s.ignore.Match(strings.Split(filepath.ToSlash(relPath), "/"), isDir)
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(dir string, path string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,133 @@
+package watch_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/watch"
+)
+
+const workspaceConfig = `workspace:
+  imports:
+    presets:
+      type: local
+      path: ./presets
+      include: [default]
+  integrations:
+    cursor:
+      enabled: true
+`
+
+// syncBuffer is a bytes.Buffer that the watcher writes to while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func writeFile(t *testing.T, path string, body string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(body), 0600))
+}
+
+func readFile(path string) string {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(body)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "ajisai.yml")
+	writeFile(t, configPath, workspaceConfig)
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "rule.md"), "# First")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.New(configPath, out, watch.Options{Debounce: 20 * time.Millisecond}).Run(ctx)
+	}()
+
+	outputDir := filepath.Join(projectDir, ".cursor", "rules", "ajisai", "presets", "default")
+	windsurfFile := filepath.Join(projectDir, ".windsurf", "rules", "ajisai", "presets", "default", "go", "style.md")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Watching for changes")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, readFile(filepath.Join(outputDir, "rule.mdc")), "# First")
+
+	// Only the changed files of the package are reported.
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "rule.md"), "# Second")
+	require.Eventually(t, func() bool {
+		return strings.Contains(readFile(filepath.Join(outputDir, "rule.mdc")), "# Second")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		modified := filepath.Join(".cursor", "rules", "ajisai", "presets", "default", "rule.mdc")
+		return strings.Contains(out.String(), "presets: applied\n  modified "+modified)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Files in new directories are applied, and outputs of removed files are removed.
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "go", "style.md"), "# Go")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(outputDir, "go", "style.mdc"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.Remove(filepath.Join(projectDir, "presets", "rules", "rule.md")))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(outputDir, "rule.mdc"))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The config is loaded again when it changes.
+	writeFile(t, configPath, workspaceConfig+"    windsurf:\n      enabled: true\n")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(windsurfFile)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// An invalid config is reported, and the previous one stays in use.
+	writeFile(t, configPath, "workspace: [")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "ajisai.yml: failed to load config file")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "go", "style.md"), "# Go 2")
+	require.Eventually(t, func() bool {
+		return strings.Contains(readFile(windsurfFile), "# Go 2")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWatcher_Run_ConfigNotLoaded(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "ajisai.yml")
+	writeFile(t, configPath, "workspace: [")
+
+	err := watch.New(configPath, &syncBuffer{}, watch.Options{}).Run(t.Context())
+	require.Error(t, err)
+}
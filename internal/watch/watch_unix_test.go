@@ -0,0 +1,103 @@
+//go:build !windows
+
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/internal/watch"
+)
+
+const slowWorkspaceConfig = `workspace:
+  imports:
+    presets:
+      type: local
+      path: ./presets
+      include: [default]
+    slow:
+      type: local
+      path: ./slow
+      mode: link
+      include: [default]
+  integrations:
+    cursor:
+      enabled: true
+`
+
+// servePipe creates a named pipe at path, and writes body to its first reader once release is closed.
+// The returned channel is closed when the reader opens the pipe, which blocks the reader until then.
+func servePipe(t *testing.T, path string, body string, release <-chan struct{}) <-chan struct{} {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, syscall.Mkfifo(path, 0600))
+
+	reading := make(chan struct{})
+	go func() {
+		pipe, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer pipe.Close()
+
+		close(reading)
+		<-release
+		_, _ = pipe.WriteString(body)
+	}()
+
+	return reading
+}
+
+func TestWatcher_Run_EditWhileApplying(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "ajisai.yml")
+	writeFile(t, configPath, workspaceConfig)
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "rule.md"), "# First")
+
+	// Loading the slow package reads from the pipe, so applying it waits until the source has been edited.
+	release := make(chan struct{})
+	reading := servePipe(t, filepath.Join(projectDir, "slow", "rules", "slow.md"), "# Slow", release)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.New(configPath, out, watch.Options{Debounce: 20 * time.Millisecond}).Run(ctx)
+	}()
+
+	outputDir := filepath.Join(projectDir, ".cursor", "rules", "ajisai")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Watching for changes")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeFile(t, configPath, slowWorkspaceConfig)
+	select {
+	case <-reading:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the slow package was not loaded")
+	}
+
+	// fsnotify holds the next event until the watcher has applied, so an unrelated event goes first to leave the
+	// edit queued behind it.
+	writeFile(t, filepath.Join(projectDir, "notes.txt"), "")
+	writeFile(t, filepath.Join(projectDir, "presets", "rules", "rule.md"), "# Second")
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(readFile(filepath.Join(outputDir, "slow", "default", "slow.mdc")), "# Slow")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return strings.Contains(readFile(filepath.Join(outputDir, "presets", "default", "rule.mdc")), "# Second")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
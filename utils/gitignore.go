@@ -2,19 +2,14 @@ package utils
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 
 	gitignore "github.com/sabhiram/go-gitignore"
 )
 
-func IsPathGitIgnored(path string) (bool, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return false, fmt.Errorf("failed to get current working directory: %w", err)
-	}
-
-	ignore, compileErr := gitignore.CompileIgnoreFile(filepath.Join(cwd, ".gitignore"))
+// IsPathGitIgnored reports whether path, relative to rootDir, is matched by the `.gitignore` in rootDir.
+func IsPathGitIgnored(rootDir string, path string) (bool, error) {
+	ignore, compileErr := gitignore.CompileIgnoreFile(filepath.Join(rootDir, ".gitignore"))
 	if compileErr != nil {
 		return false, fmt.Errorf("failed to compile gitignore: %w", compileErr)
 	}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// DigestDir returns a `sha256:<hex>` digest of the regular files under dir.
+// The digest covers the relative path and content of each file, so it changes when a file is
+// added, removed, renamed or modified. Directories named `.git` and files named in ignoreNames are skipped.
+func DigestDir(dir string, ignoreNames ...string) (string, error) {
+	hash := sha256.New()
+
+	walkErr := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || slices.Contains(ignoreNames, d.Name()) {
+			return nil
+		}
+
+		relPath, relErr := filepath.Rel(dir, path)
+		if relErr != nil {
+			return relErr
+		}
+
+		// WalkDir visits files in lexical order, so the digest is stable.
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(relPath))
+
+		file, openErr := os.Open(path)
+		if openErr != nil {
+			return openErr
+		}
+		defer file.Close()
+
+		if _, copyErr := io.Copy(hash, file); copyErr != nil {
+			return copyErr
+		}
+		hash.Write([]byte{0})
+
+		return nil
+	})
+	if walkErr != nil {
+		return "", fmt.Errorf("failed to digest directory %s: %w", dir, walkErr)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sushichan044/ajisai/utils"
+)
+
+func TestDigestDir(t *testing.T) {
+	write := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for path, content := range files {
+			fullPath := filepath.Join(dir, path)
+			require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0750))
+			require.NoError(t, os.WriteFile(fullPath, []byte(content), 0600))
+		}
+	}
+
+	base := map[string]string{"rules/a.md": "a", "prompts/b.md": "b"}
+
+	dirA := t.TempDir()
+	write(t, dirA, base)
+	digestA, err := utils.DigestDir(dirA)
+	require.NoError(t, err)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, digestA)
+
+	t.Run("same content has same digest", func(t *testing.T) {
+		dirB := t.TempDir()
+		write(t, dirB, base)
+		write(t, dirB, map[string]string{".git/HEAD": "ignored"})
+
+		digestB, digestErr := utils.DigestDir(dirB)
+		require.NoError(t, digestErr)
+		assert.Equal(t, digestA, digestB)
+	})
+
+	t.Run("renamed file changes digest", func(t *testing.T) {
+		dirC := t.TempDir()
+		write(t, dirC, map[string]string{"rules/c.md": "a", "prompts/b.md": "b"})
+
+		digestC, digestErr := utils.DigestDir(dirC)
+		require.NoError(t, digestErr)
+		assert.NotEqual(t, digestA, digestC)
+	})
+
+	t.Run("modified content changes digest", func(t *testing.T) {
+		dirD := t.TempDir()
+		write(t, dirD, map[string]string{"rules/a.md": "changed", "prompts/b.md": "b"})
+
+		digestD, digestErr := utils.DigestDir(dirD)
+		require.NoError(t, digestErr)
+		assert.NotEqual(t, digestA, digestD)
+	})
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: internal/utils/cmd.go
+// Source: utils/cmd.go
 //
 // Generated by this command:
 //
-//	mockgen -source=internal/utils/cmd.go -destination=internal/utils/mocks/cmd.go -package=utils
+//	mockgen -source=utils/cmd.go -destination=utils/mocks/cmd.go -package=utils
 //
 
 // Package utils is a generated GoMock package.
@@ -12,6 +12,7 @@ package utils
 import (
 	reflect "reflect"
 
+	utils "github.com/sushichan044/ajisai/utils"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -39,6 +40,26 @@ func (m *MockCommandRunner) EXPECT() *MockCommandRunnerMockRecorder {
 	return m.recorder
 }
 
+// OutputInDir mocks base method.
+func (m *MockCommandRunner) OutputInDir(dir, command string, args ...string) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{dir, command}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "OutputInDir", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OutputInDir indicates an expected call of OutputInDir.
+func (mr *MockCommandRunnerMockRecorder) OutputInDir(dir, command any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{dir, command}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OutputInDir", reflect.TypeOf((*MockCommandRunner)(nil).OutputInDir), varargs...)
+}
+
 // Run mocks base method.
 func (m *MockCommandRunner) Run(command string, args ...string) error {
 	m.ctrl.T.Helper()
@@ -76,3 +97,21 @@ func (mr *MockCommandRunnerMockRecorder) RunInDir(dir, command any, args ...any)
 	varargs := append([]any{dir, command}, args...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInDir", reflect.TypeOf((*MockCommandRunner)(nil).RunInDir), varargs...)
 }
+
+// WithEnv mocks base method.
+func (m *MockCommandRunner) WithEnv(env ...string) utils.CommandRunner {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range env {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WithEnv", varargs...)
+	ret0, _ := ret[0].(utils.CommandRunner)
+	return ret0
+}
+
+// WithEnv indicates an expected call of WithEnv.
+func (mr *MockCommandRunnerMockRecorder) WithEnv(env ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithEnv", reflect.TypeOf((*MockCommandRunner)(nil).WithEnv), env...)
+}
@@ -14,6 +14,13 @@ import (
 // If the path is empty, the current working directory is returned.
 // Otherwise, the path is interpreted as relative to the current working directory.
 func ResolveAbsPath(path string) (string, error) {
+	return ResolveAbsPathFrom("", path)
+}
+
+// ResolveAbsPathFrom converts the given path to an absolute path like ResolveAbsPath,
+// but interprets relative and empty paths as relative to baseDir.
+// If baseDir is empty, the current working directory is used.
+func ResolveAbsPathFrom(baseDir string, path string) (string, error) {
 	if filepath.IsAbs(path) {
 		return path, nil
 	}
@@ -28,14 +35,23 @@ func ResolveAbsPath(path string) (string, error) {
 	}
 
 	// Handle empty path or relative path
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("failed to get working directory: %w", err)
+	if baseDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		baseDir = cwd
+	} else if !filepath.IsAbs(baseDir) {
+		absBaseDir, err := ResolveAbsPath(baseDir)
+		if err != nil {
+			return "", err
+		}
+		baseDir = absBaseDir
 	}
 
 	if path == "" {
-		return cwd, nil
+		return baseDir, nil
 	}
 
-	return filepath.Join(cwd, path), nil
+	return filepath.Join(baseDir, path), nil
 }
@@ -62,3 +62,28 @@ func TestResolveAbsPath(t *testing.T) {
 		assert.Equal(t, expected, resolved)
 	})
 }
+
+func TestResolveAbsPathFrom(t *testing.T) {
+	baseDir := t.TempDir()
+
+	t.Run("relative path is joined with base directory", func(t *testing.T) {
+		resolved, err := utils.ResolveAbsPathFrom(baseDir, "../shared")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(filepath.Dir(baseDir), "shared"), resolved)
+	})
+
+	t.Run("empty path is resolved to base directory", func(t *testing.T) {
+		resolved, err := utils.ResolveAbsPathFrom(baseDir, "")
+		require.NoError(t, err)
+		assert.Equal(t, baseDir, resolved)
+	})
+
+	t.Run("empty base directory is the working directory", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+
+		resolved, err := utils.ResolveAbsPathFrom("", "relative/path")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(wd, "relative/path"), resolved)
+	})
+}